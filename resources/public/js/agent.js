@@ -171,26 +171,26 @@ $(document).ready(function () {
 			return;
     	}
     	showLoader();
-        $.get("/api/agent-umount/"+currentAgentHost(), function (operationResult) {
+        apiPost("/api/agent-umount/"+currentAgentHost(), {}, function (operationResult) {
 			hideLoader();
 			if (operationResult.Code == "ERROR") {
 				addAlert(operationResult.Message)
 			} else {
 				location.reload();
 			}	
-        }, "json");	
+        });	
     });
     $("body").on("click", "button[data-command=mountlv]", function(event) {
     	var lv = $(event.target).attr("data-lv")
     	showLoader();
-        $.get("/api/agent-mount/"+currentAgentHost()+"?lv="+encodeURIComponent(lv), function (operationResult) {
+        apiPost("/api/agent-mount/"+currentAgentHost()+"?lv="+encodeURIComponent(lv), {}, function (operationResult) {
 			hideLoader();
 			if (operationResult.Code == "ERROR") {
 				addAlert(operationResult.Message)
 			} else {
 				location.reload();
 			}	
-        }, "json");	
+        });	
     });
     $("body").on("click", "button[data-command=removelv]", function(event) {
     	var lv = $(event.target).attr("data-lv")
@@ -198,14 +198,14 @@ $(document).ready(function () {
     	bootbox.confirm(message, function(confirm) {
 			if (confirm) {
 		    	showLoader();
-		        $.get("/api/agent-removelv/"+currentAgentHost()+"?lv="+encodeURIComponent(lv), function (operationResult) {
+		        apiPost("/api/agent-removelv/"+currentAgentHost()+"?lv="+encodeURIComponent(lv), {}, function (operationResult) {
 					hideLoader();
 					if (operationResult.Code == "ERROR") {
 						addAlert(operationResult.Message)
 					} else {
 						location.reload();
 					}	
-		        }, "json");
+		        });
 			}
 		});
     });
@@ -215,14 +215,14 @@ $(document).ready(function () {
 		bootbox.confirm(message, function(confirm) {
 			if (confirm) {
 		    	showLoader();
-		        $.get("/api/agent-create-snapshot/"+currentAgentHost(), function (operationResult) {
+		        apiPost("/api/agent-create-snapshot/"+currentAgentHost(), {}, function (operationResult) {
 					hideLoader();
 					if (operationResult.Code == "ERROR") {
 						addAlert(operationResult.Message)
 					} else {
 						location.reload();
 					}	
-		        }, "json");
+		        });
 			}
 		});
     });
@@ -232,27 +232,27 @@ $(document).ready(function () {
 		bootbox.confirm(message, function(confirm) {
 			if (confirm) {
 		    	showLoader();
-		        $.get("/api/agent-mysql-stop/"+currentAgentHost(), function (operationResult) {
+		        apiPost("/api/agent-mysql-stop/"+currentAgentHost(), {}, function (operationResult) {
 					hideLoader();
 					if (operationResult.Code == "ERROR") {
 						addAlert(operationResult.Message)
 					} else {
 						location.reload();
 					}	
-		        }, "json");
+		        });
 			}
 		});
     });
     $("body").on("click", "button[data-command=mysql-start]", function(event) {
     	showLoader();
-        $.get("/api/agent-mysql-start/"+currentAgentHost(), function (operationResult) {
+        apiPost("/api/agent-mysql-start/"+currentAgentHost(), {}, function (operationResult) {
 			hideLoader();
 			if (operationResult.Code == "ERROR") {
 				addAlert(operationResult.Message)
 			} else {
 				location.reload();
 			}	
-        }, "json");	
+        });	
     });
     $("body").on("click", "button[data-command=seed]", function(event) {
     	if (hasActiveSeeds) {
@@ -278,14 +278,14 @@ $(document).ready(function () {
 		bootbox.confirm(message, function(confirm) {
 			if (confirm) {
 		    	showLoader();
-		        $.get("/api/agent-seed/"+currentAgentHost()+"/"+sourceHost, function (operationResult) {
+		        apiPost("/api/agent-seed/"+currentAgentHost()+"/"+sourceHost, {}, function (operationResult) {
 					hideLoader();
 					if (operationResult.Code == "ERROR") {
 						addAlert(operationResult.Message)
 					} else {
 						location.reload();
 					}	
-		        }, "json");
+		        });
 			}
 		});
     });
@@ -1,7 +1,11 @@
 
 $(document).ready(function () {
     showLoader();
-    $.get("/api/audit-recovery/"+currentPage(), function (auditEntries) {
+    var apiUri = "/api/audit-recovery/"+currentPage();
+    if (unacknowledgedOnly()) {
+        apiUri = "/api/unacknowledged-recoveries";
+    }
+    $.get(apiUri, function (auditEntries) {
             displayAudit(auditEntries);
     	}, "json");
     function displayAudit(auditEntries) {
@@ -16,8 +20,50 @@ $(document).ready(function () {
     		jQuery('<td/>', { text: audit.RecoveryStartTimestamp }).appendTo(row);
     		jQuery('<td/>', { text: audit.RecoveryEndTimestamp }).appendTo(row);
     		jQuery('<td/>', { text: audit.SuccessorKey.Hostname+":"+audit.SuccessorKey.Port }).appendTo(row);
+    		if (audit.Acknowledged) {
+    			jQuery('<td/>', { text: audit.AcknowledgedBy+" at "+audit.AcknowledgedAt+": "+audit.AcknowledgedComment }).appendTo(row);
+    		} else if (isAuthorizedForAction()) {
+    			var ackCell = jQuery('<td/>').appendTo(row);
+    			jQuery('<button/>', { text: "Acknowledge", "class": "btn btn-xs btn-primary", "data-recovery-id": audit.TopologyRecoveryId }).appendTo(ackCell);
+    		} else {
+    			jQuery('<td/>', { text: "" }).appendTo(row);
+    		}
+    		var stepsCell = jQuery('<td/>').appendTo(row);
+    		var steps = audit.AllSteps || [];
+    		jQuery('<button/>', { text: steps.length+" steps", "class": "btn btn-xs btn-default", "data-steps-recovery-id": audit.TopologyRecoveryId }).appendTo(stepsCell);
     		row.appendTo('#audit tbody');
+
+    		var stepsRow = jQuery('<tr/>', { "class": "recovery-steps", "data-recovery-id": audit.TopologyRecoveryId }).hide();
+    		var stepsList = jQuery('<ol/>');
+    		steps.forEach(function (step) {
+    			jQuery('<li/>', { text: step.AuditAt+" "+step.Message }).appendTo(stepsList);
+    		});
+    		jQuery('<td/>', { colspan: 10 }).append(stepsList).appendTo(stepsRow);
+    		stepsRow.appendTo('#audit tbody');
     	});
+        $("#audit button[data-steps-recovery-id]").click(function() {
+            var recoveryId = $(this).attr("data-steps-recovery-id");
+            $("#audit tr.recovery-steps[data-recovery-id='"+recoveryId+"']").toggle();
+        });
+        $("#audit button[data-recovery-id]").click(function() {
+            var recoveryId = $(this).attr("data-recovery-id");
+            bootbox.prompt("Acknowledge recovery "+recoveryId+". Please enter a comment:", function(comment) {
+                if (comment) {
+                    showLoader();
+                    apiPost("/api/ack-recovery/"+recoveryId+"?comment="+encodeURIComponent(comment), {}, function (operationResult) {
+                        hideLoader();
+                        if (operationResult.Code == "ERROR") {
+                            addAlert(operationResult.Message)
+                        } else {
+                            location.reload();
+                        }
+                    });
+                }
+            });
+        });
+        if (unacknowledgedOnly()) {
+            $("#audit .pager").hide();
+        }
         if (currentPage() <= 0) {
         	$("#audit .pager .previous").addClass("disabled");
         }
@@ -173,6 +173,22 @@ function visualizeInstances(nodesMap) {
             });
         }).remove();
 
+        // Multi-source slaves: render links to their additional masters (not part of the tree layout)
+        var additionalLinks = [];
+        nodes.forEach(function (d) {
+            (d.additionalMasterNodes || []).forEach(function (masterNode) {
+                if (nodes.indexOf(masterNode) >= 0) {
+                    additionalLinks.push({source: masterNode, target: d});
+                }
+            });
+        });
+        var additionalLink = svg.selectAll("path.additional-master-link").data(additionalLinks, function (d) {
+            return d.source.id + "-" + d.target.id;
+        });
+        additionalLink.enter().insert("path", "g").attr("class", "additional-master-link");
+        additionalLink.transition().duration(duration).attr("d", diagonal);
+        additionalLink.exit().remove();
+
         // Stash the old positions for transition.
         nodes.forEach(function (d) {
             d.x0 = d.x;
@@ -276,14 +276,14 @@ function moveBelow(node, siblingNode) {
 		if (confirm) {
 			showLoader();
 			var apiUrl = "/api/move-below/" + node.Key.Hostname + "/" + node.Key.Port + "/" + siblingNode.Key.Hostname + "/" + siblingNode.Key.Port;
-		    $.get(apiUrl, function (operationResult) {
+		    apiPost(apiUrl, {}, function (operationResult) {
 	    			hideLoader();
 	    			if (operationResult.Code == "ERROR") {
 	    				addAlert(operationResult.Message)
 	    			} else {
 	    				reloadWithOperationResult(operationResult);
 	    			}	
-	            }, "json");					
+	            });					
 		}
 		$("#cluster_container .accept_drop").removeClass("accept_drop");
     	$("#cluster_container .accept_drop").removeClass("accept_drop_warning");
@@ -302,14 +302,14 @@ function moveUp(node, grandparentNode) {
 		if (confirm) {
 			showLoader();
 			var apiUrl = "/api/move-up/" + node.Key.Hostname + "/" + node.Key.Port;
-		    $.get(apiUrl, function (operationResult) {
+		    apiPost(apiUrl, {}, function (operationResult) {
 	    			hideLoader();
 	    			if (operationResult.Code == "ERROR") {
 	    				addAlert(operationResult.Message)
 	    			} else {
 	    				reloadWithOperationResult(operationResult);
 	    			}	
-	            }, "json");					
+	            });					
 		}
 		$("#cluster_container .accept_drop").removeClass("accept_drop");
 	}); 
@@ -328,14 +328,14 @@ function enslaveMaster(node, masterNode) {
 		if (confirm) {
 			showLoader();
 			var apiUrl = "/api/enslave-master/" + node.Key.Hostname + "/" + node.Key.Port;
-		    $.get(apiUrl, function (operationResult) {
+		    apiPost(apiUrl, {}, function (operationResult) {
 	    			hideLoader();
 	    			if (operationResult.Code == "ERROR") {
 	    				addAlert(operationResult.Message)
 	    			} else {
 	    				reloadWithOperationResult(operationResult);
 	    			}	
-	            }, "json");					
+	            });					
 		}
 		$("#cluster_container .accept_drop").removeClass("accept_drop");
 	}); 
@@ -352,14 +352,14 @@ function makeCoMaster(node, childNode) {
 		if (confirm) {
 			showLoader();
 			var apiUrl = "/api/make-co-master/" + childNode.Key.Hostname + "/" + childNode.Key.Port;
-		    $.get(apiUrl, function (operationResult) {
+		    apiPost(apiUrl, {}, function (operationResult) {
 	    			hideLoader();
 	    			if (operationResult.Code == "ERROR") {
 	    				addAlert(operationResult.Message)
 	    			} else {
 	    				reloadWithOperationResult(operationResult);
 	    			}	
-	            }, "json");					
+	            });					
 		}
 		$("#cluster_container .accept_drop").removeClass("accept_drop");
 	}); 
@@ -378,14 +378,14 @@ function matchBelow(node, otherNode) {
 		if (confirm) {
 			showLoader();
 			var apiUrl = "/api/match-below/" + node.Key.Hostname + "/" + node.Key.Port + "/" + otherNode.Key.Hostname + "/" + otherNode.Key.Port;
-		    $.get(apiUrl, function (operationResult) {
+		    apiPost(apiUrl, {}, function (operationResult) {
 	    			hideLoader();
 	    			if (operationResult.Code == "ERROR") {
 	    				addAlert(operationResult.Message)
 	    			} else {
 	    				reloadWithOperationResult(operationResult);
 	    			}	
-	            }, "json");					
+	            });					
 		}
 		$("#cluster_container .accept_drop").removeClass("accept_drop");
 	}); 
@@ -616,14 +616,14 @@ function makeMaster(instance) {
 	bootbox.confirm(message, function(confirm) {
 		if (confirm) {
 	    	showLoader();
-	        $.get("/api/make-master/"+instance.Key.Hostname+"/"+instance.Key.Port, function (operationResult) {
+	        apiPost("/api/make-master/"+instance.Key.Hostname+"/"+instance.Key.Port, {}, function (operationResult) {
 				hideLoader();
 				if (operationResult.Code == "ERROR") {
 					addAlert(operationResult.Message)
 				} else {
     				reloadWithOperationResult(operationResult);
 				}	
-	        }, "json");
+	        });
 		}
 	});
 }
@@ -637,14 +637,14 @@ function makeLocalMaster(instance) {
 	bootbox.confirm(message, function(confirm) {
 		if (confirm) {
 	    	showLoader();
-	        $.get("/api/make-local-master/"+instance.Key.Hostname+"/"+instance.Key.Port, function (operationResult) {
+	        apiPost("/api/make-local-master/"+instance.Key.Hostname+"/"+instance.Key.Port, {}, function (operationResult) {
 				hideLoader();
 				if (operationResult.Code == "ERROR") {
 					addAlert(operationResult.Message)
 				} else {
                     reloadWithOperationResult(operationResult);
 				}	
-	        }, "json");
+	        });
 		}
 	});
 }
@@ -657,14 +657,14 @@ function promptForAlias(oldAlias) {
 		callback: function(result) {
 			if (result !== null) {
 		    	showLoader();
-		        $.get("/api/set-cluster-alias/"+currentClusterName()+"?alias="+encodeURIComponent(result), function (operationResult) {
+		        apiPost("/api/set-cluster-alias/"+currentClusterName()+"?alias="+encodeURIComponent(result), {}, function (operationResult) {
 					hideLoader();
 					if (operationResult.Code == "ERROR") {
 						addAlert(operationResult.Message)
 					} else {
 						location.reload();
 					}	
-		        }, "json");	    				
+		        });	    				
 			} 
 		}
 	}); 
@@ -791,5 +791,6 @@ $(document).ready(function () {
     if (isAuthorizedForAction()) {
     	// Read-only users don't get auto-refresh. Sorry!
     	activateRefreshTimer();
+    	activateEventRefresh(currentClusterName());
     }
 });
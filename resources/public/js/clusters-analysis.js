@@ -115,5 +115,6 @@ $(document).ready(function () {
     if (isAuthorizedForAction()) {
     	// Read-only users don't get auto-refresh. Sorry!
     	activateRefreshTimer();
+    	activateEventRefresh();
     }
 });	
@@ -17,7 +17,7 @@ $(document).ready(function () {
 function discover(hostname, port) {
     showLoader();
     var uri = "/api/discover/"+hostname+"/"+port;
-    $.get(uri, function (operationResult) {
+    apiPost(uri, {}, function (operationResult) {
         hideLoader();
         if (operationResult.Code == "ERROR") {
             addAlert(operationResult.Message)
@@ -27,6 +27,6 @@ function discover(hostname, port) {
             		+ " sent for discovery. This should reflect in the topology listing or in topology instances."
             		+ ' <a href="/web/search?s='+hostname+":"+port+'" class="alert-link">Search</a> for this instance');
         }   
-    }, "json"); 
+    }); 
 	
 }
\ No newline at end of file
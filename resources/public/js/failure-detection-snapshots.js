@@ -0,0 +1,81 @@
+
+$(document).ready(function () {
+    showLoader();
+    var apiUri = "/api/failure-detection-snapshots?page="+currentPage();
+    var webUri = "/web/failure-detection-snapshots";
+    if (currentClusterName()) {
+        apiUri = "/api/failure-detection-snapshots/cluster/"+currentClusterName()+"?page="+currentPage();
+        webUri = "/web/failure-detection-snapshots/cluster/"+currentClusterName();
+    }
+    $.get(apiUri, function (snapshots) {
+            displaySnapshots(snapshots);
+    	}, "json");
+    function snapshotDetails(snapshot) {
+    		var detailsDiv = jQuery('<div/>');
+    		jQuery('<h5/>', { text: "Global status" }).appendTo(detailsDiv);
+    		var statusList = jQuery('<ul/>').appendTo(detailsDiv);
+    		$.each(snapshot.GlobalStatus || {}, function (variableName, value) {
+    			jQuery('<li/>', { text: variableName+": "+value }).appendTo(statusList);
+    		});
+    		jQuery('<h5/>', { text: "Processlist" }).appendTo(detailsDiv);
+    		var processTable = jQuery('<table class="table table-condensed"><thead><tr><th>Id</th><th>User</th><th>Host</th><th>Db</th><th>Command</th><th>Time</th><th>State</th><th>Info</th></tr></thead><tbody></tbody></table>').appendTo(detailsDiv);
+    		(snapshot.Processlist || []).forEach(function (process) {
+    			var processRow = jQuery('<tr/>');
+    			[process.Id, process.User, process.Host, process.Db, process.Command, process.Time, process.State, process.Info].forEach(function (value) {
+    				jQuery('<td/>', { text: value }).appendTo(processRow);
+    			});
+    			processRow.appendTo(processTable.find("tbody"));
+    		});
+    		jQuery('<h5/>', { text: "InnoDB status" }).appendTo(detailsDiv);
+    		jQuery('<pre/>', { text: snapshot.InnoDBStatus }).appendTo(detailsDiv);
+    		return detailsDiv;
+    }
+    function displaySnapshots(snapshots) {
+        hideLoader();
+        snapshots.forEach(function (snapshot) {
+    		var row = jQuery('<tr/>');
+    		jQuery('<td/>', { text: snapshot.CapturedAt }).appendTo(row);
+    		jQuery('<td/>', { text: snapshot.Key.Hostname+":"+snapshot.Key.Port }).appendTo(row);
+    		jQuery('<td/>', { text: snapshot.Analysis }).appendTo(row);
+    		jQuery('<td/>', { text: snapshot.AnalyzedInstanceKey.Hostname+":"+snapshot.AnalyzedInstanceKey.Port }).appendTo(row);
+    		jQuery('<td/>').append(jQuery('<a/>', { text: snapshot.ClusterName, href: "/web/failure-detection-snapshots/cluster/"+snapshot.ClusterName })).appendTo(row);
+    		jQuery('<td/>', { text: snapshot.CaptureError }).appendTo(row);
+    		var detailsCell = jQuery('<td/>').appendTo(row);
+    		jQuery('<button/>', { text: "Details", "class": "btn btn-xs btn-default", "data-snapshot-id": snapshot.SnapshotId }).appendTo(detailsCell);
+    		row.appendTo('#failure_detection_snapshots tbody');
+
+    		var detailsRow = jQuery('<tr/>', { "class": "snapshot-details", "data-snapshot-id": snapshot.SnapshotId }).hide();
+    		jQuery('<td/>', { colspan: 7 }).appendTo(detailsRow);
+    		detailsRow.appendTo('#failure_detection_snapshots tbody');
+    	});
+        $("#failure_detection_snapshots button[data-snapshot-id]").click(function() {
+            var snapshotId = $(this).attr("data-snapshot-id");
+            var detailsRow = $("#failure_detection_snapshots tr.snapshot-details[data-snapshot-id='"+snapshotId+"']");
+            if (detailsRow.hasClass("loaded")) {
+                detailsRow.toggle();
+                return;
+            }
+            // Processlist & InnoDB status are only fetched per snapshot
+            $.get("/api/failure-detection-snapshot/"+snapshotId, function (snapshot) {
+                detailsRow.addClass("loaded");
+                detailsRow.find("td").append(snapshotDetails(snapshot));
+                detailsRow.show();
+            }, "json");
+        });
+        if (currentPage() <= 0) {
+        	$("#failure_detection_snapshots .pager .previous").addClass("disabled");
+        }
+        if (snapshots.length == 0) {
+        	$("#failure_detection_snapshots .pager .next").addClass("disabled");        	
+        }
+        $("#failure_detection_snapshots .pager .previous").not(".disabled").find("a").click(function() {
+            window.location.href = webUri+"?page="+(currentPage() - 1);
+        });
+        $("#failure_detection_snapshots .pager .next").not(".disabled").find("a").click(function() {
+            window.location.href = webUri+"?page="+(currentPage() + 1);
+        });
+        $("#failure_detection_snapshots .pager .disabled a").click(function() {
+            return false;
+        });
+    }
+});	
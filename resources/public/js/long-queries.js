@@ -47,14 +47,14 @@ $(document).ready(function () {
     	bootbox.confirm(message, function(confirm) {
 			if (confirm) {
 		    	showLoader();
-		        $.get("/api/kill-query/"+host + "/" + port + "/" + processId, function (operationResult) {
+		        apiPost("/api/kill-query/"+host + "/" + port + "/" + processId, {}, function (operationResult) {
 					hideLoader();
 					if (operationResult.Code == "ERROR") {
 						addAlert(operationResult.Message)
 					} else {
 						location.reload();
 					}	
-		        }, "json");
+		        });
 			}
         });
     });
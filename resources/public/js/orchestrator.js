@@ -39,6 +39,27 @@ function activateRefreshTimer() {
     	resetRefreshTimer();
     });
 }
+
+var eventRefreshDelaySeconds = 3;
+var topologyEventTypes = [
+	"master-changed", "replication-started", "replication-stopped", "replication-lagging", "replication-caught-up",
+	"instance-unreachable", "instance-reachable", "analysis", "analysis-cleared", "recovery-started",
+	"recovery-resolved", "maintenance-begun", "maintenance-ended", "downtime-begun", "downtime-ended"
+];
+
+// Listens on the topology event stream (optionally filtered by cluster name); upon an event the refresh timer
+// is cut short, so that the page reflects the change without waiting out the full refresh interval.
+function activateEventRefresh(clusterName) {
+	if (typeof EventSource == "undefined") {
+		return;
+	}
+	var eventSource = new EventSource("/api/events" + (clusterName ? "?cluster=" + encodeURIComponent(clusterName) : ""));
+	topologyEventTypes.forEach(function (eventType) {
+		eventSource.addEventListener(eventType, function () {
+			secondsTillRefresh = Math.min(secondsTillRefresh, eventRefreshDelaySeconds);
+		});
+	});
+}
 		
 function showLoader() {
     $(".ajaxLoader").css('visibility', 'visible');
@@ -118,16 +139,30 @@ function addInfo(alertText) {
 	return addAlert(alertText, "info");
 }
 
-function apiCommand(uri) {
+// apiPost issues a state changing API request via POST, with given data as JSON body.
+// The CSRF token cookie is echoed in the X-CSRF-Token header.
+function apiPost(uri, data, callback) {
+    return $.ajax({
+        type: "POST",
+        url: uri,
+        data: JSON.stringify(data || {}),
+        contentType: "application/json",
+        dataType: "json",
+        headers: { "X-CSRF-Token": $.cookie("orchestrator-csrf-token") },
+        success: callback
+    });
+}
+
+function apiCommand(uri, data) {
 	showLoader();
-    $.get(uri, function (operationResult) {
+    apiPost(uri, data, function (operationResult) {
 		hideLoader();
 		if (operationResult.Code == "ERROR") {
 			addAlert(operationResult.Message)
 		} else {
 			reloadWithOperationResult(operationResult);
 		}	
-    }, "json");	
+    });	
     return false;
 }
 
@@ -179,6 +214,13 @@ function openNodeModal(node) {
     if (node.MasterKey.Hostname) {
         var td = addNodeModalDataAttribute("Master", node.masterTitle);
         $('#node_modal button[data-btn=reset-slave]').appendTo(td.find("div"))
+        if (node.ReplicationChannels && node.ReplicationChannels.length > 1) {
+            node.ReplicationChannels.forEach(function (channel) {
+                addNodeModalDataAttribute("Channel " + (channel.ChannelName || "(default)"),
+                    channel.MasterKey.Hostname + ":" + channel.MasterKey.Port + ", " +
+                    (channel.Slave_SQL_Running && channel.Slave_IO_Running ? "replicating" : "not replicating"));
+            });
+        }
         
         td = addNodeModalDataAttribute("Replication running", booleanString(node.replicationRunning));
         $('#node_modal button[data-btn=start-slave]').appendTo(td.find("div"))
@@ -192,6 +234,18 @@ function openNodeModal(node) {
         addNodeModalDataAttribute("Seconds behind master", node.SecondsBehindMaster.Valid ? node.SecondsBehindMaster.Int64 : "null");
         addNodeModalDataAttribute("Replication lag", node.SlaveLagSeconds.Valid ? node.SlaveLagSeconds.Int64 : "null");
         addNodeModalDataAttribute("SQL delay", node.SQLDelay);
+        if (node.HasReplicationFilters) {
+            var filters = node.ReplicationFilters;
+            addNodeModalDataAttribute("Replication filters", [
+                ["Do DB", filters.DoDB], ["Ignore DB", filters.IgnoreDB],
+                ["Do table", filters.DoTable], ["Ignore table", filters.IgnoreTable],
+                ["Wild do table", filters.WildDoTable], ["Wild ignore table", filters.WildIgnoreTable]
+            ].filter(function (filter) {
+                return filter[1] && filter[1].length > 0;
+            }).map(function (filter) {
+                return filter[0] + ": " + filter[1].join(",");
+            }).join("<br/>"));
+        }
     }
     var td = addNodeModalDataAttribute("Num slaves", node.SlaveHosts.length);
     $('#node_modal button[data-btn=move-up-slaves]').appendTo(td.find("div"))
@@ -202,7 +256,18 @@ function openNodeModal(node) {
     var td = addNodeModalDataAttribute("Read only", booleanString(node.ReadOnly));
     $('#node_modal button[data-btn=set-read-only]').appendTo(td.find("div"))
     $('#node_modal button[data-btn=set-writeable]').appendTo(td.find("div"))
+    if (node.Tags && Object.keys(node.Tags).length > 0) {
+        addNodeModalDataAttribute("Tags", Object.keys(node.Tags).sort().map(function (tagName) {
+            return $('<div/>').text(tagName + "=" + node.Tags[tagName]).html();
+        }).join("<br/>"));
+    }
 
+    if (node.SemiSyncMasterEnabled) {
+        addNodeModalDataAttribute("Semi-sync master", (node.SemiSyncMasterStatus ? "active" : "inactive") + ", " + node.SemiSyncMasterClients + " clients");
+    }
+    if (node.SemiSyncSlaveEnabled) {
+        addNodeModalDataAttribute("Semi-sync slave", node.SemiSyncSlaveStatus ? "active" : "inactive");
+    }
     addNodeModalDataAttribute("Binlog format", node.Binlog_format);
     addNodeModalDataAttribute("Has binary logs", booleanString(node.LogBinEnabled));
     var td = addNodeModalDataAttribute("Logs slave updates", booleanString(node.LogSlaveUpdatesEnabled));
@@ -225,8 +290,8 @@ function openNodeModal(node) {
     	if (!$("#beginMaintenanceReason").val()) {
     		return addModalAlert("You must fill the reason field");
     	}
-    	var uri = "/api/begin-maintenance/"+node.Key.Hostname+"/"+node.Key.Port + "/" + $("#beginMaintenanceOwner").val() + "/" + $("#beginMaintenanceReason").val();
-    	apiCommand(uri);
+    	var uri = "/api/begin-maintenance/"+node.Key.Hostname+"/"+node.Key.Port;
+    	apiCommand(uri, { owner: $("#beginMaintenanceOwner").val(), reason: $("#beginMaintenanceReason").val() });
     });
     $('#node_modal button[data-btn=end-maintenance]').click(function(){
     	apiCommand("/api/end-maintenance/"+node.Key.Hostname+"/"+node.Key.Port);
@@ -369,6 +434,12 @@ function normalizeInstance(instance) {
     instance.masterTitle = instance.MasterKey.Hostname + ":" + instance.MasterKey.Port;
     instance.masterId = getInstanceId(instance.MasterKey.Hostname,
             instance.MasterKey.Port);
+    // multi-source replication: masters other than the default channel's
+    instance.additionalMasterIds = (instance.ReplicationChannels || []).map(function (channel) {
+        return getInstanceId(channel.MasterKey.Hostname, channel.MasterKey.Port);
+    }).filter(function (masterId) {
+        return masterId != instance.masterId;
+    });
 
     instance.replicationRunning = instance.Slave_SQL_Running && instance.Slave_IO_Running;
     instance.replicationAttemptingToRun = instance.Slave_SQL_Running || instance.Slave_IO_Running;
@@ -382,6 +453,7 @@ function normalizeInstance(instance) {
     instance.parent = null;
     instance.hasMaster = true;
     instance.masterNode = null;
+    instance.additionalMasterNodes = [];
     instance.inMaintenance = false;
     instance.maintenanceEntry = null;
     instance.isFirstChildInDisplay = false
@@ -494,6 +566,11 @@ function normalizeInstances(instances, maintenanceList) {
             instance.parent = null;
             instance.masterNode = null;
         }
+        instance.additionalMasterIds.forEach(function (masterId) {
+            if (instancesMap[masterId]) {
+                instance.additionalMasterNodes.push(instancesMap[masterId]);
+            }
+        });
     });
 
     instances.forEach(function (instance) {
@@ -575,6 +652,12 @@ function renderInstanceElement(popoverElement, instance, renderType) {
 	    if (instance.CountMySQLSnapshots > 0) {
 	    	popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-camera" title="'+instance.CountMySQLSnapshots +' snapshots"></span> ');
 	    } 
+	    if (instance.additionalMasterIds && instance.additionalMasterIds.length > 0) {
+	    	popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-random" title="Multi-source: '+(instance.additionalMasterIds.length+1)+' masters"></span> ');
+	    } 
+	    if (instance.SemiSyncMasterEnabled || instance.SemiSyncSlaveEnabled) {
+	    	popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-check" title="Semi-sync '+(instance.SemiSyncMasterEnabled ? 'master' : 'slave')+'"></span> ');
+	    } 
 	    if (instance.HasReplicationFilters) {
 	    	popoverElement.find("h3 div.pull-right").prepend('<span class="glyphicon glyphicon-filter" title="Using replication filters"></span> ');
 	    } 
@@ -0,0 +1,49 @@
+
+$(document).ready(function () {
+    showLoader();
+    var apiUri = "/api/scheduled-windows";
+    if (currentClusterName()) {
+        apiUri = "/api/scheduled-windows/"+currentClusterName();
+    }
+    $.get(apiUri, function (windows) {
+            displayWindows(windows);
+    	}, "json");
+    function displayWindows(windows) {
+        hideLoader();
+        windows.forEach(function (scheduledWindow) {
+    		var row = jQuery('<tr/>');
+    		var target = scheduledWindow.ClusterName ? "cluster: " + scheduledWindow.ClusterName : scheduledWindow.Key.Hostname + ":" + scheduledWindow.Key.Port;
+    		jQuery('<td/>', { text: scheduledWindow.WindowId }).appendTo(row);
+    		jQuery('<td/>', { text: scheduledWindow.WindowType }).appendTo(row);
+    		jQuery('<td/>', { text: target }).appendTo(row);
+    		jQuery('<td/>', { text: scheduledWindow.StartTimestamp }).appendTo(row);
+    		jQuery('<td/>', { text: scheduledWindow.DurationSeconds }).appendTo(row);
+    		jQuery('<td/>', { text: scheduledWindow.Recurrence || "once" }).appendTo(row);
+    		jQuery('<td/>', { text: booleanString(scheduledWindow.IsActive) }).appendTo(row);
+    		jQuery('<td/>', { text: scheduledWindow.Owner }).appendTo(row);
+    		jQuery('<td/>', { text: scheduledWindow.Reason }).appendTo(row);
+    		var actionCell = jQuery('<td/>').appendTo(row);
+    		if (isAuthorizedForAction()) {
+    			jQuery('<button/>', { text: "Unschedule", "class": "btn btn-xs btn-danger", "data-command": "unschedule-window", "data-window-id": scheduledWindow.WindowId }).appendTo(actionCell);
+    		}
+    		row.appendTo('#scheduled_windows tbody');
+    	});
+    }
+    $("body").on("click", "button[data-command=unschedule-window]", function(event) {
+    	var windowId = $(event.target).attr("data-window-id");
+    	var message = "Are you sure you wish to unschedule window <code><strong>" + windowId + "</strong></code>?";
+    	bootbox.confirm(message, function(confirm) {
+			if (confirm) {
+		    	showLoader();
+		        apiPost("/api/unschedule-window/"+windowId, {}, function (operationResult) {
+					hideLoader();
+					if (operationResult.Code == "ERROR") {
+						addAlert(operationResult.Message)
+					} else {
+						location.reload();
+					}	
+		        });
+			}
+        });
+    });
+});	
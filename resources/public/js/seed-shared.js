@@ -43,14 +43,14 @@ $("body").on("click", "button[data-command=abort-seed]", function(event) {
 	bootbox.confirm(message, function(confirm) {
 		if (confirm) {
 	    	showLoader();
-	        $.get("/api/agent-abort-seed/"+seedId, function (operationResult) {
+	        apiPost("/api/agent-abort-seed/"+seedId, {}, function (operationResult) {
 				hideLoader();
 				if (operationResult.Code == "ERROR") {
 					addAlert(operationResult.Message)
 				} else {
 					location.reload();
 				}	
-	        }, "json");
+	        });
 		}
 	});
 });
@@ -896,6 +896,11 @@ func ReadRecentSeeds() ([]SeedOperation, error) {
 	return readSeeds("", "limit 100")
 }
 
+// ReadSeeds reads a range of latest seeds from backend table.
+func ReadSeeds(offset int, limit int) ([]SeedOperation, error) {
+	return readSeeds("", fmt.Sprintf("limit %d offset %d", limit, offset))
+}
+
 // SeedOperationState reads states for a given seed operation
 func ReadSeedStates(seedId int64) ([]SeedOperationState, error) {
 	res := []SeedOperationState{}
@@ -26,6 +26,7 @@ import (
 	"net"
 	"os"
 	"os/user"
+	"sort"
 	"strings"
 )
 
@@ -78,7 +79,7 @@ func getClusterName(clusterAlias string, instanceKey *inst.InstanceKey) (cluster
 }
 
 // Cli initiates a command line interface, executing requested command.
-func Cli(command string, strict bool, instance string, sibling string, owner string, reason string, duration string, pattern string, clusterAlias string, pool string) {
+func Cli(command string, strict bool, instance string, sibling string, owner string, reason string, duration string, pattern string, clusterAlias string, pool string, promotionRule string, channel string, tag string, concurrency uint, start string, recurrence string, windowId uint) {
 
 	if instance != "" && !strings.Contains(instance, ":") {
 		instance = fmt.Sprintf("%s:%d", instance, config.Config.DefaultInstancePort)
@@ -187,7 +188,7 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 				log.Fatal("Cannot deduce instance:", instance)
 			}
 			// siblingKey can be null, in which case the instance repoints to its existing master
-			instance, err := inst.Repoint(instanceKey, siblingKey)
+			instance, err := inst.RepointChannel(instanceKey, siblingKey, channel)
 			if err != nil {
 				log.Fatale(err)
 			}
@@ -374,6 +375,76 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 				fmt.Println(promotedInstance.Key.DisplayString())
 			}
 		}
+	case cliCommand("ack-recovery"):
+		{
+			if reason == "" {
+				log.Fatal("--reason option required (comment on acknowledgement)")
+			}
+			var countAcknowledged int64
+			if clusterAlias != "" {
+				clusterName := getClusterName(clusterAlias, instanceKey)
+				countAcknowledged, err = orchestrator.AcknowledgeClusterRecoveries(clusterName, inst.GetMaintenanceOwner(), reason)
+			} else {
+				if instanceKey == nil {
+					log.Fatal("Cannot deduce instance:", instance)
+				}
+				countAcknowledged, err = orchestrator.AcknowledgeInstanceRecoveries(instanceKey, inst.GetMaintenanceOwner(), reason)
+			}
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(countAcknowledged)
+		}
+	case cliCommand("unacknowledged-recoveries"):
+		{
+			recoveries, err := orchestrator.ReadUnacknowledgedRecoveries()
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, recovery := range recoveries {
+				fmt.Println(fmt.Sprintf("%d\t%s\t%s (cluster %s): %s", recovery.TopologyRecoveryId, recovery.RecoveryStartTimestamp, recovery.AnalysisEntry.AnalyzedInstanceKey.DisplayString(), recovery.AnalysisEntry.ClusterName, recovery.AnalysisEntry.Analysis))
+			}
+		}
+	case cliCommand("recovery-blocks"):
+		{
+			recoveryBlocks, err := orchestrator.ReadActiveRecoveryBlocks()
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, recoveryBlock := range recoveryBlocks {
+				clusterName := recoveryBlock.ClusterName
+				if clusterName == "" {
+					clusterName = "(global)"
+				}
+				fmt.Println(fmt.Sprintf("%d\t%s\t%s\t%s", recoveryBlock.BlockId, clusterName, recoveryBlock.StartTimestamp, recoveryBlock.Reason))
+			}
+		}
+	case cliCommand("ack-recovery-blocks"):
+		{
+			clusterName := ""
+			if instance != "" || clusterAlias != "" {
+				clusterName = getClusterName(clusterAlias, instanceKey)
+			}
+			countAcknowledged, err := orchestrator.AcknowledgeRecoveryBlocks(clusterName, inst.GetMaintenanceOwner())
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(countAcknowledged)
+		}
+	case cliCommand("blocked-recoveries"):
+		{
+			clusterName := ""
+			if instance != "" || clusterAlias != "" {
+				clusterName = getClusterName(clusterAlias, instanceKey)
+			}
+			blockedRecoveries, err := orchestrator.ReadBlockedRecoveries(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, blockedRecovery := range blockedRecoveries {
+				fmt.Println(fmt.Sprintf("%s (cluster %s): %s: %s", blockedRecovery.FailedInstanceKey.DisplayString(), blockedRecovery.ClusterName, blockedRecovery.Analysis, blockedRecovery.BlockingReason))
+			}
+		}
 	case cliCommand("last-pseudo-gtid"):
 		{
 			if instanceKey == nil {
@@ -404,7 +475,7 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 			if instanceKey == nil {
 				log.Fatal("Cannot deduce instance:", instance)
 			}
-			_, err := inst.StopSlave(instanceKey)
+			_, err := inst.StopSlaveChannel(instanceKey, channel)
 			if err != nil {
 				log.Fatale(err)
 			}
@@ -418,7 +489,7 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 			if instanceKey == nil {
 				log.Fatal("Cannot deduce instance:", instance)
 			}
-			_, err := inst.StartSlave(instanceKey)
+			_, err := inst.StartSlaveChannel(instanceKey, channel)
 			if err != nil {
 				log.Fatale(err)
 			}
@@ -621,6 +692,237 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 			}
 			fmt.Println(instanceKey.DisplayString())
 		}
+	case cliCommand("register-promotion-rule"):
+		{
+			if instanceKey == nil {
+				instanceKey = thisInstanceKey
+			}
+			if instanceKey == nil {
+				log.Fatal("Cannot deduce instance:", instance)
+			}
+			if promotionRule == "" {
+				log.Fatal("--promotion-rule option required")
+			}
+			rule, err := inst.ParseCandidatePromotionRule(promotionRule)
+			if err != nil {
+				log.Fatale(err)
+			}
+			var durationSeconds int = 0
+			if duration != "" {
+				durationSeconds, err = util.SimpleTimeToSeconds(duration)
+				if err != nil {
+					log.Fatale(err)
+				}
+				if durationSeconds < 0 {
+					log.Fatalf("Duration value must be non-negative. Given value: %d", durationSeconds)
+				}
+			}
+			err = inst.RegisterPromotionRule(instanceKey, rule, uint(durationSeconds))
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(instanceKey.DisplayString())
+		}
+	case cliCommand("unregister-promotion-rule"):
+		{
+			if instanceKey == nil {
+				instanceKey = thisInstanceKey
+			}
+			if instanceKey == nil {
+				log.Fatal("Cannot deduce instance:", instance)
+			}
+			err := inst.UnregisterPromotionRule(instanceKey)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(instanceKey.DisplayString())
+		}
+	case cliCommand("promotion-rules"):
+		{
+			promotionRules, err := inst.ReadPromotionRules("")
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, promotionRule := range promotionRules {
+				fmt.Println(fmt.Sprintf("%s\t%s\t%s", promotionRule.Key.DisplayString(), promotionRule.Rule, promotionRule.ExpireTimestamp))
+			}
+		}
+	case cliCommand("tag"):
+		{
+			if instanceKey == nil {
+				instanceKey = thisInstanceKey
+			}
+			if instanceKey == nil {
+				log.Fatalf("Unable to get instance: unresolved instance")
+			}
+			instanceTag, err := inst.ParseTag(tag, false)
+			if err != nil {
+				log.Fatale(err)
+			}
+			err = inst.TagInstance(instanceKey, instanceTag)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(instanceKey.DisplayString())
+		}
+	case cliCommand("untag"):
+		{
+			if instanceKey == nil {
+				instanceKey = thisInstanceKey
+			}
+			if instanceKey == nil {
+				log.Fatalf("Unable to get instance: unresolved instance")
+			}
+			instanceTag, err := inst.ParseTag(tag, true)
+			if err != nil {
+				log.Fatale(err)
+			}
+			err = inst.UntagInstance(instanceKey, instanceTag.TagName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(instanceKey.DisplayString())
+		}
+	case cliCommand("instance-tags"):
+		{
+			if instanceKey == nil {
+				instanceKey = thisInstanceKey
+			}
+			if instanceKey == nil {
+				log.Fatalf("Unable to get instance: unresolved instance")
+			}
+			tags, err := inst.ReadInstanceTags(instanceKey)
+			if err != nil {
+				log.Fatale(err)
+			}
+			tagNames := []string{}
+			for tagName := range tags {
+				tagNames = append(tagNames, tagName)
+			}
+			sort.Strings(tagNames)
+			for _, tagName := range tagNames {
+				fmt.Println(fmt.Sprintf("%s=%s", tagName, tags[tagName]))
+			}
+		}
+	case cliCommand("tagged"):
+		{
+			if tag == "" {
+				log.Fatal("No tag selector given")
+			}
+			instances, err := inst.ReadTaggedInstances(tag)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, instance := range instances {
+				fmt.Println(instance.Key.DisplayString())
+			}
+		}
+	case cliCommand("bulk-set-read-only"), cliCommand("bulk-set-writeable"), cliCommand("bulk-start-slave"), cliCommand("bulk-stop-slave"),
+		cliCommand("bulk-stop-slave-nice"), cliCommand("bulk-begin-downtime"), cliCommand("bulk-end-downtime"),
+		cliCommand("bulk-begin-maintenance"), cliCommand("bulk-end-maintenance"), cliCommand("bulk-refresh"):
+		{
+			operation := strings.TrimPrefix(command, "bulk-")
+			selector := &inst.InstanceSelector{Pattern: pattern, Pool: pool, Tag: tag}
+			if clusterAlias != "" || instanceKey != nil {
+				selector.ClusterName = getClusterName(clusterAlias, instanceKey)
+			}
+			if selector.IsEmpty() {
+				log.Fatal("Expecting selector: cluster (-i or -alias), --pattern, --pool and/or --tag")
+			}
+			operationParams := &inst.BulkOperationParams{Owner: inst.GetMaintenanceOwner(), Reason: reason}
+			if operation == inst.BulkBeginDowntime || operation == inst.BulkBeginMaintenance {
+				if reason == "" {
+					log.Fatal("--reason option required")
+				}
+				if duration != "" {
+					durationSeconds, err := util.SimpleTimeToSeconds(duration)
+					if err != nil {
+						log.Fatale(err)
+					}
+					if durationSeconds < 0 {
+						log.Fatalf("Duration value must be non-negative. Given value: %d", durationSeconds)
+					}
+					operationParams.DurationSeconds = uint(durationSeconds)
+				}
+			}
+			results, err := inst.ExecuteBulkOperation(selector, operation, operationParams, int(concurrency))
+			if err != nil {
+				log.Fatale(err)
+			}
+			countFailures := 0
+			for _, result := range results {
+				status := "ok"
+				if !result.Success {
+					status = "error"
+					countFailures++
+				}
+				fmt.Println(fmt.Sprintf("%s\t%s\t%s", result.Key.DisplayString(), status, result.Message))
+			}
+			if countFailures > 0 {
+				log.Fatalf("%s failed on %d out of %d instances", command, countFailures, len(results))
+			}
+		}
+	case cliCommand("schedule-maintenance"), cliCommand("schedule-downtime"):
+		{
+			window := &inst.ScheduledMaintenanceWindow{
+				WindowType:     strings.TrimPrefix(command, "schedule-"),
+				StartTimestamp: start,
+				Recurrence:     recurrence,
+				Owner:          owner,
+				Reason:         reason,
+			}
+			if window.Owner == "" {
+				window.Owner = inst.GetMaintenanceOwner()
+			}
+			if clusterAlias != "" {
+				window.ClusterName = getClusterName(clusterAlias, nil)
+			} else if instanceKey != nil {
+				window.Key = *instanceKey
+			} else {
+				log.Fatal("Expecting either -i (instance) or -alias (cluster wide)")
+			}
+			if duration == "" {
+				log.Fatal("--duration option required")
+			}
+			durationSeconds, err := util.SimpleTimeToSeconds(duration)
+			if err != nil {
+				log.Fatale(err)
+			}
+			if durationSeconds <= 0 {
+				log.Fatalf("Duration value must be positive. Given value: %d", durationSeconds)
+			}
+			window.DurationSeconds = uint(durationSeconds)
+			scheduledWindowId, err := inst.CreateScheduledMaintenanceWindow(window)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(scheduledWindowId)
+		}
+	case cliCommand("scheduled-windows"):
+		{
+			clusterName := ""
+			if clusterAlias != "" || instanceKey != nil {
+				clusterName = getClusterName(clusterAlias, instanceKey)
+			}
+			windows, err := inst.ReadScheduledMaintenanceWindows(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, window := range windows {
+				fmt.Println(fmt.Sprintf("%d\t%s\t%s\t%s\t%d\t%s\t%t\t%s\t%s", window.WindowId, window.WindowType, window.TargetDescription(), window.StartTimestamp, window.DurationSeconds, window.Recurrence, window.IsActive, window.Owner, window.Reason))
+			}
+		}
+	case cliCommand("unschedule-window"):
+		{
+			if windowId == 0 {
+				log.Fatal("--window option required")
+			}
+			err := inst.DeleteScheduledMaintenanceWindow(windowId)
+			if err != nil {
+				log.Fatale(err)
+			}
+			fmt.Println(windowId)
+		}
 	case cliCommand("submit-pool-instances"):
 		{
 			if pool == "" {
@@ -652,10 +954,19 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 		}
 	case cliCommand("find"):
 		{
-			if pattern == "" {
+			if pattern == "" && tag == "" {
 				log.Fatal("No pattern given")
 			}
-			instances, err := inst.FindInstances(pattern)
+			var instances [](*inst.Instance)
+			var err error
+			if pattern == "" {
+				instances, err = inst.ReadTaggedInstances(tag)
+			} else {
+				instances, err = inst.FindInstances(pattern)
+				if err == nil {
+					instances, err = inst.FilterInstancesByTagSelector(instances, tag)
+				}
+			}
 			if err != nil {
 				log.Fatale(err)
 			} else {
@@ -736,10 +1047,25 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 			if err != nil {
 				log.Fatale(err)
 			}
+			instances, err = inst.FilterInstancesByTagSelector(instances, tag)
+			if err != nil {
+				log.Fatale(err)
+			}
 			for _, clusterInstance := range instances {
 				fmt.Println(clusterInstance.Key.DisplayString())
 			}
 		}
+	case cliCommand("replication-filters-divergence"):
+		{
+			clusterName := getClusterName(clusterAlias, instanceKey)
+			divergences, err := inst.GetClusterReplicationFiltersDivergence(clusterName)
+			if err != nil {
+				log.Fatale(err)
+			}
+			for _, divergence := range divergences {
+				fmt.Println(fmt.Sprintf("%s\t%s\t%s", divergence.Key.DisplayString(), divergence.MasterKey.DisplayString(), strings.Join(divergence.Diff, "; ")))
+			}
+		}
 	case cliCommand("which-cluster-osc-slaves"):
 		{
 			clusterName := getClusterName(clusterAlias, instanceKey)
@@ -747,6 +1073,10 @@ func Cli(command string, strict bool, instance string, sibling string, owner str
 			if err != nil {
 				log.Fatale(err)
 			}
+			instances, err = inst.FilterInstancesByTagSelector(instances, tag)
+			if err != nil {
+				log.Fatale(err)
+			}
 			for _, clusterInstance := range instances {
 				fmt.Println(clusterInstance.Key.DisplayString())
 			}
@@ -0,0 +1,418 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package app
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/client"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/inst"
+	"github.com/outbrain/orchestrator/logic"
+	"net/url"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// remoteOutput indicates how the result of a remotely executed command is printed
+type remoteOutput int
+
+const (
+	remoteOutputMessage             remoteOutput = iota // the API response message
+	remoteOutputInstanceKey                             // the operated instance
+	remoteOutputInstanceMaster                          // instance<master, of the instance in the response
+	remoteOutputInstanceKeys                            // keys of the instances in the response
+	remoteOutputStrings                                 // the strings in the response
+	remoteOutputClusterName                             // cluster name of the instance in the response
+	remoteOutputMasterKey                               // master of the instance in the response
+	remoteOutputTags                                    // name=value tags in the response
+	remoteOutputTopology                                // ASCII topology of the instances in the response
+	remoteOutputReplicationAnalysis                     // replication analysis entries in the response
+	remoteOutputRecoveries                              // recoveries in the response
+	remoteOutputJSON                                    // the response, as JSON
+)
+
+// remoteCommand describes how a CLI command is executed via the HTTP API
+type remoteCommand struct {
+	method string
+	// routes are API routes in order of preference. The first route whose params are all given is used.
+	// :host/:port stand for the -i instance; :siblingHost/:siblingPort, :belowHost/:belowPort and
+	// :candidateHost/:candidatePort for the -s instance; :clusterAlias for -alias; :clusterName for
+	// the cluster of -alias or of the -i instance.
+	routes []string
+	output remoteOutput
+}
+
+var remoteCommands = map[string]remoteCommand{
+	"skip-query":                   {"POST", []string{"/api/skip-query/:host/:port"}, remoteOutputInstanceKey},
+	"move-up":                      {"POST", []string{"/api/move-up/:host/:port"}, remoteOutputInstanceMaster},
+	"move-up-slaves":               {"POST", []string{"/api/move-up-slaves/:host/:port"}, remoteOutputMessage},
+	"move-below":                   {"POST", []string{"/api/move-below/:host/:port/:siblingHost/:siblingPort"}, remoteOutputInstanceMaster},
+	"repoint":                      {"POST", []string{"/api/repoint/:host/:port/:belowHost/:belowPort", "/api/repoint/:host/:port"}, remoteOutputInstanceMaster},
+	"enslave-siblings":             {"POST", []string{"/api/enslave-siblings/:host/:port"}, remoteOutputMessage},
+	"enslave-master":               {"POST", []string{"/api/enslave-master/:host/:port"}, remoteOutputMessage},
+	"make-co-master":               {"POST", []string{"/api/make-co-master/:host/:port"}, remoteOutputInstanceMaster},
+	"match-below":                  {"POST", []string{"/api/match-below/:host/:port/:belowHost/:belowPort"}, remoteOutputInstanceMaster},
+	"match-up":                     {"POST", []string{"/api/match-up/:host/:port"}, remoteOutputInstanceMaster},
+	"multi-match-slaves":           {"POST", []string{"/api/multi-match-slaves/:host/:port/:belowHost/:belowPort"}, remoteOutputMessage},
+	"match-up-slaves":              {"POST", []string{"/api/match-up-slaves/:host/:port"}, remoteOutputMessage},
+	"regroup-slaves":               {"POST", []string{"/api/regroup-slaves/:host/:port"}, remoteOutputMessage},
+	"recover":                      {"POST", []string{"/api/recover/:host/:port/:candidateHost/:candidatePort", "/api/recover/:host/:port"}, remoteOutputMessage},
+	"ack-recovery":                 {"POST", []string{"/api/ack-recovery/cluster/alias/:clusterAlias", "/api/ack-recovery/instance/:host/:port"}, remoteOutputMessage},
+	"unacknowledged-recoveries":    {"GET", []string{"/api/unacknowledged-recoveries"}, remoteOutputRecoveries},
+	"recovery-blocks":              {"GET", []string{"/api/recovery-blocks"}, remoteOutputJSON},
+	"ack-recovery-blocks":          {"POST", []string{"/api/ack-recovery-blocks/cluster/:clusterName", "/api/ack-recovery-blocks"}, remoteOutputMessage},
+	"blocked-recoveries":           {"GET", []string{"/api/blocked-recoveries/cluster/:clusterName", "/api/blocked-recoveries"}, remoteOutputJSON},
+	"last-pseudo-gtid":             {"GET", []string{"/api/last-pseudo-gtid/:host/:port"}, remoteOutputJSON},
+	"stop-slave":                   {"POST", []string{"/api/stop-slave/:host/:port"}, remoteOutputInstanceKey},
+	"start-slave":                  {"POST", []string{"/api/start-slave/:host/:port"}, remoteOutputInstanceKey},
+	"reset-slave":                  {"POST", []string{"/api/reset-slave/:host/:port"}, remoteOutputInstanceKey},
+	"detach-slave":                 {"POST", []string{"/api/detach-slave/:host/:port"}, remoteOutputInstanceKey},
+	"reattach-slave":               {"POST", []string{"/api/reattach-slave/:host/:port"}, remoteOutputInstanceKey},
+	"set-read-only":                {"POST", []string{"/api/set-read-only/:host/:port"}, remoteOutputInstanceKey},
+	"set-writeable":                {"POST", []string{"/api/set-writeable/:host/:port"}, remoteOutputInstanceKey},
+	"discover":                     {"POST", []string{"/api/discover/:host/:port"}, remoteOutputInstanceKey},
+	"forget":                       {"POST", []string{"/api/forget/:host/:port"}, remoteOutputInstanceKey},
+	"begin-maintenance":            {"POST", []string{"/api/begin-maintenance/:host/:port"}, remoteOutputInstanceKey},
+	"end-maintenance":              {"POST", []string{"/api/end-maintenance/:host/:port"}, remoteOutputInstanceKey},
+	"begin-downtime":               {"POST", []string{"/api/begin-downtime/:host/:port"}, remoteOutputInstanceKey},
+	"end-downtime":                 {"POST", []string{"/api/end-downtime/:host/:port"}, remoteOutputInstanceKey},
+	"register-promotion-rule":      {"POST", []string{"/api/register-promotion-rule/:host/:port/:promotionRule"}, remoteOutputInstanceKey},
+	"unregister-promotion-rule":    {"POST", []string{"/api/unregister-promotion-rule/:host/:port"}, remoteOutputInstanceKey},
+	"promotion-rules":              {"GET", []string{"/api/promotion-rules/:clusterName", "/api/promotion-rules"}, remoteOutputJSON},
+	"tag":                          {"POST", []string{"/api/tag/:host/:port"}, remoteOutputInstanceKey},
+	"untag":                        {"POST", []string{"/api/untag/:host/:port"}, remoteOutputInstanceKey},
+	"instance-tags":                {"GET", []string{"/api/tags/:host/:port"}, remoteOutputTags},
+	"tagged":                       {"GET", []string{"/api/tagged"}, remoteOutputInstanceKeys},
+	"scheduled-windows":            {"GET", []string{"/api/scheduled-windows/:clusterName", "/api/scheduled-windows"}, remoteOutputJSON},
+	"unschedule-window":            {"POST", []string{"/api/unschedule-window/:windowId"}, remoteOutputMessage},
+	"clusters":                     {"GET", []string{"/api/clusters"}, remoteOutputStrings},
+	"find":                         {"GET", []string{"/api/search"}, remoteOutputInstanceKeys},
+	"topology":                     {"GET", []string{"/api/cluster/alias/:clusterAlias", "/api/cluster/:clusterName"}, remoteOutputTopology},
+	"which-instance":               {"GET", []string{"/api/instance/:host/:port"}, remoteOutputJSON},
+	"which-master":                 {"GET", []string{"/api/instance/:host/:port"}, remoteOutputMasterKey},
+	"which-cluster":                {"GET", []string{"/api/instance/:host/:port"}, remoteOutputClusterName},
+	"which-cluster-instances":      {"GET", []string{"/api/cluster/alias/:clusterAlias", "/api/cluster/:clusterName"}, remoteOutputInstanceKeys},
+	"which-cluster-osc-slaves":     {"GET", []string{"/api/cluster-osc-slaves/:clusterName"}, remoteOutputInstanceKeys},
+	"replication-analysis":         {"GET", []string{"/api/replication-analysis"}, remoteOutputReplicationAnalysis},
+	"reset-hostname-resolve-cache": {"POST", []string{"/api/reset-hostname-resolve-cache"}, remoteOutputMessage},
+}
+
+// remoteCli executes CLI commands via the HTTP API of an orchestrator service
+type remoteCli struct {
+	client       *client.Client
+	instanceKey  *inst.InstanceKey
+	siblingKey   *inst.InstanceKey
+	clusterAlias string
+	// explicitInstance indicates the instance was given via -i, rather than implied as the local host
+	explicitInstance bool
+}
+
+// routeParams returns the values of route params which can be deduced from command line arguments.
+// With explicitCluster, a cluster name is only deduced from -alias or an explicitly given -i instance.
+func (this *remoteCli) routeParams(routeParamNames []string, extraParams map[string]string, explicitCluster bool) (map[string]string, bool) {
+	routeParams := map[string]string{}
+	for _, name := range routeParamNames {
+		switch name {
+		case "host", "port":
+			if this.instanceKey == nil {
+				return nil, false
+			}
+			routeParams["host"] = this.instanceKey.Hostname
+			routeParams["port"] = strconv.Itoa(this.instanceKey.Port)
+		case "siblingHost", "siblingPort", "belowHost", "belowPort", "candidateHost", "candidatePort":
+			if this.siblingKey == nil {
+				return nil, false
+			}
+			prefix := strings.TrimSuffix(strings.TrimSuffix(name, "Host"), "Port")
+			routeParams[prefix+"Host"] = this.siblingKey.Hostname
+			routeParams[prefix+"Port"] = strconv.Itoa(this.siblingKey.Port)
+		case "clusterAlias":
+			if this.clusterAlias == "" {
+				return nil, false
+			}
+			routeParams[name] = this.clusterAlias
+		case "clusterName":
+			if this.clusterAlias == "" && this.instanceKey == nil {
+				return nil, false
+			}
+			if this.clusterAlias == "" && explicitCluster && !this.explicitInstance {
+				return nil, false
+			}
+			routeParams[name] = this.getClusterName()
+		default:
+			if extraParams[name] == "" {
+				return nil, false
+			}
+			routeParams[name] = extraParams[name]
+		}
+	}
+	return routeParams, true
+}
+
+// getClusterName deduces the cluster name of -alias, or else of the -i instance
+func (this *remoteCli) getClusterName() string {
+	if this.clusterAlias != "" {
+		response, err := this.client.Request("GET", "/api/cluster/alias/"+this.clusterAlias, nil)
+		if err != nil {
+			log.Fatale(err)
+		}
+		instances := [](*inst.Instance){}
+		if err := json.Unmarshal(response.Details, &instances); err != nil {
+			log.Fatale(err)
+		}
+		if len(instances) == 0 {
+			log.Fatalf("Unable to determine cluster name of alias %s", this.clusterAlias)
+		}
+		return instances[0].ClusterName
+	}
+	instance := this.readInstance()
+	if instance.ClusterName == "" {
+		log.Fatalf("Unable to determine cluster name")
+	}
+	return instance.ClusterName
+}
+
+// readInstance reads the -i instance
+func (this *remoteCli) readInstance() *inst.Instance {
+	response, err := this.client.Request("GET", fmt.Sprintf("/api/instance/%s/%d", this.instanceKey.Hostname, this.instanceKey.Port), nil)
+	if err != nil {
+		log.Fatale(err)
+	}
+	instance := &inst.Instance{}
+	if err := json.Unmarshal(response.Details, instance); err != nil {
+		log.Fatale(err)
+	}
+	return instance
+}
+
+// route picks the first route of given command whose params can all be deduced, and fills in its params
+func (this *remoteCli) route(command remoteCommand, extraParams map[string]string) (string, bool) {
+	for i, route := range command.routes {
+		tokens := strings.Split(route, "/")
+		routeParamNames := []string{}
+		for _, token := range tokens {
+			if strings.HasPrefix(token, ":") {
+				routeParamNames = append(routeParamNames, strings.TrimPrefix(token, ":"))
+			}
+		}
+		// Where a less specific route follows, the cluster must be given explicitly
+		routeParams, ok := this.routeParams(routeParamNames, extraParams, i < len(command.routes)-1)
+		if !ok {
+			continue
+		}
+		for i, token := range tokens {
+			if strings.HasPrefix(token, ":") {
+				tokens[i] = routeParams[strings.TrimPrefix(token, ":")]
+			}
+		}
+		return strings.Join(tokens, "/"), true
+	}
+	return "", false
+}
+
+// printResponse prints the response of a command according to its output type
+func (this *remoteCli) printResponse(output remoteOutput, response *client.APIResponse) error {
+	switch output {
+	case remoteOutputMessage:
+		fmt.Println(response.Message)
+	case remoteOutputInstanceKey:
+		fmt.Println(this.instanceKey.DisplayString())
+	case remoteOutputInstanceMaster, remoteOutputMasterKey, remoteOutputClusterName:
+		instance := &inst.Instance{}
+		if err := json.Unmarshal(response.Details, instance); err != nil {
+			return err
+		}
+		switch output {
+		case remoteOutputInstanceMaster:
+			fmt.Println(fmt.Sprintf("%s<%s", instance.Key.DisplayString(), instance.MasterKey.DisplayString()))
+		case remoteOutputMasterKey:
+			fmt.Println(instance.MasterKey.DisplayString())
+		case remoteOutputClusterName:
+			fmt.Println(instance.ClusterName)
+		}
+	case remoteOutputInstanceKeys, remoteOutputTopology:
+		instances := [](*inst.Instance){}
+		if err := json.Unmarshal(response.Details, &instances); err != nil {
+			return err
+		}
+		if output == remoteOutputTopology {
+			fmt.Println(inst.ASCIITopologyOfInstances(instances, true))
+			return nil
+		}
+		for _, instance := range instances {
+			fmt.Println(instance.Key.DisplayString())
+		}
+	case remoteOutputStrings:
+		values := []string{}
+		if err := json.Unmarshal(response.Details, &values); err != nil {
+			return err
+		}
+		fmt.Println(strings.Join(values, "\n"))
+	case remoteOutputTags:
+		tags := map[string]string{}
+		if err := json.Unmarshal(response.Details, &tags); err != nil {
+			return err
+		}
+		tagNames := []string{}
+		for tagName := range tags {
+			tagNames = append(tagNames, tagName)
+		}
+		sort.Strings(tagNames)
+		for _, tagName := range tagNames {
+			fmt.Println(fmt.Sprintf("%s=%s", tagName, tags[tagName]))
+		}
+	case remoteOutputReplicationAnalysis:
+		analysis := []inst.ReplicationAnalysis{}
+		if err := json.Unmarshal(response.Details, &analysis); err != nil {
+			return err
+		}
+		for _, entry := range analysis {
+			fmt.Println(fmt.Sprintf("%s (cluster %s): %s", entry.AnalyzedInstanceKey.DisplayString(), entry.ClusterName, entry.Analysis))
+		}
+	case remoteOutputRecoveries:
+		recoveries := []orchestrator.TopologyRecovery{}
+		if err := json.Unmarshal(response.Details, &recoveries); err != nil {
+			return err
+		}
+		for _, recovery := range recoveries {
+			fmt.Println(fmt.Sprintf("%d\t%s\t%s (cluster %s): %s", recovery.TopologyRecoveryId, recovery.RecoveryStartTimestamp, recovery.AnalysisEntry.AnalyzedInstanceKey.DisplayString(), recovery.AnalysisEntry.ClusterName, recovery.AnalysisEntry.Analysis))
+		}
+	case remoteOutputJSON:
+		var details interface{}
+		if err := json.Unmarshal(response.Details, &details); err != nil {
+			return err
+		}
+		blob, err := json.MarshalIndent(details, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(blob))
+	}
+	return nil
+}
+
+// remoteClientConfig returns the API client configuration of remote commands. Basic auth credentials are read
+// from the ORCHESTRATOR_HTTP_USER and ORCHESTRATOR_HTTP_PASSWORD environment variables, or else from the
+// HTTPAuthUser and HTTPAuthPassword config; these are overridden by credentials given in the URLs.
+// With "proxy" AuthenticationMethod, the user given by ORCHESTRATOR_AUTH_USER (default: OS user) is sent
+// in the AuthUserHeader header.
+func remoteClientConfig(remote string) client.Config {
+	clientConfig := client.Config{
+		Endpoints: strings.Split(remote, ","),
+		TLSConfig: &tls.Config{InsecureSkipVerify: config.Config.SSLSkipVerify},
+		Retries:   1, // state changing commands are only retried when not served, see client.Client
+	}
+	for _, endpoint := range clientConfig.Endpoints {
+		if endpointURL, err := url.Parse(endpoint); err == nil && endpointURL.User != nil {
+			// Credentials are in the URLs
+			return clientConfig
+		}
+	}
+	switch config.Config.AuthenticationMethod {
+	case "basic", "multi":
+		clientConfig.User, clientConfig.Password = os.Getenv("ORCHESTRATOR_HTTP_USER"), os.Getenv("ORCHESTRATOR_HTTP_PASSWORD")
+		if clientConfig.User == "" {
+			clientConfig.User, clientConfig.Password = config.Config.HTTPAuthUser, config.Config.HTTPAuthPassword
+		}
+	case "proxy":
+		clientConfig.AuthUserHeader = config.Config.AuthUserHeader
+		clientConfig.AuthUser = os.Getenv("ORCHESTRATOR_AUTH_USER")
+		if clientConfig.AuthUser == "" {
+			if usr, err := user.Current(); err == nil {
+				clientConfig.AuthUser = usr.Username
+			}
+		}
+	}
+	return clientConfig
+}
+
+// RemoteCli executes a command via the HTTP API of an orchestrator service, rather than by directly
+// accessing the backend database and topology servers. remote is a comma delimited list of service
+// endpoints, tried in order. See remoteClientConfig for authentication.
+func RemoteCli(remote string, command string, instance string, sibling string, owner string, reason string, duration string, pattern string, clusterAlias string, pool string, promotionRule string, channel string, tag string, windowId uint) {
+	remoteCommand, found := remoteCommands[command]
+	if !found {
+		log.Fatalf("Command not supported via --remote: %s", command)
+	}
+	orchestratorClient, err := client.NewClient(remoteClientConfig(remote))
+	if err != nil {
+		log.Fatale(err)
+	}
+	cli := &remoteCli{client: orchestratorClient, clusterAlias: clusterAlias, explicitInstance: instance != ""}
+
+	if instance != "" && !strings.Contains(instance, ":") {
+		instance = fmt.Sprintf("%s:%d", instance, config.Config.DefaultInstancePort)
+	}
+	if cli.instanceKey, err = inst.NewRawInstanceKey(instance); err != nil {
+		cli.instanceKey = nil
+		if hostname, err := os.Hostname(); err == nil {
+			cli.instanceKey = &inst.InstanceKey{Hostname: hostname, Port: int(config.Config.DefaultInstancePort)}
+		}
+	}
+	if sibling != "" && !strings.Contains(sibling, ":") {
+		sibling = fmt.Sprintf("%s:%d", sibling, config.Config.DefaultInstancePort)
+	}
+	if cli.siblingKey, err = inst.NewRawInstanceKey(sibling); err != nil {
+		cli.siblingKey = nil
+	}
+	if len(owner) == 0 {
+		// get os username as owner
+		usr, err := user.Current()
+		if err != nil {
+			log.Fatale(err)
+		}
+		owner = usr.Username
+	}
+
+	params := map[string]string{
+		"owner":         owner,
+		"reason":        reason,
+		"comment":       reason,
+		"duration":      duration,
+		"pattern":       pattern,
+		"s":             pattern,
+		"pool":          pool,
+		"promotionRule": promotionRule,
+		"channel":       channel,
+		"tag":           tag,
+	}
+	if windowId > 0 {
+		params["windowId"] = fmt.Sprintf("%d", windowId)
+	}
+	route, ok := cli.route(remoteCommand, params)
+	if !ok {
+		log.Fatalf("Cannot deduce instance/cluster arguments of %s", command)
+	}
+	for key, value := range params {
+		if value == "" {
+			delete(params, key)
+		}
+	}
+	response, err := orchestratorClient.Request(remoteCommand.method, route, params)
+	if err != nil {
+		log.Fatale(err)
+	}
+	if err := cli.printResponse(remoteCommand.output, response); err != nil {
+		log.Fatale(err)
+	}
+}
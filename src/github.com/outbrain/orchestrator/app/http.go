@@ -31,6 +31,7 @@ import (
 	"github.com/outbrain/orchestrator/http"
 	"github.com/outbrain/orchestrator/inst"
 	"github.com/outbrain/orchestrator/logic"
+	"github.com/outbrain/orchestrator/ssl"
 )
 
 // Http starts serving
@@ -70,6 +71,14 @@ func standardHttp(discovery bool) {
 				return auth.SecureCompare(username, config.Config.HTTPAuthUser) && auth.SecureCompare(password, config.Config.HTTPAuthPassword)
 			}))
 		}
+	case "ssl":
+		{
+			if !config.Config.UseSSL || !config.Config.UseMutualTLS {
+				log.Fatal("AuthenticationMethod is configured as 'ssl' but UseSSL/UseMutualTLS are not enabled")
+			}
+			// User identity is taken from the verified client certificate; see getUserId()
+			m.Map(auth.User(""))
+		}
 	default:
 		{
 			// We inject a dummy User object because we have function signatures with User argument in api.go
@@ -77,6 +86,7 @@ func standardHttp(discovery bool) {
 		}
 	}
 
+	m.Use(http.DisableEventStreamCompression)
 	m.Use(gzip.All())
 	// Render html templates from templates directory
 	m.Use(render.Renderer(render.Options{
@@ -85,6 +95,7 @@ func standardHttp(discovery bool) {
 		HTMLContentType: "text/html",
 	}))
 	m.Use(martini.Static("resources/public"))
+	m.Use(http.SetCSRFCookie)
 
 	inst.SetMaintenanceOwner(orchestrator.ThisHostname)
 
@@ -96,11 +107,24 @@ func standardHttp(discovery bool) {
 	inst.ReadClusterAliases()
 
 	http.API.RegisterRequests(m)
+	http.APIv2.RegisterRequests(m)
 	http.Web.RegisterRequests(m)
 
 	// Serve
-	if err := nethttp.ListenAndServe(config.Config.ListenAddress, m); err != nil {
-		log.Fatale(err)
+	if config.Config.UseSSL {
+		log.Info("Serving via SSL")
+		tlsConfig, err := ssl.NewTLSConfig(config.Config.SSLCAFile, config.Config.UseMutualTLS)
+		if err != nil {
+			log.Fatale(err)
+		}
+		server := &nethttp.Server{Addr: config.Config.ListenAddress, Handler: m, TLSConfig: tlsConfig}
+		if err := server.ListenAndServeTLS(config.Config.SSLCertFile, config.Config.SSLPrivateKeyFile); err != nil {
+			log.Fatale(err)
+		}
+	} else {
+		if err := nethttp.ListenAndServe(config.Config.ListenAddress, m); err != nil {
+			log.Fatale(err)
+		}
 	}
 }
 
@@ -119,11 +143,11 @@ func agentsHttp() {
 	// Serve
 	if config.Config.AgentsUseSSL {
 		log.Info("Serving via SSL")
-		err := nethttp.ListenAndServeTLS(":3001", config.Config.SSLCertFile, config.Config.SSLPrivateKeyFile, m)
+		err := nethttp.ListenAndServeTLS(config.Config.AgentsListenAddress, config.Config.SSLCertFile, config.Config.SSLPrivateKeyFile, m)
 		if err != nil {
 			log.Fatale(err)
 		}
 	} else {
-		nethttp.ListenAndServe(":3001", m)
+		nethttp.ListenAndServe(config.Config.AgentsListenAddress, m)
 	}
 }
@@ -0,0 +1,542 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+// Package client is a Go client of the orchestrator HTTP API. It returns orchestrator's own types, supports
+// basic and proxy authentication, retries requests across multiple orchestrator nodes, and optionally
+// directs requests at the elected active node (leader). State changing requests are only retried when
+// they were certainly not served, such that e.g. a recovery is never executed twice.
+package client
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/outbrain/orchestrator/agent"
+	"github.com/outbrain/orchestrator/inst"
+	"github.com/outbrain/orchestrator/logic"
+)
+
+// Error codes of the v2 API
+const (
+	ErrorBadRequest          string = "bad-request"
+	ErrorForbidden                  = "forbidden"
+	ErrorNotFound                   = "not-found"
+	ErrorMaintenanceConflict        = "maintenance-conflict"
+	ErrorPreconditionFailed         = "precondition-failed"
+	ErrorInternalError              = "internal-error"
+)
+
+// leaderHeader is set by /api/lb-check, indicating whether the responding node is the elected active node
+const leaderHeader = "X-Orchestrator-Leader"
+
+// Config configures a Client
+type Config struct {
+	Endpoints      []string      // Base URLs of orchestrator nodes, e.g. "http://orchestrator1:3000"
+	User           string        // With Password, user for basic authentication
+	Password       string        // With User, password for basic authentication
+	AuthUserHeader string        // With AuthUser, header name for proxy authentication (orchestrator's AuthUserHeader)
+	AuthUser       string        // With AuthUserHeader, user for proxy authentication
+	TLSConfig      *tls.Config   // Optional TLS configuration for https endpoints (e.g. CA, client certificate)
+	Timeout        time.Duration // Per request timeout. Defaults to 10 seconds
+	Retries        int           // Number of times a failed request is retried over all endpoints
+	RetryInterval  time.Duration // Wait time between retries. Defaults to 1 second
+	PreferLeader   bool          // Direct requests at the elected active node first
+}
+
+// APIError is an error returned by the orchestrator API
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (this *APIError) Error() string {
+	return fmt.Sprintf("%s: %s", this.Code, this.Message)
+}
+
+// ErrorCode returns the API error code of given error, or an empty string when err is not an API error
+func ErrorCode(err error) string {
+	if apiError, ok := err.(*APIError); ok {
+		return apiError.Code
+	}
+	return ""
+}
+
+// IsNotFound tests whether given error indicates a requested entity does not exist
+func IsNotFound(err error) bool {
+	return ErrorCode(err) == ErrorNotFound
+}
+
+// IsMaintenanceConflict tests whether given error indicates an instance is already under maintenance
+func IsMaintenanceConflict(err error) bool {
+	return ErrorCode(err) == ErrorMaintenanceConflict
+}
+
+// IsPreconditionFailed tests whether given error indicates an entity is not in the state an operation requires
+func IsPreconditionFailed(err error) bool {
+	return ErrorCode(err) == ErrorPreconditionFailed
+}
+
+// page is the pagination info of a v2 API response
+type page struct {
+	Page    int
+	PerPage int
+	HasMore bool
+}
+
+// apiv2Response is the body of a successful v2 API response
+type apiv2Response struct {
+	Data json.RawMessage
+	Page *page
+}
+
+// APIResponse is the response of a v1 API request. Routes responding with plain data have it in Details.
+type APIResponse struct {
+	Code    string
+	Message string
+	Details json.RawMessage
+}
+
+// Client is an orchestrator API client. It is safe for concurrent use.
+type Client struct {
+	config     Config
+	httpClient *http.Client
+
+	leaderMutex sync.Mutex
+	leader      string
+}
+
+// NewClient creates a client of given configuration
+func NewClient(config Config) (*Client, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("No orchestrator endpoints given")
+	}
+	endpoints := []string{}
+	for _, endpoint := range config.Endpoints {
+		endpoints = append(endpoints, strings.TrimRight(endpoint, "/"))
+	}
+	config.Endpoints = endpoints
+	if config.Timeout == 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.RetryInterval == 0 {
+		config.RetryInterval = time.Second
+	}
+	httpClient := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: config.TLSConfig, Proxy: http.ProxyFromEnvironment},
+		Timeout:   config.Timeout,
+	}
+	return &Client{config: config, httpClient: httpClient}, nil
+}
+
+// newRequest creates a request to given endpoint, applying authentication. State changing requests
+// carry a JSON body, as orchestrator expects.
+func (this *Client) newRequest(method string, endpoint string, path string, body map[string]string) (*http.Request, error) {
+	var bodyReader *bytes.Reader
+	if body == nil {
+		bodyReader = bytes.NewReader(nil)
+	} else {
+		blob, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		bodyReader = bytes.NewReader(blob)
+	}
+	req, err := http.NewRequest(method, endpoint+path, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if method != "GET" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if this.config.User != "" {
+		req.SetBasicAuth(this.config.User, this.config.Password)
+	}
+	if this.config.AuthUserHeader != "" {
+		req.Header.Set(this.config.AuthUserHeader, this.config.AuthUser)
+	}
+	return req, nil
+}
+
+// isRetriable tests whether a response status indicates the node is unable to serve, such that
+// another node, or a later retry, may succeed. A gateway timeout may hide a request which is still being
+// served, hence is only retriable for idempotent requests.
+func isRetriable(statusCode int, idempotent bool) bool {
+	if statusCode == http.StatusBadGateway || statusCode == http.StatusServiceUnavailable {
+		return true
+	}
+	return idempotent && statusCode == http.StatusGatewayTimeout
+}
+
+// isConnectError tests whether a request failed to connect to its endpoint, hence was certainly not sent
+func isConnectError(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+// endpoints returns endpoints in the order requests should attempt them
+func (this *Client) endpoints() []string {
+	if !this.config.PreferLeader {
+		return this.config.Endpoints
+	}
+	leader, err := this.Leader()
+	if err != nil {
+		return this.config.Endpoints
+	}
+	endpoints := []string{leader}
+	for _, endpoint := range this.config.Endpoints {
+		if endpoint != leader {
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// do makes a request, retrying across endpoints on network errors and unavailable nodes.
+// It returns the response status and body of the first node to serve the request.
+// GET requests are retried on any error. Other (state changing) requests are only retried when they
+// could not connect, or were rejected by an unavailable node; in particular they are not retried on timeout.
+func (this *Client) do(method string, path string, body map[string]string) (int, []byte, error) {
+	idempotent := (method == "GET")
+	var lastErr error
+	for attempt := 0; attempt <= this.config.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(this.config.RetryInterval)
+		}
+		for _, endpoint := range this.endpoints() {
+			statusCode, responseBody, err := this.doEndpoint(method, endpoint, path, body)
+			if err != nil && !idempotent && !isConnectError(err) {
+				// The request may have been served; do not replay it
+				return 0, nil, err
+			}
+			if err == nil && isRetriable(statusCode, idempotent) {
+				err = fmt.Errorf("%s%s: %s", endpoint, path, http.StatusText(statusCode))
+			}
+			if err == nil {
+				return statusCode, responseBody, nil
+			}
+			lastErr = err
+			this.forgetLeader(endpoint)
+		}
+	}
+	return 0, nil, lastErr
+}
+
+// doEndpoint makes a request to a given endpoint
+func (this *Client) doEndpoint(method string, endpoint string, path string, body map[string]string) (int, []byte, error) {
+	req, err := this.newRequest(method, endpoint, path, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	res, err := this.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+	responseBody, err := ioutil.ReadAll(res.Body)
+	return res.StatusCode, responseBody, err
+}
+
+// requestV2 makes a v2 API request, unmarshalling the response data into given data
+func (this *Client) requestV2(method string, path string, body map[string]string, data interface{}) (*page, error) {
+	statusCode, responseBody, err := this.do(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	if statusCode != http.StatusOK {
+		apiError := &APIError{StatusCode: statusCode}
+		if err := json.Unmarshal(responseBody, apiError); err != nil || apiError.Code == "" {
+			return nil, fmt.Errorf("Unexpected response status %d on %s", statusCode, path)
+		}
+		return nil, apiError
+	}
+	response := apiv2Response{}
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return nil, err
+	}
+	if data != nil {
+		if err := json.Unmarshal(response.Data, data); err != nil {
+			return nil, err
+		}
+	}
+	return response.Page, nil
+}
+
+// listV2 reads all pages of a paginated v2 API resource, handing the data of each page to appendPage
+func (this *Client) listV2(path string, query url.Values, appendPage func(data json.RawMessage) error) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	for pageNumber := 0; ; pageNumber++ {
+		query.Set("page", strconv.Itoa(pageNumber))
+		var data json.RawMessage
+		page, err := this.requestV2("GET", path+"?"+query.Encode(), nil, &data)
+		if err != nil {
+			return err
+		}
+		if err := appendPage(data); err != nil {
+			return err
+		}
+		if page == nil || !page.HasMore {
+			return nil
+		}
+	}
+}
+
+// Request makes a v1 API request to given path, e.g. "/api/move-up/db-1/3306". Params are sent as JSON
+// body on state changing (POST, DELETE) requests, and as query params on GET requests. A response with
+// ERROR code is returned along with an error.
+func (this *Client) Request(method string, path string, params map[string]string) (*APIResponse, error) {
+	var body map[string]string
+	if method == "GET" {
+		query := url.Values{}
+		for key, value := range params {
+			query.Set(key, value)
+		}
+		if len(query) > 0 {
+			path = path + "?" + query.Encode()
+		}
+	} else {
+		body = params
+	}
+	statusCode, responseBody, err := this.do(method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	response := &APIResponse{}
+	var codedResponse struct {
+		Code *string
+	}
+	if json.Unmarshal(responseBody, &codedResponse) == nil && codedResponse.Code != nil && (*codedResponse.Code == "OK" || *codedResponse.Code == "ERROR") {
+		if err := json.Unmarshal(responseBody, response); err != nil {
+			return nil, err
+		}
+	} else if statusCode == http.StatusOK {
+		response.Code = "OK"
+		response.Details = responseBody
+	} else {
+		return nil, fmt.Errorf("Unexpected response status %d on %s", statusCode, path)
+	}
+	if response.Code != "OK" {
+		return response, errors.New(response.Message)
+	}
+	return response, nil
+}
+
+// requestV1 makes a v1 API request, unmarshalling the response details into given details
+func (this *Client) requestV1(method string, path string, details interface{}) error {
+	response, err := this.Request(method, path, nil)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(response.Details, details)
+}
+
+// instancePath returns the v2 API path of given instance
+func instancePath(instanceKey *inst.InstanceKey) string {
+	return fmt.Sprintf("/api/v2/instances/%s/%d", url.QueryEscape(instanceKey.Hostname), instanceKey.Port)
+}
+
+// readInstances reads all instances of a paginated v2 API resource
+func (this *Client) readInstances(path string, query url.Values) ([]inst.Instance, error) {
+	instances := []inst.Instance{}
+	err := this.listV2(path, query, func(data json.RawMessage) error {
+		pageInstances := []inst.Instance{}
+		if err := json.Unmarshal(data, &pageInstances); err != nil {
+			return err
+		}
+		instances = append(instances, pageInstances...)
+		return nil
+	})
+	return instances, err
+}
+
+// Leader returns the endpoint of the elected active node, as indicated by /api/lb-check
+func (this *Client) Leader() (string, error) {
+	this.leaderMutex.Lock()
+	defer this.leaderMutex.Unlock()
+
+	if this.leader != "" {
+		return this.leader, nil
+	}
+	for _, endpoint := range this.config.Endpoints {
+		req, err := this.newRequest("GET", endpoint, "/api/lb-check", nil)
+		if err != nil {
+			return "", err
+		}
+		res, err := this.httpClient.Do(req)
+		if err != nil {
+			continue
+		}
+		res.Body.Close()
+		if res.StatusCode == http.StatusOK && res.Header.Get(leaderHeader) == "true" {
+			this.leader = endpoint
+			return endpoint, nil
+		}
+	}
+	return "", errors.New("Cannot find elected orchestrator node")
+}
+
+// forgetLeader clears the known leader if it is given endpoint, such that it is detected anew
+func (this *Client) forgetLeader(endpoint string) {
+	this.leaderMutex.Lock()
+	defer this.leaderMutex.Unlock()
+
+	if this.leader == endpoint {
+		this.leader = ""
+	}
+}
+
+// Clusters returns known clusters along with some metadata per cluster
+func (this *Client) Clusters() ([]inst.ClusterInfo, error) {
+	clusters := []inst.ClusterInfo{}
+	err := this.listV2("/api/v2/clusters", nil, func(data json.RawMessage) error {
+		pageClusters := []inst.ClusterInfo{}
+		if err := json.Unmarshal(data, &pageClusters); err != nil {
+			return err
+		}
+		clusters = append(clusters, pageClusters...)
+		return nil
+	})
+	return clusters, err
+}
+
+// Cluster returns metadata of a given cluster
+func (this *Client) Cluster(clusterName string) (*inst.ClusterInfo, error) {
+	clusterInfo := &inst.ClusterInfo{}
+	_, err := this.requestV2("GET", "/api/v2/clusters/"+url.QueryEscape(clusterName), nil, clusterInfo)
+	return clusterInfo, err
+}
+
+// ClusterInstances returns the instances of a given cluster, optionally filtered by tag selector
+func (this *Client) ClusterInstances(clusterName string, tagSelector string) ([]inst.Instance, error) {
+	query := url.Values{}
+	if tagSelector != "" {
+		query.Set("tag", tagSelector)
+	}
+	return this.readInstances("/api/v2/clusters/"+url.QueryEscape(clusterName)+"/instances", query)
+}
+
+// SearchInstances returns instances matching given search string
+func (this *Client) SearchInstances(searchString string) ([]inst.Instance, error) {
+	return this.readInstances("/api/v2/instances", url.Values{"search": []string{searchString}})
+}
+
+// Instance returns a given instance
+func (this *Client) Instance(instanceKey *inst.InstanceKey) (*inst.Instance, error) {
+	instance := &inst.Instance{}
+	_, err := this.requestV2("GET", instancePath(instanceKey), nil, instance)
+	return instance, err
+}
+
+// InstanceSlaves returns the direct slaves of a given instance
+func (this *Client) InstanceSlaves(instanceKey *inst.InstanceKey) ([]inst.Instance, error) {
+	return this.readInstances(instancePath(instanceKey)+"/slaves", nil)
+}
+
+// RefreshInstance synchronously re-reads a given instance from the topology, and returns it
+func (this *Client) RefreshInstance(instanceKey *inst.InstanceKey) (*inst.Instance, error) {
+	instance := &inst.Instance{}
+	_, err := this.requestV2("POST", instancePath(instanceKey)+"/refresh", nil, instance)
+	return instance, err
+}
+
+// ForgetInstance removes a given instance from orchestrator's backend database
+func (this *Client) ForgetInstance(instanceKey *inst.InstanceKey) error {
+	_, err := this.requestV2("DELETE", instancePath(instanceKey), nil, nil)
+	return err
+}
+
+// BeginMaintenance begins maintenance mode for a given instance and returns the maintenance token.
+// duration (e.g. "30m") is optional. An instance already under maintenance is a maintenance-conflict error.
+func (this *Client) BeginMaintenance(instanceKey *inst.InstanceKey, owner string, reason string, duration string) (int64, error) {
+	var maintenanceToken int64
+	body := map[string]string{"owner": owner, "reason": reason, "duration": duration}
+	_, err := this.requestV2("POST", instancePath(instanceKey)+"/maintenance", body, &maintenanceToken)
+	return maintenanceToken, err
+}
+
+// EndMaintenance ends maintenance mode of a given instance
+func (this *Client) EndMaintenance(instanceKey *inst.InstanceKey) error {
+	_, err := this.requestV2("DELETE", instancePath(instanceKey)+"/maintenance", nil, nil)
+	return err
+}
+
+// BeginDowntime sets downtime for a given instance. duration (e.g. "2h") is optional.
+func (this *Client) BeginDowntime(instanceKey *inst.InstanceKey, owner string, reason string, duration string) error {
+	body := map[string]string{"owner": owner, "reason": reason, "duration": duration}
+	_, err := this.requestV2("POST", instancePath(instanceKey)+"/downtime", body, nil)
+	return err
+}
+
+// EndDowntime ends downtime of a given instance
+func (this *Client) EndDowntime(instanceKey *inst.InstanceKey) error {
+	_, err := this.requestV2("DELETE", instancePath(instanceKey)+"/downtime", nil, nil)
+	return err
+}
+
+// ReplicationAnalysis returns the current replication analysis of all clusters
+func (this *Client) ReplicationAnalysis() ([]inst.ReplicationAnalysis, error) {
+	analysis := []inst.ReplicationAnalysis{}
+	err := this.requestV1("GET", "/api/replication-analysis", &analysis)
+	return analysis, err
+}
+
+// Recoveries returns a page of latest recoveries, optionally of a given cluster, and whether more pages follow
+func (this *Client) Recoveries(clusterName string, pageNumber int) ([]orchestrator.TopologyRecovery, bool, error) {
+	path := "/api/v2/recoveries"
+	if clusterName != "" {
+		path = "/api/v2/clusters/" + url.QueryEscape(clusterName) + "/recoveries"
+	}
+	recoveries := []orchestrator.TopologyRecovery{}
+	page, err := this.requestV2("GET", fmt.Sprintf("%s?page=%d", path, pageNumber), nil, &recoveries)
+	return recoveries, page != nil && page.HasMore, err
+}
+
+// Recovery returns a given recovery
+func (this *Client) Recovery(recoveryId int64) (*orchestrator.TopologyRecovery, error) {
+	recovery := &orchestrator.TopologyRecovery{}
+	_, err := this.requestV2("GET", fmt.Sprintf("/api/v2/recoveries/%d", recoveryId), nil, recovery)
+	return recovery, err
+}
+
+// Seeds returns a page of latest seeds, and whether more pages follow
+func (this *Client) Seeds(pageNumber int) ([]agent.SeedOperation, bool, error) {
+	seeds := []agent.SeedOperation{}
+	page, err := this.requestV2("GET", fmt.Sprintf("/api/v2/seeds?page=%d", pageNumber), nil, &seeds)
+	return seeds, page != nil && page.HasMore, err
+}
+
+// Seed returns a given seed
+func (this *Client) Seed(seedId int64) (*agent.SeedOperation, error) {
+	seed := &agent.SeedOperation{}
+	_, err := this.requestV2("GET", fmt.Sprintf("/api/v2/seeds/%d", seedId), nil, seed)
+	return seed, err
+}
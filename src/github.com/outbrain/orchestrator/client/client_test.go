@@ -0,0 +1,376 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/outbrain/orchestrator/inst"
+	. "gopkg.in/check.v1"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+var instanceKey = inst.InstanceKey{Hostname: "db-1.example.com", Port: 3306}
+
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// newNode creates a test orchestrator node serving given handlers, as well as /api/lb-check
+func newNode(isLeader bool, handlers map[string]http.HandlerFunc) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/lb-check", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(leaderHeader, strconv.FormatBool(isLeader))
+		writeJSON(w, 200, "OK")
+	})
+	for path, handler := range handlers {
+		mux.HandleFunc(path, handler)
+	}
+	return httptest.NewServer(mux)
+}
+
+func newTestClient(c *C, config Config) *Client {
+	client, err := NewClient(config)
+	c.Assert(err, IsNil)
+	return client
+}
+
+func (s *TestSuite) TestNewClientWithoutEndpoints(c *C) {
+	_, err := NewClient(Config{})
+	c.Assert(err, NotNil)
+}
+
+func (s *TestSuite) TestInstance(c *C) {
+	instance := inst.NewInstance()
+	instance.Key = instanceKey
+	instance.Version = "5.6.26-log"
+	instance.SlaveHosts[inst.InstanceKey{Hostname: "db-2.example.com", Port: 3306}] = true
+
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/instances/db-1.example.com/3306": func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.Method, Equals, "GET")
+			writeJSON(w, 200, map[string]interface{}{"Data": instance})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL + "/"}})
+	result, err := client.Instance(&instanceKey)
+	c.Assert(err, IsNil)
+	c.Assert(result.Key, Equals, instanceKey)
+	c.Assert(result.Version, Equals, "5.6.26-log")
+	c.Assert(len(result.SlaveHosts), Equals, 1)
+	c.Assert(result.SlaveHosts[inst.InstanceKey{Hostname: "db-2.example.com", Port: 3306}], Equals, true)
+}
+
+func (s *TestSuite) TestNotFound(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/instances/": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 404, map[string]string{"Code": "not-found", "Message": "Unknown instance"})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	_, err := client.Instance(&instanceKey)
+	c.Assert(err, NotNil)
+	c.Assert(IsNotFound(err), Equals, true)
+	c.Assert(err.(*APIError).StatusCode, Equals, 404)
+}
+
+func (s *TestSuite) TestBasicAuth(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/clusters/": func(w http.ResponseWriter, r *http.Request) {
+			user, password, ok := r.BasicAuth()
+			c.Check(ok, Equals, true)
+			c.Check(user, Equals, "dba")
+			c.Check(password, Equals, "secret")
+			writeJSON(w, 200, map[string]interface{}{"Data": inst.ClusterInfo{ClusterName: "db-1.example.com:3306"}})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}, User: "dba", Password: "secret"})
+	clusterInfo, err := client.Cluster("db-1.example.com:3306")
+	c.Assert(err, IsNil)
+	c.Assert(clusterInfo.ClusterName, Equals, "db-1.example.com:3306")
+}
+
+func (s *TestSuite) TestProxyAuth(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/instances/db-1.example.com/3306/downtime": func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.Header.Get("X-Forwarded-User"), Equals, "dba")
+			writeJSON(w, 200, map[string]interface{}{"Data": instanceKey})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}, AuthUserHeader: "X-Forwarded-User", AuthUser: "dba"})
+	c.Assert(client.EndDowntime(&instanceKey), IsNil)
+}
+
+func (s *TestSuite) TestBeginMaintenance(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/instances/db-1.example.com/3306/maintenance": func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.Method, Equals, "POST")
+			c.Check(r.Header.Get("Content-Type"), Equals, "application/json")
+			body, _ := ioutil.ReadAll(r.Body)
+			params := map[string]string{}
+			c.Check(json.Unmarshal(body, &params), IsNil)
+			c.Check(params["owner"], Equals, "dba")
+			c.Check(params["reason"], Equals, "upgrade")
+			c.Check(params["duration"], Equals, "1h")
+			writeJSON(w, 200, map[string]interface{}{"Data": 17})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	maintenanceToken, err := client.BeginMaintenance(&instanceKey, "dba", "upgrade", "1h")
+	c.Assert(err, IsNil)
+	c.Assert(maintenanceToken, Equals, int64(17))
+}
+
+func (s *TestSuite) TestMaintenanceConflict(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/instances/db-1.example.com/3306/maintenance": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 409, map[string]string{"Code": "maintenance-conflict", "Message": "Cannot begin maintenance"})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	_, err := client.BeginMaintenance(&instanceKey, "dba", "upgrade", "")
+	c.Assert(IsMaintenanceConflict(err), Equals, true)
+	c.Assert(IsNotFound(err), Equals, false)
+}
+
+func (s *TestSuite) TestPagination(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/clusters/db-1.example.com:3306/instances": func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.URL.Query().Get("tag"), Equals, "role=backup")
+			pageNumber, _ := strconv.Atoi(r.URL.Query().Get("page"))
+			instance := inst.NewInstance()
+			instance.Key = inst.InstanceKey{Hostname: "db-" + strconv.Itoa(pageNumber), Port: 3306}
+			page := map[string]interface{}{"Page": pageNumber, "PerPage": 1, "HasMore": pageNumber < 2}
+			writeJSON(w, 200, map[string]interface{}{"Data": []*inst.Instance{instance}, "Page": page})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	instances, err := client.ClusterInstances("db-1.example.com:3306", "role=backup")
+	c.Assert(err, IsNil)
+	c.Assert(len(instances), Equals, 3)
+	c.Assert(instances[2].Key.Hostname, Equals, "db-2")
+}
+
+func (s *TestSuite) TestReplicationAnalysis(c *C) {
+	analysis := []inst.ReplicationAnalysis{{AnalyzedInstanceKey: instanceKey, Analysis: inst.DeadMaster, SlaveHosts: inst.InstanceKeyMap{}}}
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/replication-analysis": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 200, map[string]interface{}{"Code": "OK", "Message": "Analysis", "Details": analysis})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	result, err := client.ReplicationAnalysis()
+	c.Assert(err, IsNil)
+	c.Assert(len(result), Equals, 1)
+	c.Assert(result[0].AnalyzedInstanceKey, Equals, instanceKey)
+	c.Assert(string(result[0].Analysis), Equals, inst.DeadMaster)
+}
+
+func (s *TestSuite) TestReplicationAnalysisError(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/replication-analysis": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 200, map[string]interface{}{"Code": "ERROR", "Message": "Cannot get analysis"})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	_, err := client.ReplicationAnalysis()
+	c.Assert(err, ErrorMatches, "Cannot get analysis")
+}
+
+func (s *TestSuite) TestRecoveriesAndSeeds(c *C) {
+	node := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/clusters/db-1.example.com:3306/recoveries": func(w http.ResponseWriter, r *http.Request) {
+			c.Check(r.URL.Query().Get("page"), Equals, "1")
+			recoveries := []map[string]interface{}{{"TopologyRecoveryId": 7, "LostSlaves": []inst.InstanceKey{instanceKey}}}
+			page := map[string]interface{}{"Page": 1, "PerPage": 1, "HasMore": true}
+			writeJSON(w, 200, map[string]interface{}{"Data": recoveries, "Page": page})
+		},
+		"/api/v2/seeds/3": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 200, map[string]interface{}{"Data": map[string]interface{}{"SeedId": 3, "TargetHostname": "db-1.example.com"}})
+		},
+	})
+	defer node.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{node.URL}})
+	recoveries, hasMore, err := client.Recoveries("db-1.example.com:3306", 1)
+	c.Assert(err, IsNil)
+	c.Assert(hasMore, Equals, true)
+	c.Assert(len(recoveries), Equals, 1)
+	c.Assert(recoveries[0].TopologyRecoveryId, Equals, int64(7))
+	c.Assert(recoveries[0].LostSlaves[instanceKey], Equals, true)
+
+	seed, err := client.Seed(3)
+	c.Assert(err, IsNil)
+	c.Assert(seed.TargetHostname, Equals, "db-1.example.com")
+}
+
+func (s *TestSuite) TestRetryAcrossNodes(c *C) {
+	unavailableRequests := 0
+	unavailable := newNode(false, map[string]http.HandlerFunc{
+		"/api/v2/clusters": func(w http.ResponseWriter, r *http.Request) {
+			unavailableRequests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+	defer unavailable.Close()
+	down := newNode(false, nil)
+	down.Close()
+	available := newNode(false, map[string]http.HandlerFunc{
+		"/api/v2/clusters": func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, 200, map[string]interface{}{"Data": []inst.ClusterInfo{{ClusterName: "db-1.example.com:3306"}}})
+		},
+	})
+	defer available.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{down.URL, unavailable.URL, available.URL}})
+	clusters, err := client.Clusters()
+	c.Assert(err, IsNil)
+	c.Assert(len(clusters), Equals, 1)
+	c.Assert(unavailableRequests, Equals, 1)
+}
+
+func (s *TestSuite) TestRetriesExhausted(c *C) {
+	requests := 0
+	unavailable := newNode(false, map[string]http.HandlerFunc{
+		"/api/v2/clusters": func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		},
+	})
+	defer unavailable.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{unavailable.URL}, Retries: 2, RetryInterval: 1})
+	_, err := client.Clusters()
+	c.Assert(err, NotNil)
+	c.Assert(requests, Equals, 3)
+}
+
+func (s *TestSuite) TestStateChangingRequestNotReplayedOnTimeout(c *C) {
+	requests := 0
+	slowHandler := func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		time.Sleep(200 * time.Millisecond)
+		writeJSON(w, 200, map[string]interface{}{"Code": "OK", "Message": "Recovered"})
+	}
+	slow := newNode(false, map[string]http.HandlerFunc{"/api/recover/db-1.example.com/3306": slowHandler})
+	defer slow.Close()
+	other := newNode(false, map[string]http.HandlerFunc{"/api/recover/db-1.example.com/3306": slowHandler})
+	defer other.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{slow.URL, other.URL}, Timeout: 50 * time.Millisecond, Retries: 2, RetryInterval: 1})
+	_, err := client.Request("POST", "/api/recover/db-1.example.com/3306", nil)
+	c.Assert(err, NotNil)
+	c.Assert(requests, Equals, 1)
+}
+
+func (s *TestSuite) TestStateChangingRequestRetriedOnConnectError(c *C) {
+	down := newNode(false, nil)
+	down.Close()
+	requests := 0
+	available := newNode(false, map[string]http.HandlerFunc{
+		"/api/recover/db-1.example.com/3306": func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			writeJSON(w, 200, map[string]interface{}{"Code": "OK", "Message": "Recovered"})
+		},
+	})
+	defer available.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{down.URL, available.URL}})
+	response, err := client.Request("POST", "/api/recover/db-1.example.com/3306", nil)
+	c.Assert(err, IsNil)
+	c.Assert(response.Message, Equals, "Recovered")
+	c.Assert(requests, Equals, 1)
+}
+
+func (s *TestSuite) TestStateChangingRequestNotRetriedOnGatewayTimeout(c *C) {
+	requests := 0
+	gatewayTimeout := newNode(false, map[string]http.HandlerFunc{
+		"/api/recover/db-1.example.com/3306": func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusGatewayTimeout)
+		},
+	})
+	defer gatewayTimeout.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{gatewayTimeout.URL}, Retries: 2, RetryInterval: 1})
+	_, err := client.Request("POST", "/api/recover/db-1.example.com/3306", nil)
+	c.Assert(err, NotNil)
+	c.Assert(requests, Equals, 1)
+}
+
+func (s *TestSuite) TestLeader(c *C) {
+	follower := newNode(false, nil)
+	defer follower.Close()
+	leaderRequests := 0
+	leader := newNode(true, map[string]http.HandlerFunc{
+		"/api/v2/instances/db-1.example.com/3306/refresh": func(w http.ResponseWriter, r *http.Request) {
+			leaderRequests++
+			writeJSON(w, 200, map[string]interface{}{"Data": inst.Instance{Key: instanceKey}})
+		},
+	})
+	defer leader.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{follower.URL, leader.URL}, PreferLeader: true})
+	leaderEndpoint, err := client.Leader()
+	c.Assert(err, IsNil)
+	c.Assert(leaderEndpoint, Equals, leader.URL)
+
+	instance, err := client.RefreshInstance(&instanceKey)
+	c.Assert(err, IsNil)
+	c.Assert(instance.Key, Equals, instanceKey)
+	c.Assert(leaderRequests, Equals, 1)
+}
+
+func (s *TestSuite) TestNoLeader(c *C) {
+	follower := newNode(false, nil)
+	defer follower.Close()
+
+	client := newTestClient(c, Config{Endpoints: []string{follower.URL}})
+	_, err := client.Leader()
+	c.Assert(err, NotNil)
+}
@@ -24,6 +24,15 @@ import (
 	"github.com/outbrain/golib/log"
 )
 
+// RoleBinding grants a role ("viewer", "operator" or "admin") to a user. The binding may be scoped to clusters
+// whose name or alias match a regexp pattern; an empty pattern applies to all clusters. The pattern must match
+// the entire name or alias: "db1" applies to "db1" but not to "db10"; use e.g. "db1.*" for a prefix.
+type RoleBinding struct {
+	User           string // User name, or "*" for any user
+	Role           string
+	ClusterPattern string
+}
+
 // Configuration makes for orchestrator configuration input, which can be provided by user via JSON formatted file.
 // Some of the parameteres have reasonable default values, and some (like database credentials) are
 // strictly expected from user.
@@ -33,13 +42,28 @@ type Configuration struct {
 	MySQLTopologyUser                          string
 	MySQLTopologyPassword                      string // my.cnf style configuration file from where to pick credentials. Expecting `user`, `password` under `[client]` section
 	MySQLTopologyCredentialsConfigFile         string
-	MySQLTopologyMaxPoolConnections            int // Max concurrent connections on any topology instance
+	MySQLTopologyMaxPoolConnections            int    // Max concurrent connections on any topology instance
+	MySQLTopologyUseSSL                        bool   // When "true", connections to topology instances are encrypted
+	MySQLTopologySSLCAFile                     string // CA bundle used to verify topology servers' certificates
+	MySQLTopologySSLCertFile                   string // Client certificate presented to topology servers (optional)
+	MySQLTopologySSLPrivateKeyFile             string // Private key of MySQLTopologySSLCertFile
+	MySQLTopologySSLSkipVerify                 bool   // When "true", topology servers' certificates are not verified
 	MySQLOrchestratorHost                      string
 	MySQLOrchestratorPort                      uint
 	MySQLOrchestratorDatabase                  string
 	MySQLOrchestratorUser                      string
 	MySQLOrchestratorPassword                  string
 	MySQLOrchestratorCredentialsConfigFile     string // my.cnf style configuration file from where to pick credentials. Expecting `user`, `password` under `[client]` section
+	MySQLOrchestratorUseSSL                    bool   // When "true", connections to the backend database are encrypted
+	MySQLOrchestratorSSLCAFile                 string // CA bundle used to verify the backend server's certificate
+	MySQLOrchestratorSSLCertFile               string // Client certificate presented to the backend server (optional)
+	MySQLOrchestratorSSLPrivateKeyFile         string // Private key of MySQLOrchestratorSSLCertFile
+	MySQLOrchestratorSSLSkipVerify             bool   // When "true", the backend server's certificate is not verified
+	ReplicationUseSSL                          bool   // When "true", CHANGE MASTER TO statements issued by orchestrator set MASTER_SSL=1 along with the below MASTER_SSL_* options
+	ReplicationSSLCAFile                       string // MASTER_SSL_CA: path, on the slave's host, of CA bundle verifying the master
+	ReplicationSSLCertFile                     string // MASTER_SSL_CERT: path, on the slave's host, of client certificate (optional)
+	ReplicationSSLPrivateKeyFile               string // MASTER_SSL_KEY: path, on the slave's host, of client private key (optional)
+	ReplicationSSLVerifyServerCert             bool   // MASTER_SSL_VERIFY_SERVER_CERT: verify master's certificate host name
 	MySQLConnectTimeoutSeconds                 int    // Number of seconds before connection is aborted (driver-side)
 	DefaultInstancePort                        int    // In case port was not specified on command line
 	SkipOrchestratorDatabaseUpdate             bool   // When false, orchestrator will attempt to create & update all tables in backend database; when true, this is skipped. It makes sense to skip on command-line invocations and to enable for http or occasional invocations, or just after upgrades
@@ -52,8 +76,12 @@ type Configuration struct {
 	SnapshotTopologiesIntervalHours            uint   // Interval in hour between snapshot-topologies invocation. Default: 0 (disabled)
 	DiscoveryPollSeconds                       uint   // Auto/continuous discovery of instances sleep time between polls
 	InstanceBulkOperationsWaitTimeoutSeconds   uint   // Time to wait on a single instance when doing bulk (many instances) operation
+	BulkOperationsMaxConcurrency               uint   // Maximum number of instances concurrently operated on by a bulk operation (e.g. -c bulk, /api/bulk)
 	ActiveNodeExpireSeconds                    uint   // Maximum time to wait for active node to send keepalive before attempting to take over as active node.
-	HostnameResolveMethod                      string // Method by which to "normalize" hostname ("none"/"default"/"cname")
+	HostnameResolveMethod                      string // Method by which to "normalize" hostname ("none"/"default"/"cname"/"srv"/"reverse-dns"/"static"/"http")
+	HostnameResolveStaticMapFile               string // For "static" HostnameResolveMethod: JSON file mapping hostnames to resolved hostnames, re-read upon change
+	HostnameResolveHTTPURL                     string // For "http" HostnameResolveMethod: key/value service URL where "{hostname}" is substituted; response body is the resolved hostname, 404 for unmapped (e.g. Consul "http://127.0.0.1:8500/v1/kv/mysql/hostnames/{hostname}?raw")
+	HostnameResolveHTTPTimeoutSeconds          int    // Timeout for "http" HostnameResolveMethod requests
 	MySQLHostnameResolveMethod                 string // Method by which to "normalize" hostname via MySQL server. ("none"/"@@hostname"/"@@report_host"; default "@@hostname")
 	ExpiryHostnameResolvesMinutes              int    // Number of minutes after which to expire hostname-resolves
 	RejectHostnameResolvePattern               string // Regexp pattern for resolved hostname that will not be accepted (not cached, not written to db). This is done to avoid storing wrong resolves due to network glitches.
@@ -68,21 +96,30 @@ type Configuration struct {
 	AuditPageSize                              int
 	RemoveTextFromHostnameDisplay              string // Text to strip off the hostname on cluster/clusters pages
 	ReadOnly                                   bool
-	AuthenticationMethod                       string            // Type of autherntication to use, if any. "" for none, "basic" for BasicAuth, "multi" for advanced BasicAuth, "proxy" for forwarded credentials via reverse proxy
+	AuthenticationMethod                       string            // Type of autherntication to use, if any. "" for none, "basic" for BasicAuth, "multi" for advanced BasicAuth, "proxy" for forwarded credentials via reverse proxy, "ssl" for client certificates (requires UseMutualTLS)
 	HTTPAuthUser                               string            // Username for HTTP Basic authentication (blank disables authentication)
 	HTTPAuthPassword                           string            // Password for HTTP Basic authentication
 	AuthUserHeader                             string            // HTTP header indicating auth user, when AuthenticationMethod is "proxy"
-	PowerAuthUsers                             []string          // On AuthenticationMethod == "proxy", list of users that can make changes. All others are read-only.
+	PowerAuthUsers                             []string          // On AuthenticationMethod == "proxy" or "ssl", list of users that can make changes. All others are read-only.
+	RoleBindings                               []RoleBinding     // Role based access control. When bindings exist (here or in the backend database), API actions require a viewer/operator/admin role on the affected cluster, replacing the read-only/writer distinction
+	AllowMutatingGetRequests                   bool              // When "true", state changing API routes are served via GET as well as POST. Deprecated; set to "false" so that crawlers, link previews or prefetch cannot trigger changes
 	ClusterNameToAlias                         map[string]string // map between regex matching cluster name to a human friendly alias
 	DetectClusterAliasQuery                    string            // Optional query (executed on topology instance) that returns the alias of a cluster. Query will only be executed on cluster master (though until the topology's master is resovled it may execute on other/all slaves). If provided, must return one row, one column
 	DataCenterPattern                          string            // Regexp pattern with one group, extracting the datacenter name from the hostname
 	PhysicalEnvironmentPattern                 string            // Regexp pattern with one group, extracting physical environment info from hostname (e.g. combination of datacenter & prod/dev env)
 	PromotionIgnoreHostnameFilters             []string          // Orchestrator will not promote slaves with hostname matching pattern (via -c recovery; for example, avoid promoting dev-dedicated machines)
+	PromotionIgnoreTagSelectors                []string          // Orchestrator will not promote slaves matching any of these tag selectors (e.g. "role=reporting")
+	AllowCrossDataCenterMasterFailover         bool              // When false, a failed master is only replaced by a slave in the same data center (applies when DataCenterPattern is in use)
+	KeepIntraDataCenterReplicationChains       bool              // When true, after master failover slaves residing in a remote data center are regrouped below a local sibling rather than each replicating cross data center
 	ServeAgentsHttp                            bool              // Spawn another HTTP interface dedicated for orcehstrator-agent
 	AgentsUseSSL                               bool              // When "true" orchestrator will listen on agents port with SSL as well as connect to agents via SSL
 	SSLSkipVerify                              bool              // When using SSL, should we ignore SSL certification error
-	SSLPrivateKeyFile                          string            // Name of SSL private key file, applies only when AgentsUseSSL = true
-	SSLCertFile                                string            // Name of SSL certification file, applies only when AgentsUseSSL = true
+	SSLPrivateKeyFile                          string            // Name of SSL private key file, applies when AgentsUseSSL = true or UseSSL = true
+	SSLCertFile                                string            // Name of SSL certification file, applies when AgentsUseSSL = true or UseSSL = true
+	SSLCAFile                                  string            // Name of SSL CA bundle file, used to verify client certificates when UseMutualTLS = true
+	UseSSL                                     bool              // When "true" the main web/API listener serves HTTPS, using SSLCertFile & SSLPrivateKeyFile
+	UseMutualTLS                               bool              // When "true" (along with UseSSL) clients must present a certificate signed by SSLCAFile. With AuthenticationMethod = "ssl" the certificate's common name is the user identity
+	AgentsListenAddress                        string            // Address on which agents HTTP interface listens (when ServeAgentsHttp = true)
 	HttpTimeoutSeconds                         int               // Number of idle seconds before HTTP GET request times out (when accessing orchestrator-agent)
 	AgentPollMinutes                           uint              // Minutes between agent polling
 	UnseenAgentForgetHours                     uint              // Number of hours after which an unseen agent is forgotten
@@ -93,15 +130,30 @@ type Configuration struct {
 	BinlogEventsChunkSize                      int               // Chunk size (X) for SHOW BINLOG|RELAYLOG EVENTS LIMIT ?,X statements. Smaller means less locking and mroe work to be done
 	BufferBinlogEvents                         bool              // Should we used buffered read on SHOW BINLOG|RELAYLOG EVENTS -- releases the database lock sooner (recommended)
 	RecoveryPeriodBlockMinutes                 int               // The time for which an instance's recovery is kept "active", so as to avoid concurrent recoveries on smae instance as well as flapping
+	MaxClusterRecoveriesPerHour                int               // Maximum number of recoveries on a single cluster within an hour. Once reached, further recoveries on the cluster are blocked until manually acknowledged. 0 for unlimited
+	MaxGlobalRecoveries                        int               // Maximum number of recoveries across all clusters within MaxGlobalRecoveriesWindowMinutes. Once reached, all recoveries are blocked until manually acknowledged. 0 for unlimited
+	MaxGlobalRecoveriesWindowMinutes           int               // Time window for MaxGlobalRecoveries
+	BlockRecoveriesUntilAcknowledged           bool              // When true, a recovery on a cluster is blocked while previous recoveries on that cluster have not been acknowledged
+	LostInRecoveryDowntimeSeconds              int               // Number of seconds to downtime slaves lost in master recovery (slaves that could not be regrouped below the promoted master). 0 to disable
+	MinSemiSyncSlaves                          uint              // Minimal number of replicating semi-sync slaves expected on a semi-sync master; effective value is at least rpl_semi_sync_master_wait_for_slave_count. Also the number of slaves enabled as semi-sync after master failover. Refactoring which would leave a semi-sync master with fewer semi-sync slaves is refused
+	CaptureFailureDetectionSnapshots           bool              // When true, PROCESSLIST, InnoDB status and global status are captured from an instance (and its slaves) upon UnreachableMaster/AllMasterSlavesNotReplicating analysis
+	FailureDetectionSnapshotStatusVariables    []string          // Global status variables to capture in failure detection snapshots
+	FailureDetectionSnapshotExpiryDays         uint              // Days after which failure detection snapshots are purged
+	InstanceChangeLogExpiryDays                uint              // Days after which instance change log entries (master, read_only, version, binlog_format etc. transitions) are purged
+	PostPromotionSetWriteable                  bool              // When true, a newly promoted master (via master recovery or make-master) is set with read_only=0
+	PostPromotionResetSlaveMethod              string            // How to discard replication config on a newly promoted master: "" (leave as is), "reset" (RESET SLAVE ALL) or "detach" (detach-slave; reversible)
+	PostPromotionRegisterClusterAlias          bool              // When true, the alias of the failed cluster is registered onto the cluster of the newly promoted master
+	PostPromotionClusterAliasUpdateQuery       string            // Optional query to execute on a newly promoted master, updating the data read by DetectClusterAliasQuery. A single '?' placeholder is replaced with the cluster alias
 	RecoveryIgnoreHostnameFilters              []string          // Recovery analysis will completely ignore hosts matching given patterns
 	RecoverMasterClusterFilters                []string          // Only do master recovery on clusters matching these regexp patterns (of course the ".*" pattern matches everything)
 	RecoverIntermediateMasterClusterFilters    []string          // Only do IM recovery on clusters matching these regexp patterns (of course the ".*" pattern matches everything)
 	OnFailureDetectionProcesses                []string          // Processes to execute when detecting a failover scenario (before making a decision whether to failover or not). May and should use some of these placeholders: {failureType}, {failureDescription}, {failedHost}, {failureCluster}, {failureClusterAlias}, {failedPort}, {successorHost}, {successorPort}, {countSlaves}, {slaveHosts}
 	PreFailoverProcesses                       []string          // Processes to execute before doing a failover (aborting operation should any once of them exits with non-zero code; order of execution undefined). May and should use some of these placeholders: {failureType}, {failureDescription}, {failedHost}, {failureCluster}, {failureClusterAlias}, {failedPort}, {successorHost}, {successorPort}, {countSlaves}, {slaveHosts}
-	PostFailoverProcesses                      []string          // Processes to execute after doing a failover (order of execution undefined). May and should use some of these placeholders: {failureType}, {failureDescription}, {failedHost}, {failureCluster}, {failureClusterAlias}, {failedPort}, {successorHost}, {successorPort}, {countSlaves}, {slaveHosts}
+	PostFailoverProcesses                      []string          // Processes to execute after doing a failover (order of execution undefined). May and should use some of these placeholders: {failureType}, {failureDescription}, {failedHost}, {failureCluster}, {failureClusterAlias}, {failedPort}, {successorHost}, {successorPort}, {countSlaves}, {slaveHosts}, {lostSlaves}
 	PostMasterFailoverProcesses                []string          // Processes to execute after doing a master failover (order of execution undefined). Uses same placeholders as PostFailoverProcesses
 	PostIntermediateMasterFailoverProcesses    []string          // Processes to execute after doing a master failover (order of execution undefined). Uses same placeholders as PostFailoverProcesses
 	OSCIgnoreHostnameFilters                   []string          // OSC slaves recommendation will ignore slave hostnames matching given patterns
+	OSCTagSelector                             string            // When non empty, OSC slaves recommendation only considers slaves matching this tag selector (e.g. "hw=ssd-gen3")
 }
 
 var Config *Configuration = NewConfiguration()
@@ -114,6 +166,21 @@ func NewConfiguration() *Configuration {
 		MySQLOrchestratorPort:                      3306,
 		MySQLTopologyMaxPoolConnections:            3,
 		MySQLConnectTimeoutSeconds:                 5,
+		MySQLTopologyUseSSL:                        false,
+		MySQLTopologySSLCAFile:                     "",
+		MySQLTopologySSLCertFile:                   "",
+		MySQLTopologySSLPrivateKeyFile:             "",
+		MySQLTopologySSLSkipVerify:                 false,
+		MySQLOrchestratorUseSSL:                    false,
+		MySQLOrchestratorSSLCAFile:                 "",
+		MySQLOrchestratorSSLCertFile:               "",
+		MySQLOrchestratorSSLPrivateKeyFile:         "",
+		MySQLOrchestratorSSLSkipVerify:             false,
+		ReplicationUseSSL:                          false,
+		ReplicationSSLCAFile:                       "",
+		ReplicationSSLCertFile:                     "",
+		ReplicationSSLPrivateKeyFile:               "",
+		ReplicationSSLVerifyServerCert:             false,
 		DefaultInstancePort:                        3306,
 		SkipOrchestratorDatabaseUpdate:             false,
 		InstancePollSeconds:                        60,
@@ -124,8 +191,12 @@ func NewConfiguration() *Configuration {
 		DiscoverByShowSlaveHosts:                   false,
 		DiscoveryPollSeconds:                       5,
 		InstanceBulkOperationsWaitTimeoutSeconds:   60,
+		BulkOperationsMaxConcurrency:               10,
 		ActiveNodeExpireSeconds:                    60,
 		HostnameResolveMethod:                      "cname",
+		HostnameResolveStaticMapFile:               "",
+		HostnameResolveHTTPURL:                     "",
+		HostnameResolveHTTPTimeoutSeconds:          3,
 		MySQLHostnameResolveMethod:                 "@@hostname",
 		ExpiryHostnameResolvesMinutes:              60,
 		RejectHostnameResolvePattern:               "",
@@ -145,16 +216,25 @@ func NewConfiguration() *Configuration {
 		HTTPAuthPassword:                           "",
 		AuthUserHeader:                             "X-Forwarded-User",
 		PowerAuthUsers:                             []string{"*"},
+		RoleBindings:                               []RoleBinding{},
+		AllowMutatingGetRequests:                   true,
 		ClusterNameToAlias:                         make(map[string]string),
 		DetectClusterAliasQuery:                    "",
 		DataCenterPattern:                          "",
 		PhysicalEnvironmentPattern:                 "",
 		PromotionIgnoreHostnameFilters:             []string{},
+		PromotionIgnoreTagSelectors:                []string{},
+		AllowCrossDataCenterMasterFailover:         false,
+		KeepIntraDataCenterReplicationChains:       false,
 		ServeAgentsHttp:                            false,
 		AgentsUseSSL:                               false,
 		SSLSkipVerify:                              false,
 		SSLPrivateKeyFile:                          "",
 		SSLCertFile:                                "",
+		SSLCAFile:                                  "",
+		UseSSL:                                     false,
+		UseMutualTLS:                               false,
+		AgentsListenAddress:                        ":3001",
 		HttpTimeoutSeconds:                         60,
 		AgentPollMinutes:                           60,
 		UnseenAgentForgetHours:                     6,
@@ -165,6 +245,20 @@ func NewConfiguration() *Configuration {
 		BinlogEventsChunkSize:                      10000,
 		BufferBinlogEvents:                         true,
 		RecoveryPeriodBlockMinutes:                 60,
+		MaxClusterRecoveriesPerHour:                0,
+		MaxGlobalRecoveries:                        0,
+		MaxGlobalRecoveriesWindowMinutes:           60,
+		BlockRecoveriesUntilAcknowledged:           false,
+		LostInRecoveryDowntimeSeconds:              0,
+		MinSemiSyncSlaves:                          1,
+		CaptureFailureDetectionSnapshots:           false,
+		FailureDetectionSnapshotStatusVariables:    []string{"Uptime", "Threads_connected", "Threads_running", "Max_used_connections", "Aborted_connects", "Connection_errors_max_connections", "Questions", "Slow_queries", "Innodb_row_lock_current_waits", "Innodb_buffer_pool_pages_dirty", "Innodb_data_pending_fsyncs", "Open_files", "Slave_running"},
+		FailureDetectionSnapshotExpiryDays:         7,
+		InstanceChangeLogExpiryDays:                365,
+		PostPromotionSetWriteable:                  false,
+		PostPromotionResetSlaveMethod:              "",
+		PostPromotionRegisterClusterAlias:          false,
+		PostPromotionClusterAliasUpdateQuery:       "",
 		RecoveryIgnoreHostnameFilters:              []string{},
 		RecoverMasterClusterFilters:                []string{},
 		RecoverIntermediateMasterClusterFilters:    []string{},
@@ -174,6 +268,7 @@ func NewConfiguration() *Configuration {
 		PostIntermediateMasterFailoverProcesses:    []string{},
 		PostFailoverProcesses:                      []string{},
 		OSCIgnoreHostnameFilters:                   []string{},
+		OSCTagSelector:                             "",
 	}
 }
 
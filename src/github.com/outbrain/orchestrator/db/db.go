@@ -228,6 +228,41 @@ var generateSQL = []string{
 		  KEY start_active_period_idx (start_active_period)
 		) ENGINE=InnoDB DEFAULT CHARSET=ascii
 	`,
+	`
+		CREATE TABLE IF NOT EXISTS topology_recovery_steps (
+          recovery_step_id bigint unsigned not null auto_increment,
+          recovery_id bigint unsigned not null,
+          audit_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          message text CHARACTER SET utf8 NOT NULL,
+          PRIMARY KEY (recovery_step_id),
+          KEY recovery_id_idx (recovery_id)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS topology_recovery_block (
+          block_id bigint unsigned not null auto_increment,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          block_reason text CHARACTER SET utf8 NOT NULL,
+          start_block timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          is_acknowledged tinyint unsigned NOT NULL DEFAULT 0,
+          acknowledged_by varchar(128) CHARACTER SET utf8 NOT NULL DEFAULT '',
+          acknowledged_at timestamp NULL DEFAULT NULL,
+          PRIMARY KEY (block_id),
+          KEY cluster_name_acknowledged_idx (cluster_name, is_acknowledged)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+		CREATE TABLE IF NOT EXISTS blocked_topology_recovery (
+		  hostname varchar(128) NOT NULL,
+		  port smallint unsigned NOT NULL,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          analysis varchar(128) CHARACTER SET ascii NOT NULL,
+          last_blocked_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          blocking_reason text CHARACTER SET utf8 NOT NULL,
+		  PRIMARY KEY (hostname, port),
+          KEY cluster_blocked_idx (cluster_name, last_blocked_timestamp)
+		) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
 	`
 		CREATE TABLE IF NOT EXISTS hostname_unresolve (
 		  hostname varchar(128) NOT NULL,
@@ -267,6 +302,18 @@ var generateSQL = []string{
           KEY last_suggested_idx (last_suggested)
         ) ENGINE=InnoDB DEFAULT CHARSET=ascii
 
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS database_instance_promotion_rule (
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          promotion_rule varchar(16) CHARACTER SET ascii NOT NULL,
+          registered_timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          expire_timestamp TIMESTAMP NULL DEFAULT NULL,
+          PRIMARY KEY (hostname, port),
+          KEY expire_timestamp_idx (expire_timestamp)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+
 	`,
 	`
         CREATE TABLE IF NOT EXISTS database_instance_downtime (
@@ -280,6 +327,142 @@ var generateSQL = []string{
           PRIMARY KEY (hostname, port)
         ) ENGINE=InnoDB DEFAULT CHARSET=ascii
 	`,
+	`
+        CREATE TABLE IF NOT EXISTS database_instance_replication_channel (
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          channel_name varchar(64) CHARACTER SET utf8 NOT NULL,
+          master_host varchar(128) CHARACTER SET ascii NOT NULL,
+          master_port smallint(5) unsigned NOT NULL,
+          slave_sql_running tinyint(3) unsigned NOT NULL,
+          slave_io_running tinyint(3) unsigned NOT NULL,
+          master_log_file varchar(128) CHARACTER SET ascii NOT NULL,
+          read_master_log_pos bigint(20) unsigned NOT NULL,
+          relay_master_log_file varchar(128) CHARACTER SET ascii NOT NULL,
+          exec_master_log_pos bigint(20) unsigned NOT NULL,
+          last_sql_error text NOT NULL,
+          last_io_error text NOT NULL,
+          seconds_behind_master bigint(20) unsigned DEFAULT NULL,
+          last_checked timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          PRIMARY KEY (hostname, port, channel_name),
+          KEY master_host_port_idx (master_host, master_port)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS query_kill_policy (
+          policy_id int(10) unsigned NOT NULL AUTO_INCREMENT,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          user_pattern varchar(128) CHARACTER SET utf8 NOT NULL,
+          db_pattern varchar(128) CHARACTER SET utf8 NOT NULL,
+          command_pattern varchar(128) CHARACTER SET utf8 NOT NULL,
+          max_time_seconds int(10) unsigned NOT NULL,
+          apply_to varchar(16) CHARACTER SET ascii NOT NULL,
+          dry_run tinyint(3) unsigned NOT NULL,
+          created_by varchar(128) CHARACTER SET utf8 NOT NULL,
+          created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          PRIMARY KEY (policy_id),
+          KEY cluster_name_idx (cluster_name)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS query_kill_history (
+          kill_id bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+          policy_id int(10) unsigned NOT NULL,
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          process_id bigint(20) NOT NULL,
+          process_started_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          process_user varchar(16) CHARACTER SET utf8 NOT NULL,
+          process_host varchar(128) CHARACTER SET utf8 NOT NULL,
+          process_db varchar(128) CHARACTER SET utf8 NOT NULL,
+          process_command varchar(16) CHARACTER SET utf8 NOT NULL,
+          process_time_seconds int(11) NOT NULL,
+          process_info varchar(1024) CHARACTER SET utf8 NOT NULL,
+          is_dry_run tinyint(3) unsigned NOT NULL,
+          killed_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          kill_error text CHARACTER SET utf8 NOT NULL,
+          PRIMARY KEY (kill_id),
+          UNIQUE KEY process_uidx (hostname, port, process_id, process_started_at, policy_id),
+          KEY cluster_killed_at_idx (cluster_name, killed_at)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS failure_detection_snapshot (
+          snapshot_id bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          analyzed_hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          analyzed_port smallint(5) unsigned NOT NULL,
+          analysis varchar(128) CHARACTER SET ascii NOT NULL,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          captured_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          processlist mediumtext CHARACTER SET utf8 NOT NULL,
+          innodb_status mediumtext CHARACTER SET utf8 NOT NULL,
+          global_status text CHARACTER SET utf8 NOT NULL,
+          capture_error text CHARACTER SET utf8 NOT NULL,
+          PRIMARY KEY (snapshot_id),
+          KEY analyzed_instance_idx (analyzed_hostname, analyzed_port, captured_at),
+          KEY cluster_captured_at_idx (cluster_name, captured_at),
+          KEY captured_at_idx (captured_at)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS database_instance_tag (
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          tag_name varchar(128) CHARACTER SET utf8 NOT NULL,
+          tag_value varchar(255) CHARACTER SET utf8 NOT NULL,
+          last_updated timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          PRIMARY KEY (hostname, port, tag_name),
+          KEY tag_name_idx (tag_name, tag_value)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS scheduled_maintenance_window (
+          window_id int(10) unsigned NOT NULL AUTO_INCREMENT,
+          window_type varchar(32) CHARACTER SET ascii NOT NULL,
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          start_timestamp timestamp NOT NULL DEFAULT '0000-00-00 00:00:00',
+          duration_seconds int(10) unsigned NOT NULL,
+          recurrence varchar(32) CHARACTER SET ascii NOT NULL,
+          owner varchar(128) CHARACTER SET utf8 NOT NULL,
+          reason text CHARACTER SET utf8 NOT NULL,
+          window_active tinyint(3) unsigned NOT NULL DEFAULT 0,
+          window_completed tinyint(3) unsigned NOT NULL DEFAULT 0,
+          last_activated_timestamp timestamp NULL DEFAULT NULL,
+          PRIMARY KEY (window_id),
+          KEY start_timestamp_idx (window_completed, start_timestamp)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS role_binding (
+          user_name varchar(128) CHARACTER SET utf8 NOT NULL,
+          role varchar(32) CHARACTER SET ascii NOT NULL,
+          cluster_pattern varchar(255) CHARACTER SET utf8 NOT NULL,
+          granted_by varchar(128) CHARACTER SET utf8 NOT NULL,
+          granted_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          PRIMARY KEY (user_name, role, cluster_pattern)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
+	`
+        CREATE TABLE IF NOT EXISTS database_instance_change_log (
+          change_id bigint(20) unsigned NOT NULL AUTO_INCREMENT,
+          hostname varchar(128) CHARACTER SET ascii NOT NULL,
+          port smallint(5) unsigned NOT NULL,
+          cluster_name varchar(128) CHARACTER SET ascii NOT NULL,
+          change_timestamp timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+          attribute_name varchar(64) CHARACTER SET ascii NOT NULL,
+          old_value varchar(255) CHARACTER SET utf8 NOT NULL,
+          new_value varchar(255) CHARACTER SET utf8 NOT NULL,
+          PRIMARY KEY (change_id),
+          KEY instance_change_timestamp_idx (hostname, port, change_timestamp),
+          KEY cluster_change_timestamp_idx (cluster_name, change_timestamp),
+          KEY change_timestamp_idx (change_timestamp)
+        ) ENGINE=InnoDB DEFAULT CHARSET=ascii
+	`,
 }
 
 var generateSQLPatches = []string{
@@ -392,11 +575,61 @@ var generateSQLPatches = []string{
 			ADD COLUMN count_affected_slaves int unsigned NOT NULL,
 			ADD COLUMN slave_hosts text CHARACTER SET ascii NOT NULL
 	`,
+	`
+		ALTER TABLE 
+			topology_recovery
+			ADD COLUMN acknowledged         TINYINT UNSIGNED NOT NULL DEFAULT 0,
+			ADD COLUMN acknowledged_by      varchar(128) CHARACTER SET utf8 NOT NULL DEFAULT '',
+			ADD COLUMN acknowledged_comment text CHARACTER SET utf8 NOT NULL,
+			ADD COLUMN acknowledged_at      TIMESTAMP NULL DEFAULT NULL,
+			ADD KEY acknowledged_idx (acknowledged, acknowledged_at)
+	`,
+	`
+		ALTER TABLE 
+			topology_recovery
+			ADD COLUMN lost_slaves text CHARACTER SET ascii NOT NULL
+	`,
+	`
+		ALTER TABLE 
+			database_instance
+			ADD COLUMN semi_sync_master_enabled TINYINT UNSIGNED NOT NULL AFTER is_co_master,
+			ADD COLUMN semi_sync_slave_enabled TINYINT UNSIGNED NOT NULL AFTER semi_sync_master_enabled,
+			ADD COLUMN semi_sync_master_status TINYINT UNSIGNED NOT NULL AFTER semi_sync_slave_enabled,
+			ADD COLUMN semi_sync_slave_status TINYINT UNSIGNED NOT NULL AFTER semi_sync_master_status,
+			ADD COLUMN semi_sync_master_clients INT UNSIGNED NOT NULL AFTER semi_sync_slave_status,
+			ADD COLUMN semi_sync_master_wait_for_slave_count INT UNSIGNED NOT NULL AFTER semi_sync_master_clients
+	`,
+	`
+		ALTER TABLE 
+			database_instance
+			ADD COLUMN replicate_do_db text CHARACTER SET utf8 NOT NULL AFTER has_replication_filters,
+			ADD COLUMN replicate_ignore_db text CHARACTER SET utf8 NOT NULL AFTER replicate_do_db,
+			ADD COLUMN replicate_do_table text CHARACTER SET utf8 NOT NULL AFTER replicate_ignore_db,
+			ADD COLUMN replicate_ignore_table text CHARACTER SET utf8 NOT NULL AFTER replicate_do_table,
+			ADD COLUMN replicate_wild_do_table text CHARACTER SET utf8 NOT NULL AFTER replicate_ignore_table,
+			ADD COLUMN replicate_wild_ignore_table text CHARACTER SET utf8 NOT NULL AFTER replicate_wild_do_table
+	`,
+	`
+		ALTER TABLE 
+			database_instance_long_running_queries
+			MODIFY process_user varchar(32) CHARACTER SET utf8 NOT NULL
+	`,
+	`
+		ALTER TABLE 
+			query_kill_history
+			MODIFY process_user varchar(32) CHARACTER SET utf8 NOT NULL
+	`,
 }
 
 // OpenTopology returns a DB instance to access a topology instance
 func OpenTopology(host string, port int) (*sql.DB, error) {
 	mysql_uri := fmt.Sprintf("%s:%s@tcp(%s:%d)/?timeout=%ds", config.Config.MySQLTopologyUser, config.Config.MySQLTopologyPassword, host, port, config.Config.MySQLConnectTimeoutSeconds)
+	if config.Config.MySQLTopologyUseSSL {
+		if err := registerTopologyTLSConfig(); err != nil {
+			return nil, log.Errore(err)
+		}
+		mysql_uri = fmt.Sprintf("%s&tls=%s", mysql_uri, topologyTLSConfigKey)
+	}
 	db, _, err := sqlutils.GetDB(mysql_uri)
 	db.SetMaxOpenConns(config.Config.MySQLTopologyMaxPoolConnections)
 	db.SetMaxIdleConns(config.Config.MySQLTopologyMaxPoolConnections)
@@ -407,6 +640,12 @@ func OpenTopology(host string, port int) (*sql.DB, error) {
 func OpenOrchestrator() (*sql.DB, error) {
 	mysql_uri := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?timeout=%ds", config.Config.MySQLOrchestratorUser, config.Config.MySQLOrchestratorPassword,
 		config.Config.MySQLOrchestratorHost, config.Config.MySQLOrchestratorPort, config.Config.MySQLOrchestratorDatabase, config.Config.MySQLConnectTimeoutSeconds)
+	if config.Config.MySQLOrchestratorUseSSL {
+		if err := registerOrchestratorTLSConfig(); err != nil {
+			return nil, log.Errore(err)
+		}
+		mysql_uri = fmt.Sprintf("%s&tls=%s", mysql_uri, orchestratorTLSConfigKey)
+	}
 	db, fromCache, err := sqlutils.GetDB(mysql_uri)
 	if err == nil && !fromCache {
 		if !config.Config.SkipOrchestratorDatabaseUpdate {
@@ -0,0 +1,66 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package db
+
+import (
+	"github.com/go-sql-driver/mysql"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/ssl"
+	"sync"
+)
+
+// Names under which TLS configurations are registered with the MySQL driver; referenced by DSN "tls" param
+const (
+	topologyTLSConfigKey     = "topology"
+	orchestratorTLSConfigKey = "orchestrator"
+)
+
+var topologyTLSOnce, orchestratorTLSOnce sync.Once
+var topologyTLSError, orchestratorTLSError error
+
+// registerTLSConfig builds a client TLS configuration and registers it with the MySQL driver
+func registerTLSConfig(key string, caFile string, certFile string, keyFile string, skipVerify bool) error {
+	tlsConfig, err := ssl.NewTLSConfig(caFile, false)
+	if err != nil {
+		return err
+	}
+	if certFile != "" {
+		if err := ssl.AppendKeyPair(tlsConfig, certFile, keyFile); err != nil {
+			return err
+		}
+	}
+	tlsConfig.InsecureSkipVerify = skipVerify
+	return mysql.RegisterTLSConfig(key, tlsConfig)
+}
+
+// registerTopologyTLSConfig registers, once, the TLS configuration for topology connections
+func registerTopologyTLSConfig() error {
+	topologyTLSOnce.Do(func() {
+		topologyTLSError = registerTLSConfig(topologyTLSConfigKey, config.Config.MySQLTopologySSLCAFile,
+			config.Config.MySQLTopologySSLCertFile, config.Config.MySQLTopologySSLPrivateKeyFile, config.Config.MySQLTopologySSLSkipVerify)
+	})
+	return topologyTLSError
+}
+
+// registerOrchestratorTLSConfig registers, once, the TLS configuration for backend database connections
+func registerOrchestratorTLSConfig() error {
+	orchestratorTLSOnce.Do(func() {
+		orchestratorTLSError = registerTLSConfig(orchestratorTLSConfigKey, config.Config.MySQLOrchestratorSSLCAFile,
+			config.Config.MySQLOrchestratorSSLCertFile, config.Config.MySQLOrchestratorSSLPrivateKeyFile, config.Config.MySQLOrchestratorSSLSkipVerify)
+	})
+	return orchestratorTLSError
+}
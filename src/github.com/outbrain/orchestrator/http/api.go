@@ -22,8 +22,10 @@ import (
 	"github.com/go-martini/martini"
 	"github.com/martini-contrib/auth"
 	"github.com/martini-contrib/render"
+	"github.com/outbrain/golib/util"
 	"net"
 	"net/http"
+	"regexp"
 	"strconv"
 
 	"github.com/outbrain/orchestrator/agent"
@@ -70,6 +72,15 @@ func (this *HttpAPI) getInstanceKey(host string, port string) (inst.InstanceKey,
 	return *instanceKey, err
 }
 
+// getParam returns the named route param or, lacking such, the query param (which includes JSON body params
+// of POST/DELETE requests)
+func (this *HttpAPI) getParam(params martini.Params, req *http.Request, name string) string {
+	if value := params[name]; value != "" {
+		return value
+	}
+	return req.URL.Query().Get(name)
+}
+
 // Instance reads and returns an instance's details.
 func (this *HttpAPI) Instance(params martini.Params, r render.Render, req *http.Request) {
 	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
@@ -170,7 +181,7 @@ func (this *HttpAPI) BeginMaintenance(params martini.Params, r render.Render, re
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
 	}
-	key, err := inst.BeginMaintenance(&instanceKey, params["owner"], params["reason"])
+	key, err := inst.BeginMaintenance(&instanceKey, this.getParam(params, req, "owner"), this.getParam(params, req, "reason"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error(), Details: key})
 		return
@@ -232,7 +243,7 @@ func (this *HttpAPI) Maintenance(params martini.Params, r render.Render, req *ht
 	r.JSON(200, instanceKeys)
 }
 
-// BeginDowntime sets a downtime flag with default duration
+// BeginDowntime sets a downtime flag, with default duration unless "duration" is given
 func (this *HttpAPI) BeginDowntime(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
@@ -244,7 +255,15 @@ func (this *HttpAPI) BeginDowntime(params martini.Params, r render.Render, req *
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
 	}
-	err = inst.BeginDowntime(&instanceKey, params["owner"], params["reason"], 0)
+	var durationSeconds int = 0
+	if duration := this.getParam(params, req, "duration"); duration != "" {
+		durationSeconds, err = util.SimpleTimeToSeconds(duration)
+		if err != nil || durationSeconds < 0 {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Invalid duration: %s", duration)})
+			return
+		}
+	}
+	err = inst.BeginDowntime(&instanceKey, this.getParam(params, req, "owner"), this.getParam(params, req, "reason"), uint(durationSeconds))
 
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error(), Details: instanceKey})
@@ -275,6 +294,75 @@ func (this *HttpAPI) EndDowntime(params martini.Params, r render.Render, req *ht
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Downtime ended: %+v", instanceKey)})
 }
 
+// RegisterPromotionRule persists a promotion rule (prefer, neutral, prefer_not, must_not) for an instance,
+// with optional expiry duration
+func (this *HttpAPI) RegisterPromotionRule(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	promotionRule, err := inst.ParseCandidatePromotionRule(params["promotionRule"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	var durationSeconds int = 0
+	if params["duration"] != "" {
+		durationSeconds, err = util.SimpleTimeToSeconds(params["duration"])
+		if err != nil || durationSeconds < 0 {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Invalid duration: %s", params["duration"])})
+			return
+		}
+	}
+	err = inst.RegisterPromotionRule(&instanceKey, promotionRule, uint(durationSeconds))
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error(), Details: instanceKey})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Promotion rule registered: %+v: %s", instanceKey, promotionRule), Details: instanceKey})
+}
+
+// UnregisterPromotionRule removes a persisted promotion rule from an instance
+func (this *HttpAPI) UnregisterPromotionRule(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	err = inst.UnregisterPromotionRule(&instanceKey)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Promotion rule removed: %+v", instanceKey), Details: instanceKey})
+}
+
+// PromotionRules returns registered promotion rules, optionally limited to a given cluster
+func (this *HttpAPI) PromotionRules(params martini.Params, r render.Render, req *http.Request) {
+	promotionRules, err := inst.ReadPromotionRules(params["clusterName"])
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, promotionRules)
+}
+
 // MoveUp attempts to move an instance up the topology
 func (this *HttpAPI) MoveUp(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
@@ -429,6 +517,37 @@ func (this *HttpAPI) MoveBelow(params martini.Params, r render.Render, req *http
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Instance %+v moved below %+v", instanceKey, siblingKey), Details: instance})
 }
 
+// Repoint points an instance at a given master (its current master when not given) without changing
+// binlog coordinates. Accepts an optional "channel" query param for multi-source replication.
+func (this *HttpAPI) Repoint(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	var masterKey *inst.InstanceKey
+	if params["belowHost"] != "" {
+		belowKey, err := this.getInstanceKey(params["belowHost"], params["belowPort"])
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+			return
+		}
+		masterKey = &belowKey
+	}
+
+	instance, err := inst.RepointChannel(&instanceKey, masterKey, req.URL.Query().Get("channel"))
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Instance %+v repointed", instanceKey), Details: instance})
+}
+
 // EnslaveSiblings
 func (this *HttpAPI) EnslaveSiblings(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
@@ -641,6 +760,15 @@ func (this *HttpAPI) MakeMaster(params martini.Params, r render.Render, req *htt
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
 	}
+	clusterAlias := ""
+	if clusterInfo, err := inst.ReadClusterInfo(instance.ClusterName); err == nil {
+		clusterAlias = clusterInfo.ClusterAlias
+	}
+	instance, err = orchestrator.ApplyPostPromotionActions(&instance.MasterKey, &instanceKey, clusterAlias)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
 
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Instance %+v now made master", instanceKey), Details: instance})
 }
@@ -700,7 +828,7 @@ func (this *HttpAPI) StartSlave(params martini.Params, r render.Render, req *htt
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
 	}
-	instance, err := inst.StartSlave(&instanceKey)
+	instance, err := inst.StartSlaveChannel(&instanceKey, req.URL.Query().Get("channel"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
@@ -721,7 +849,7 @@ func (this *HttpAPI) StopSlave(params martini.Params, r render.Render, req *http
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
 	}
-	instance, err := inst.StopSlave(&instanceKey)
+	instance, err := inst.StopSlaveChannel(&instanceKey, req.URL.Query().Get("channel"))
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
 		return
@@ -819,9 +947,261 @@ func (this *HttpAPI) KillQuery(params martini.Params, r render.Render, req *http
 	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Query killed on : %+v", instance.Key), Details: instance})
 }
 
+// TagInstance sets a tag on an instance. The tag is given by the "tag" query param, in name=value format
+func (this *HttpAPI) TagInstance(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	tag, err := inst.ParseTag(req.URL.Query().Get("tag"), false)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	err = inst.TagInstance(&instanceKey, tag)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("%+v tagged with %s", instanceKey, tag.String()), Details: instanceKey})
+}
+
+// UntagInstance removes a tag, given by name via the "tag" query param, from an instance
+func (this *HttpAPI) UntagInstance(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	tag, err := inst.ParseTag(req.URL.Query().Get("tag"), true)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	err = inst.UntagInstance(&instanceKey, tag.TagName)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("%+v untagged %s", instanceKey, tag.TagName), Details: instanceKey})
+}
+
+// InstanceTags lists the tags of an instance
+func (this *HttpAPI) InstanceTags(params martini.Params, r render.Render, req *http.Request) {
+	instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	tags, err := inst.ReadInstanceTags(&instanceKey)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, tags)
+}
+
+// TaggedInstances lists instances matching the tag selector given by the "tag" query param
+func (this *HttpAPI) TaggedInstances(params martini.Params, r render.Render, req *http.Request) {
+	instances, err := inst.ReadTaggedInstances(req.URL.Query().Get("tag"))
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, instances)
+}
+
+// scheduleWindow creates a scheduled maintenance/downtime window for an instance or for a cluster. Window
+// details are given by the query params start, duration, recurrence, owner & reason.
+func (this *HttpAPI) scheduleWindow(windowType string, params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	query := req.URL.Query()
+	window := &inst.ScheduledMaintenanceWindow{
+		WindowType:     windowType,
+		ClusterName:    params["clusterName"],
+		StartTimestamp: query.Get("start"),
+		Recurrence:     query.Get("recurrence"),
+		Owner:          query.Get("owner"),
+		Reason:         query.Get("reason"),
+	}
+	if window.Owner == "" {
+		window.Owner = getUserId(req, user)
+	}
+	if window.ClusterName == "" {
+		instanceKey, err := this.getInstanceKey(params["host"], params["port"])
+		if err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+			return
+		}
+		window.Key = instanceKey
+	}
+	durationSeconds, err := util.SimpleTimeToSeconds(query.Get("duration"))
+	if err != nil || durationSeconds <= 0 {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Invalid duration: %s", query.Get("duration"))})
+		return
+	}
+	window.DurationSeconds = uint(durationSeconds)
+
+	windowId, err := inst.CreateScheduledMaintenanceWindow(window)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Scheduled %s window %d for %s", windowType, windowId, window.TargetDescription()), Details: windowId})
+}
+
+// ScheduleMaintenance creates a scheduled maintenance window for an instance or a cluster
+func (this *HttpAPI) ScheduleMaintenance(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	this.scheduleWindow(inst.ScheduledMaintenance, params, r, req, user)
+}
+
+// ScheduleDowntime creates a scheduled downtime window for an instance or a cluster
+func (this *HttpAPI) ScheduleDowntime(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	this.scheduleWindow(inst.ScheduledDowntime, params, r, req, user)
+}
+
+// UnscheduleWindow removes a scheduled maintenance/downtime window
+func (this *HttpAPI) UnscheduleWindow(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	windowId, err := strconv.ParseUint(params["windowId"], 10, 0)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	err = inst.DeleteScheduledMaintenanceWindow(uint(windowId))
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Unscheduled window %d", windowId)})
+}
+
+// ScheduledWindows lists pending & active scheduled maintenance/downtime windows, optionally by cluster
+func (this *HttpAPI) ScheduledWindows(params martini.Params, r render.Render, req *http.Request) {
+	windows, err := inst.ReadScheduledMaintenanceWindows(params["clusterName"])
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, windows)
+}
+
+// getBulkSelector reads the instance selector of a bulk operation off the query params. A cluster may be given
+// either by name or by alias, not both. The same selector is used for authorization and for execution.
+func getBulkSelector(req *http.Request) (*inst.InstanceSelector, error) {
+	query := req.URL.Query()
+	selector := &inst.InstanceSelector{
+		ClusterName: query.Get("cluster"),
+		Pattern:     query.Get("pattern"),
+		Pool:        query.Get("pool"),
+		Tag:         query.Get("tag"),
+	}
+	if clusterAlias := query.Get("alias"); clusterAlias != "" {
+		if selector.ClusterName != "" {
+			return nil, fmt.Errorf("Expecting either cluster or alias, not both")
+		}
+		clusterName, err := inst.ReadClusterByAlias(clusterAlias)
+		if err != nil {
+			return nil, err
+		}
+		selector.ClusterName = clusterName
+	}
+	return selector, nil
+}
+
+// BulkOperation applies an operation on all instances matching a selector, given via query params:
+// cluster, alias, pattern, pool and/or tag. Optional params: concurrency, owner, reason, duration.
+// Per-instance results are listed in the response details.
+func (this *HttpAPI) BulkOperation(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	operation := params["operation"]
+	if !inst.IsBulkOperation(operation) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Unsupported bulk operation: %s", operation)})
+		return
+	}
+	selector, err := getBulkSelector(req)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	query := req.URL.Query()
+	operationParams := &inst.BulkOperationParams{Owner: query.Get("owner"), Reason: query.Get("reason")}
+	if operationParams.Owner == "" {
+		operationParams.Owner = getUserId(req, user)
+	}
+	if operation == inst.BulkBeginDowntime || operation == inst.BulkBeginMaintenance {
+		if operationParams.Reason == "" {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: "reason required"})
+			return
+		}
+		if query.Get("duration") != "" {
+			durationSeconds, err := util.SimpleTimeToSeconds(query.Get("duration"))
+			if err != nil || durationSeconds < 0 {
+				r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Invalid duration: %s", query.Get("duration"))})
+				return
+			}
+			operationParams.DurationSeconds = uint(durationSeconds)
+		}
+	}
+	concurrency := 0
+	if query.Get("concurrency") != "" {
+		var err error
+		if concurrency, err = strconv.Atoi(query.Get("concurrency")); err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+			return
+		}
+	}
+
+	results, err := inst.ExecuteBulkOperation(selector, operation, operationParams, concurrency)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	countFailures := 0
+	for _, result := range results {
+		if !result.Success {
+			countFailures++
+		}
+	}
+	if countFailures > 0 {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Bulk %s failed on %d out of %d instances", operation, countFailures, len(results)), Details: results})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Bulk %s applied on %d instances", operation, len(results)), Details: results})
+}
+
 // Cluster provides list of instances in given cluster
 func (this *HttpAPI) Cluster(params martini.Params, r render.Render, req *http.Request) {
 	instances, err := inst.ReadClusterInstances(params["clusterName"])
+	if err == nil {
+		instances, err = inst.FilterInstancesByTagSelector(instances, req.URL.Query().Get("tag"))
+	}
 
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
@@ -858,6 +1238,9 @@ func (this *HttpAPI) ClusterInfo(params martini.Params, r render.Render, req *ht
 // ClusterOSCSlaves returns heuristic list of OSC slaves
 func (this *HttpAPI) ClusterOSCSlaves(params martini.Params, r render.Render, req *http.Request) {
 	instances, err := inst.GetClusterOSCSlaves(params["clusterName"])
+	if err == nil {
+		instances, err = inst.FilterInstancesByTagSelector(instances, req.URL.Query().Get("tag"))
+	}
 
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
@@ -867,6 +1250,18 @@ func (this *HttpAPI) ClusterOSCSlaves(params martini.Params, r render.Render, re
 	r.JSON(200, instances)
 }
 
+// ClusterReplicationFiltersDivergence lists slaves in a cluster whose replication filters diverge from their siblings'
+func (this *HttpAPI) ClusterReplicationFiltersDivergence(params martini.Params, r render.Render, req *http.Request) {
+	divergences, err := inst.GetClusterReplicationFiltersDivergence(params["clusterName"])
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, divergences)
+}
+
 // SetClusterAlias will change an alias for a given clustername
 func (this *HttpAPI) SetClusterAlias(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	if !isAuthorizedForAction(req, user) {
@@ -915,6 +1310,9 @@ func (this *HttpAPI) Search(params martini.Params, r render.Render, req *http.Re
 		searchString = req.URL.Query().Get("s")
 	}
 	instances, err := inst.SearchInstances(searchString)
+	if err == nil {
+		instances, err = inst.FilterInstancesByTagSelector(instances, req.URL.Query().Get("tag"))
+	}
 
 	if err != nil {
 		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
@@ -965,6 +1363,156 @@ func (this *HttpAPI) LongQueries(params martini.Params, r render.Render, req *ht
 	r.JSON(200, longQueries)
 }
 
+// QueryKillPolicies lists query kill policies, optionally those applying to a given cluster
+func (this *HttpAPI) QueryKillPolicies(params martini.Params, r render.Render, req *http.Request) {
+	policies, err := inst.ReadQueryKillPolicies(params["clusterName"])
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, policies)
+}
+
+// CreateQueryKillPolicy creates a query kill policy on a given cluster (or on all clusters when not given).
+// Policy is described by query params: user, db, command (regular expressions), maxTime (seconds),
+// applyTo (all|master|slaves; default all) and dryRun (true|false; default false)
+func (this *HttpAPI) CreateQueryKillPolicy(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	maxTimeSeconds, err := strconv.ParseInt(req.URL.Query().Get("maxTime"), 10, 64)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Cannot parse maxTime: %+v", err)})
+		return
+	}
+	applyTo := req.URL.Query().Get("applyTo")
+	if applyTo == "" {
+		applyTo = inst.QueryKillApplyToAll
+	}
+	policy := inst.QueryKillPolicy{
+		ClusterName:    params["clusterName"],
+		UserPattern:    req.URL.Query().Get("user"),
+		DbPattern:      req.URL.Query().Get("db"),
+		CommandPattern: req.URL.Query().Get("command"),
+		MaxTimeSeconds: maxTimeSeconds,
+		ApplyTo:        applyTo,
+		DryRun:         (req.URL.Query().Get("dryRun") == "true"),
+		CreatedBy:      getUserId(req, user),
+	}
+	policyId, err := inst.CreateQueryKillPolicy(&policy)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Created query kill policy %d", policyId), Details: policyId})
+}
+
+// DeleteQueryKillPolicy removes a query kill policy
+func (this *HttpAPI) DeleteQueryKillPolicy(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	policyId, err := strconv.ParseInt(params["policyId"], 10, 64)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	if err := inst.DeleteQueryKillPolicy(policyId, getUserId(req, user)); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Deleted query kill policy %d", policyId)})
+}
+
+// QueryKillHistory lists queries killed by policies, optionally on a given cluster; paginated by "page" query param
+func (this *HttpAPI) QueryKillHistory(params martini.Params, r render.Render, req *http.Request) {
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+	kills, err := inst.ReadQueryKillHistory(params["clusterName"], page)
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, kills)
+}
+
+// FailureDetectionSnapshots lists snapshots captured upon failure detection, optionally on a given cluster;
+// paginated by "page" query param. Processlist and InnoDB status are only returned by FailureDetectionSnapshot
+func (this *HttpAPI) FailureDetectionSnapshots(params martini.Params, r render.Render, req *http.Request) {
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+	var snapshots []inst.FailureDetectionSnapshot
+	if clusterName := params["clusterName"]; clusterName != "" {
+		snapshots, err = inst.ReadClusterFailureDetectionSnapshots(clusterName, page)
+	} else {
+		snapshots, err = inst.ReadFailureDetectionSnapshots(page)
+	}
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, snapshots)
+}
+
+// InstanceChanges lists the change log (master, read_only, version, binlog_format etc. transitions) of a given
+// instance, or of all instances in a given cluster; optionally filtered by "attribute" and paginated by "page" query params
+func (this *HttpAPI) InstanceChanges(params martini.Params, r render.Render, req *http.Request) {
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+	attributeName := req.URL.Query().Get("attribute")
+	var changes []inst.InstanceChange
+	if clusterName := params["clusterName"]; clusterName != "" {
+		changes, err = inst.ReadClusterInstanceChanges(clusterName, attributeName, page)
+	} else {
+		var instanceKey inst.InstanceKey
+		if instanceKey, err = this.getInstanceKey(params["host"], params["port"]); err != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+			return
+		}
+		changes, err = inst.ReadInstanceChanges(&instanceKey, attributeName, page)
+	}
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, changes)
+}
+
+// FailureDetectionSnapshot returns a single failure detection snapshot
+func (this *HttpAPI) FailureDetectionSnapshot(params martini.Params, r render.Render, req *http.Request) {
+	snapshotId, err := strconv.ParseInt(params["snapshotId"], 10, 64)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	snapshot, err := inst.ReadFailureDetectionSnapshot(snapshotId)
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, snapshot)
+}
+
 // HostnameResolveCache shows content of in-memory hostname cache
 func (this *HttpAPI) HostnameResolveCache(params martini.Params, r render.Render, req *http.Request) {
 	content, err := inst.HostnameResolveCache()
@@ -1381,7 +1929,10 @@ func (this *HttpAPI) Health(params martini.Params, r render.Render, req *http.Re
 }
 
 // LBCheck returns a constant respnse, and this can be used by load balancers that expect a given string.
-func (this *HttpAPI) LBCheck(params martini.Params, r render.Render, req *http.Request) {
+// The X-Orchestrator-Leader response header indicates whether this node is the elected active node.
+func (this *HttpAPI) LBCheck(params martini.Params, r render.Render, req *http.Request, res http.ResponseWriter) {
+	isElected, _ := orchestrator.IsElected()
+	res.Header().Set("X-Orchestrator-Leader", strconv.FormatBool(isElected))
 	r.JSON(200, "OK")
 }
 
@@ -1452,6 +2003,125 @@ func (this *HttpAPI) Recover(params martini.Params, r render.Render, req *http.R
 	}
 }
 
+// AcknowledgeRecovery acknowledges recoveries: a specific recovery by id, all recoveries on a cluster (by name
+// or alias), or all recoveries on a failed instance. Comment is given via "comment" query parameter.
+func (this *HttpAPI) AcknowledgeRecovery(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	comment := req.URL.Query().Get("comment")
+	if comment == "" {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "No acknowledge comment given"})
+		return
+	}
+	userId := getUserId(req, user)
+	if userId == "" {
+		userId = inst.GetMaintenanceOwner()
+	}
+
+	var countAcknowledged int64
+	var err error
+	if params["recoveryId"] != "" {
+		recoveryId, perr := strconv.ParseInt(params["recoveryId"], 10, 0)
+		if perr != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: perr.Error()})
+			return
+		}
+		countAcknowledged, err = orchestrator.AcknowledgeRecovery(recoveryId, userId, comment)
+	} else if params["clusterName"] != "" {
+		countAcknowledged, err = orchestrator.AcknowledgeClusterRecoveries(params["clusterName"], userId, comment)
+	} else if params["clusterAlias"] != "" {
+		clusterName, aerr := inst.ReadClusterByAlias(params["clusterAlias"])
+		if aerr != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: aerr.Error()})
+			return
+		}
+		countAcknowledged, err = orchestrator.AcknowledgeClusterRecoveries(clusterName, userId, comment)
+	} else {
+		instanceKey, kerr := this.getInstanceKey(params["host"], params["port"])
+		if kerr != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: kerr.Error()})
+			return
+		}
+		countAcknowledged, err = orchestrator.AcknowledgeInstanceRecoveries(&instanceKey, userId, comment)
+	}
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Acknowledged %d recoveries", countAcknowledged), Details: countAcknowledged})
+}
+
+// UnacknowledgedRecoveries returns recoveries which have not been acknowledged
+func (this *HttpAPI) UnacknowledgedRecoveries(params martini.Params, r render.Render, req *http.Request) {
+	recoveries, err := orchestrator.ReadUnacknowledgedRecoveries()
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, recoveries)
+}
+
+// RecoveryBlocks returns active (non-acknowledged) recovery blocks
+func (this *HttpAPI) RecoveryBlocks(params martini.Params, r render.Render, req *http.Request) {
+	recoveryBlocks, err := orchestrator.ReadActiveRecoveryBlocks()
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, recoveryBlocks)
+}
+
+// AcknowledgeRecoveryBlocks acknowledges, and thereby lifts, recovery blocks. These are either
+// identified by block id, by cluster name, or else the global block is acknowledged.
+func (this *HttpAPI) AcknowledgeRecoveryBlocks(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	owner := getUserId(req, user)
+	if owner == "" {
+		owner = inst.GetMaintenanceOwner()
+	}
+
+	var countAcknowledged int64
+	var err error
+	if params["blockId"] != "" {
+		blockId, perr := strconv.ParseInt(params["blockId"], 10, 0)
+		if perr != nil {
+			r.JSON(200, &APIResponse{Code: ERROR, Message: perr.Error()})
+			return
+		}
+		countAcknowledged, err = orchestrator.AcknowledgeRecoveryBlock(blockId, owner)
+	} else {
+		countAcknowledged, err = orchestrator.AcknowledgeRecoveryBlocks(params["clusterName"], owner)
+	}
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Acknowledged %d recovery blocks", countAcknowledged), Details: countAcknowledged})
+}
+
+// BlockedRecoveries returns recent recovery attempts which were blocked, optionally limited to a given cluster
+func (this *HttpAPI) BlockedRecoveries(params martini.Params, r render.Render, req *http.Request) {
+	blockedRecoveries, err := orchestrator.ReadBlockedRecoveries(params["clusterName"])
+
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%+v", err)})
+		return
+	}
+
+	r.JSON(200, blockedRecoveries)
+}
+
 // AutomatedRecoveryFilters retuens list of clusters which are configured with automated recovery
 func (this *HttpAPI) AutomatedRecoveryFilters(params martini.Params, r render.Render, req *http.Request) {
 	automatedRecoveryMap := make(map[string]interface{})
@@ -1478,89 +2148,223 @@ func (this *HttpAPI) AuditRecovery(params martini.Params, r render.Render, req *
 	r.JSON(200, audits)
 }
 
+// RoleBindings lists role bindings, both configured and stored in the backend database
+func (this *HttpAPI) RoleBindings(params martini.Params, r render.Render, req *http.Request) {
+	roleBindings, _ := getRoleBindings()
+	r.JSON(200, roleBindings)
+}
+
+// roleBindingFromRequest builds a role binding by user & role params, and the optional "clusterPattern" query param
+func roleBindingFromRequest(params martini.Params, req *http.Request) (*config.RoleBinding, error) {
+	roleBinding := &config.RoleBinding{User: params["user"], Role: params["role"], ClusterPattern: req.URL.Query().Get("clusterPattern")}
+	if !IsValidRole(roleBinding.Role) {
+		return roleBinding, fmt.Errorf("Unknown role: %s. Expected %s, %s or %s", roleBinding.Role, RoleViewer, RoleOperator, RoleAdmin)
+	}
+	if _, err := regexp.Compile(roleBinding.ClusterPattern); err != nil {
+		return roleBinding, err
+	}
+	return roleBinding, nil
+}
+
+// GrantRole grants a role to a user, optionally scoped by cluster pattern
+func (this *HttpAPI) GrantRole(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	roleBinding, err := roleBindingFromRequest(params, req)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	if err := inst.GrantRole(roleBinding, getUserId(req, user)); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	roleBindingsCache.Flush()
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Granted %s to %s on '%s'", roleBinding.Role, roleBinding.User, roleBinding.ClusterPattern), Details: roleBinding})
+}
+
+// RevokeRole removes a role binding stored in the backend database
+func (this *HttpAPI) RevokeRole(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	if !isAuthorizedForAction(req, user) {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Unauthorized"})
+		return
+	}
+	roleBinding, err := roleBindingFromRequest(params, req)
+	if err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	if err := inst.RevokeRole(roleBinding, getUserId(req, user)); err != nil {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+		return
+	}
+	roleBindingsCache.Flush()
+
+	r.JSON(200, &APIResponse{Code: OK, Message: fmt.Sprintf("Revoked %s from %s on '%s'", roleBinding.Role, roleBinding.User, roleBinding.ClusterPattern), Details: roleBinding})
+}
+
+// registerRequest registers an API route, preceded by role based authorization of the route's action.
+// State changing routes are served via POST (and DELETE, for removals), with arguments optionally given
+// as JSON body; their GET variant is deprecated and only served when AllowMutatingGetRequests is set.
+func (this *HttpAPI) registerRequest(m *martini.ClassicMartini, route string, handler martini.Handler) {
+	action := routeAction(route)
+	authorize := authorizeAPIRequest(route)
+	if !isStateChangingAction(action) {
+		m.Get(route, authorize, handler)
+		return
+	}
+	if config.Config.AllowMutatingGetRequests {
+		m.Get(route, warnDeprecatedGetRequest, authorize, handler)
+	} else {
+		m.Get(route, rejectMutatingGetRequest)
+	}
+	this.registerPostRequest(m, route, handler)
+}
+
+// registerPostRequest registers a state changing API route served via POST (and DELETE, for removals) only
+func (this *HttpAPI) registerPostRequest(m *martini.ClassicMartini, route string, handler martini.Handler) {
+	authorize := authorizeAPIRequest(route)
+	m.Post(route, verifyCSRFRequest, readJSONBodyParams, authorize, handler)
+	if deleteActions[routeAction(route)] {
+		m.Delete(route, verifyCSRFRequest, readJSONBodyParams, authorize, handler)
+	}
+}
+
 // RegisterRequests makes for the de-facto list of known API calls
 func (this *HttpAPI) RegisterRequests(m *martini.ClassicMartini) {
-	m.Get("/api/instance/:host/:port", this.Instance)
-	m.Get("/api/discover/:host/:port", this.Discover)
-	m.Get("/api/refresh/:host/:port", this.Refresh)
-	m.Get("/api/forget/:host/:port", this.Forget)
-	m.Get("/api/resolve/:host/:port", this.Resolve)
-	m.Get("/api/move-up/:host/:port", this.MoveUp)
-	m.Get("/api/move-up-slaves/:host/:port", this.MoveUpSlaves)
-	m.Get("/api/make-co-master/:host/:port", this.MakeCoMaster)
-	m.Get("/api/reset-slave/:host/:port", this.ResetSlave)
-	m.Get("/api/detach-slave/:host/:port", this.DetachSlave)
-	m.Get("/api/reattach-slave/:host/:port", this.ReattachSlave)
-	m.Get("/api/move-below/:host/:port/:siblingHost/:siblingPort", this.MoveBelow)
-	m.Get("/api/enslave-siblings/:host/:port", this.EnslaveSiblings)
-	m.Get("/api/enslave-master/:host/:port", this.EnslaveMaster)
-	m.Get("/api/last-pseudo-gtid/:host/:port", this.LastPseudoGTID)
-	m.Get("/api/match-below/:host/:port/:belowHost/:belowPort", this.MatchBelow)
-	m.Get("/api/match-up/:host/:port", this.MatchUp)
-	m.Get("/api/multi-match-slaves/:host/:port/:belowHost/:belowPort", this.MultiMatchSlaves)
-	m.Get("/api/match-up-slaves/:host/:port", this.MatchUpSlaves)
-	m.Get("/api/regroup-slaves/:host/:port", this.RegroupSlaves)
-	m.Get("/api/make-master/:host/:port", this.MakeMaster)
-	m.Get("/api/make-local-master/:host/:port", this.MakeLocalMaster)
-	m.Get("/api/begin-maintenance/:host/:port/:owner/:reason", this.BeginMaintenance)
-	m.Get("/api/end-maintenance/:host/:port", this.EndMaintenanceByInstanceKey)
-	m.Get("/api/end-maintenance/:maintenanceKey", this.EndMaintenance)
-	m.Get("/api/begin-downtime/:host/:port/:owner/:reason", this.BeginDowntime)
-	m.Get("/api/end-downtime/:host/:port", this.EndDowntime)
-	m.Get("/api/skip-query/:host/:port", this.SkipQuery)
-	m.Get("/api/start-slave/:host/:port", this.StartSlave)
-	m.Get("/api/stop-slave/:host/:port", this.StopSlave)
-	m.Get("/api/stop-slave-nice/:host/:port", this.StopSlaveNicely)
-	m.Get("/api/set-read-only/:host/:port", this.SetReadOnly)
-	m.Get("/api/set-writeable/:host/:port", this.SetWriteable)
-	m.Get("/api/kill-query/:host/:port/:process", this.KillQuery)
-	m.Get("/api/maintenance", this.Maintenance)
-	m.Get("/api/cluster/:clusterName", this.Cluster)
-	m.Get("/api/cluster/alias/:clusterAlias", this.ClusterByAlias)
-	m.Get("/api/cluster-info/:clusterName", this.ClusterInfo)
-	m.Get("/api/cluster-osc-slaves/:clusterName", this.ClusterOSCSlaves)
-	m.Get("/api/set-cluster-alias/:clusterName", this.SetClusterAlias)
-	m.Get("/api/clusters", this.Clusters)
-	m.Get("/api/clusters-info", this.ClustersInfo)
-	m.Get("/api/search/:searchString", this.Search)
-	m.Get("/api/search", this.Search)
-	m.Get("/api/problems", this.Problems)
-	m.Get("/api/long-queries", this.LongQueries)
-	m.Get("/api/long-queries/:filter", this.LongQueries)
-	m.Get("/api/audit", this.Audit)
-	m.Get("/api/audit/:page", this.Audit)
+	this.registerRequest(m, "/api/instance/:host/:port", this.Instance)
+	this.registerRequest(m, "/api/discover/:host/:port", this.Discover)
+	this.registerRequest(m, "/api/refresh/:host/:port", this.Refresh)
+	this.registerRequest(m, "/api/forget/:host/:port", this.Forget)
+	this.registerRequest(m, "/api/resolve/:host/:port", this.Resolve)
+	this.registerRequest(m, "/api/move-up/:host/:port", this.MoveUp)
+	this.registerRequest(m, "/api/move-up-slaves/:host/:port", this.MoveUpSlaves)
+	this.registerRequest(m, "/api/make-co-master/:host/:port", this.MakeCoMaster)
+	this.registerRequest(m, "/api/reset-slave/:host/:port", this.ResetSlave)
+	this.registerRequest(m, "/api/detach-slave/:host/:port", this.DetachSlave)
+	this.registerRequest(m, "/api/reattach-slave/:host/:port", this.ReattachSlave)
+	this.registerRequest(m, "/api/move-below/:host/:port/:siblingHost/:siblingPort", this.MoveBelow)
+	this.registerRequest(m, "/api/repoint/:host/:port", this.Repoint)
+	this.registerRequest(m, "/api/repoint/:host/:port/:belowHost/:belowPort", this.Repoint)
+	this.registerRequest(m, "/api/enslave-siblings/:host/:port", this.EnslaveSiblings)
+	this.registerRequest(m, "/api/enslave-master/:host/:port", this.EnslaveMaster)
+	this.registerRequest(m, "/api/last-pseudo-gtid/:host/:port", this.LastPseudoGTID)
+	this.registerRequest(m, "/api/match-below/:host/:port/:belowHost/:belowPort", this.MatchBelow)
+	this.registerRequest(m, "/api/match-up/:host/:port", this.MatchUp)
+	this.registerRequest(m, "/api/multi-match-slaves/:host/:port/:belowHost/:belowPort", this.MultiMatchSlaves)
+	this.registerRequest(m, "/api/match-up-slaves/:host/:port", this.MatchUpSlaves)
+	this.registerRequest(m, "/api/regroup-slaves/:host/:port", this.RegroupSlaves)
+	this.registerRequest(m, "/api/make-master/:host/:port", this.MakeMaster)
+	this.registerRequest(m, "/api/make-local-master/:host/:port", this.MakeLocalMaster)
+	this.registerRequest(m, "/api/begin-maintenance/:host/:port/:owner/:reason", this.BeginMaintenance)
+	this.registerPostRequest(m, "/api/begin-maintenance/:host/:port", this.BeginMaintenance)
+	this.registerRequest(m, "/api/end-maintenance/:host/:port", this.EndMaintenanceByInstanceKey)
+	this.registerRequest(m, "/api/end-maintenance/:maintenanceKey", this.EndMaintenance)
+	this.registerRequest(m, "/api/begin-downtime/:host/:port/:owner/:reason", this.BeginDowntime)
+	this.registerPostRequest(m, "/api/begin-downtime/:host/:port", this.BeginDowntime)
+	this.registerRequest(m, "/api/end-downtime/:host/:port", this.EndDowntime)
+	this.registerRequest(m, "/api/register-promotion-rule/:host/:port/:promotionRule", this.RegisterPromotionRule)
+	this.registerRequest(m, "/api/register-promotion-rule/:host/:port/:promotionRule/:duration", this.RegisterPromotionRule)
+	this.registerRequest(m, "/api/unregister-promotion-rule/:host/:port", this.UnregisterPromotionRule)
+	this.registerRequest(m, "/api/promotion-rules", this.PromotionRules)
+	this.registerRequest(m, "/api/promotion-rules/:clusterName", this.PromotionRules)
+	this.registerRequest(m, "/api/skip-query/:host/:port", this.SkipQuery)
+	this.registerRequest(m, "/api/start-slave/:host/:port", this.StartSlave)
+	this.registerRequest(m, "/api/stop-slave/:host/:port", this.StopSlave)
+	this.registerRequest(m, "/api/stop-slave-nice/:host/:port", this.StopSlaveNicely)
+	this.registerRequest(m, "/api/set-read-only/:host/:port", this.SetReadOnly)
+	this.registerRequest(m, "/api/set-writeable/:host/:port", this.SetWriteable)
+	this.registerRequest(m, "/api/kill-query/:host/:port/:process", this.KillQuery)
+	this.registerRequest(m, "/api/tag/:host/:port", this.TagInstance)
+	this.registerRequest(m, "/api/untag/:host/:port", this.UntagInstance)
+	this.registerRequest(m, "/api/tags/:host/:port", this.InstanceTags)
+	this.registerRequest(m, "/api/tagged", this.TaggedInstances)
+	this.registerRequest(m, "/api/bulk/:operation", this.BulkOperation)
+	this.registerRequest(m, "/api/role-bindings", this.RoleBindings)
+	this.registerRequest(m, "/api/grant-role/:user/:role", this.GrantRole)
+	this.registerRequest(m, "/api/revoke-role/:user/:role", this.RevokeRole)
+	this.registerRequest(m, "/api/schedule-maintenance/cluster/:clusterName", this.ScheduleMaintenance)
+	this.registerRequest(m, "/api/schedule-maintenance/:host/:port", this.ScheduleMaintenance)
+	this.registerRequest(m, "/api/schedule-downtime/cluster/:clusterName", this.ScheduleDowntime)
+	this.registerRequest(m, "/api/schedule-downtime/:host/:port", this.ScheduleDowntime)
+	this.registerRequest(m, "/api/unschedule-window/:windowId", this.UnscheduleWindow)
+	this.registerRequest(m, "/api/scheduled-windows", this.ScheduledWindows)
+	this.registerRequest(m, "/api/scheduled-windows/:clusterName", this.ScheduledWindows)
+	this.registerRequest(m, "/api/maintenance", this.Maintenance)
+	this.registerRequest(m, "/api/cluster/:clusterName", this.Cluster)
+	this.registerRequest(m, "/api/cluster/alias/:clusterAlias", this.ClusterByAlias)
+	this.registerRequest(m, "/api/cluster-info/:clusterName", this.ClusterInfo)
+	this.registerRequest(m, "/api/cluster-osc-slaves/:clusterName", this.ClusterOSCSlaves)
+	this.registerRequest(m, "/api/cluster-replication-filters-divergence/:clusterName", this.ClusterReplicationFiltersDivergence)
+	this.registerRequest(m, "/api/set-cluster-alias/:clusterName", this.SetClusterAlias)
+	this.registerRequest(m, "/api/clusters", this.Clusters)
+	this.registerRequest(m, "/api/clusters-info", this.ClustersInfo)
+	this.registerRequest(m, "/api/search/:searchString", this.Search)
+	this.registerRequest(m, "/api/search", this.Search)
+	this.registerRequest(m, "/api/problems", this.Problems)
+	this.registerRequest(m, "/api/long-queries", this.LongQueries)
+	this.registerRequest(m, "/api/long-queries/:filter", this.LongQueries)
+	this.registerRequest(m, "/api/query-kill-policies", this.QueryKillPolicies)
+	this.registerRequest(m, "/api/query-kill-policies/:clusterName", this.QueryKillPolicies)
+	this.registerRequest(m, "/api/create-query-kill-policy", this.CreateQueryKillPolicy)
+	this.registerRequest(m, "/api/create-query-kill-policy/:clusterName", this.CreateQueryKillPolicy)
+	this.registerRequest(m, "/api/delete-query-kill-policy/:policyId", this.DeleteQueryKillPolicy)
+	this.registerRequest(m, "/api/query-kill-history", this.QueryKillHistory)
+	this.registerRequest(m, "/api/query-kill-history/:clusterName", this.QueryKillHistory)
+	this.registerRequest(m, "/api/failure-detection-snapshots", this.FailureDetectionSnapshots)
+	this.registerRequest(m, "/api/failure-detection-snapshots/cluster/:clusterName", this.FailureDetectionSnapshots)
+	this.registerRequest(m, "/api/failure-detection-snapshot/:snapshotId", this.FailureDetectionSnapshot)
+	this.registerRequest(m, "/api/instance-changes/cluster/:clusterName", this.InstanceChanges)
+	this.registerRequest(m, "/api/instance-changes/:host/:port", this.InstanceChanges)
+	this.registerRequest(m, "/api/audit", this.Audit)
+	this.registerRequest(m, "/api/audit/:page", this.Audit)
+	this.registerRequest(m, eventsRoute, this.Events)
 	// General
-	m.Get("/api/headers", this.Headers)
-	m.Get("/api/health", this.Health)
-	m.Get("/api/lb-check", this.LBCheck)
-	m.Get("/api/grab-election", this.GrabElection)
-	m.Get("/api/reload-configuration", this.ReloadConfiguration)
-	m.Get("/api/reload-cluster-alias", this.ReloadClusterAlias)
-	m.Get("/api/hostname-resolve-cache", this.HostnameResolveCache)
-	m.Get("/api/reset-hostname-resolve-cache", this.ResetHostnameResolveCache)
-	m.Get("/api/submit-pool-instances/:pool", this.SubmitPoolInstances)
-	m.Get("/api/cluster-pool-instances/:clusterName", this.ReadClusterPoolInstances)
+	this.registerRequest(m, "/api/headers", this.Headers)
+	this.registerRequest(m, "/api/health", this.Health)
+	this.registerRequest(m, "/api/lb-check", this.LBCheck)
+	this.registerRequest(m, "/api/grab-election", this.GrabElection)
+	this.registerRequest(m, "/api/reload-configuration", this.ReloadConfiguration)
+	this.registerRequest(m, "/api/reload-cluster-alias", this.ReloadClusterAlias)
+	this.registerRequest(m, "/api/hostname-resolve-cache", this.HostnameResolveCache)
+	this.registerRequest(m, "/api/reset-hostname-resolve-cache", this.ResetHostnameResolveCache)
+	this.registerRequest(m, "/api/submit-pool-instances/:pool", this.SubmitPoolInstances)
+	this.registerRequest(m, "/api/cluster-pool-instances/:clusterName", this.ReadClusterPoolInstances)
 	// Recovery
-	m.Get("/api/replication-analysis", this.ReplicationAnalysis)
-	m.Get("/api/recover/:host/:port", this.Recover)
-	m.Get("/api/recover/:host/:port/:candidateHost/:candidatePort", this.Recover)
-	m.Get("/api/automated-recovery-filters", this.AutomatedRecoveryFilters)
-	m.Get("/api/audit-recovery", this.AuditRecovery)
-	m.Get("/api/audit-recovery/:page", this.AuditRecovery)
+	this.registerRequest(m, "/api/replication-analysis", this.ReplicationAnalysis)
+	this.registerRequest(m, "/api/recover/:host/:port", this.Recover)
+	this.registerRequest(m, "/api/recover/:host/:port/:candidateHost/:candidatePort", this.Recover)
+	this.registerRequest(m, "/api/automated-recovery-filters", this.AutomatedRecoveryFilters)
+	this.registerRequest(m, "/api/ack-recovery/cluster/:clusterName", this.AcknowledgeRecovery)
+	this.registerRequest(m, "/api/ack-recovery/cluster/alias/:clusterAlias", this.AcknowledgeRecovery)
+	this.registerRequest(m, "/api/ack-recovery/instance/:host/:port", this.AcknowledgeRecovery)
+	this.registerRequest(m, "/api/ack-recovery/:recoveryId", this.AcknowledgeRecovery)
+	this.registerRequest(m, "/api/unacknowledged-recoveries", this.UnacknowledgedRecoveries)
+	this.registerRequest(m, "/api/recovery-blocks", this.RecoveryBlocks)
+	this.registerRequest(m, "/api/ack-recovery-blocks", this.AcknowledgeRecoveryBlocks)
+	this.registerRequest(m, "/api/ack-recovery-blocks/cluster/:clusterName", this.AcknowledgeRecoveryBlocks)
+	this.registerRequest(m, "/api/ack-recovery-block/:blockId", this.AcknowledgeRecoveryBlocks)
+	this.registerRequest(m, "/api/blocked-recoveries", this.BlockedRecoveries)
+	this.registerRequest(m, "/api/blocked-recoveries/cluster/:clusterName", this.BlockedRecoveries)
+	this.registerRequest(m, "/api/audit-recovery", this.AuditRecovery)
+	this.registerRequest(m, "/api/audit-recovery/:page", this.AuditRecovery)
 	// Agents
-	m.Get("/api/agents", this.Agents)
-	m.Get("/api/agent/:host", this.Agent)
-	m.Get("/api/agent-umount/:host", this.AgentUnmount)
-	m.Get("/api/agent-mount/:host", this.AgentMountLV)
-	m.Get("/api/agent-create-snapshot/:host", this.AgentCreateSnapshot)
-	m.Get("/api/agent-removelv/:host", this.AgentRemoveLV)
-	m.Get("/api/agent-mysql-stop/:host", this.AgentMySQLStop)
-	m.Get("/api/agent-mysql-start/:host", this.AgentMySQLStart)
-	m.Get("/api/agent-seed/:targetHost/:sourceHost", this.AgentSeed)
-	m.Get("/api/agent-active-seeds/:host", this.AgentActiveSeeds)
-	m.Get("/api/agent-recent-seeds/:host", this.AgentRecentSeeds)
-	m.Get("/api/agent-seed-details/:seedId", this.AgentSeedDetails)
-	m.Get("/api/agent-seed-states/:seedId", this.AgentSeedStates)
-	m.Get("/api/agent-abort-seed/:seedId", this.AbortSeed)
-	m.Get("/api/seeds", this.Seeds)
+	this.registerRequest(m, "/api/agents", this.Agents)
+	this.registerRequest(m, "/api/agent/:host", this.Agent)
+	this.registerRequest(m, "/api/agent-umount/:host", this.AgentUnmount)
+	this.registerRequest(m, "/api/agent-mount/:host", this.AgentMountLV)
+	this.registerRequest(m, "/api/agent-create-snapshot/:host", this.AgentCreateSnapshot)
+	this.registerRequest(m, "/api/agent-removelv/:host", this.AgentRemoveLV)
+	this.registerRequest(m, "/api/agent-mysql-stop/:host", this.AgentMySQLStop)
+	this.registerRequest(m, "/api/agent-mysql-start/:host", this.AgentMySQLStart)
+	this.registerRequest(m, "/api/agent-seed/:targetHost/:sourceHost", this.AgentSeed)
+	this.registerRequest(m, "/api/agent-active-seeds/:host", this.AgentActiveSeeds)
+	this.registerRequest(m, "/api/agent-recent-seeds/:host", this.AgentRecentSeeds)
+	this.registerRequest(m, "/api/agent-seed-details/:seedId", this.AgentSeedDetails)
+	this.registerRequest(m, "/api/agent-seed-states/:seedId", this.AgentSeedStates)
+	this.registerRequest(m, "/api/agent-abort-seed/:seedId", this.AbortSeed)
+	this.registerRequest(m, "/api/seeds", this.Seeds)
 }
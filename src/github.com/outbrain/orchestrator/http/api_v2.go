@@ -0,0 +1,626 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	"github.com/martini-contrib/render"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/util"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/outbrain/orchestrator/agent"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/inst"
+	"github.com/outbrain/orchestrator/logic"
+)
+
+// APIv2ErrorCode is a typed error code of the v2 API, which clients may act upon in place of parsing messages
+type APIv2ErrorCode string
+
+const (
+	APIv2BadRequest          APIv2ErrorCode = "bad-request"
+	APIv2Forbidden           APIv2ErrorCode = "forbidden"
+	APIv2NotFound            APIv2ErrorCode = "not-found"
+	APIv2MaintenanceConflict APIv2ErrorCode = "maintenance-conflict"
+	APIv2PreconditionFailed  APIv2ErrorCode = "precondition-failed"
+	APIv2InternalError       APIv2ErrorCode = "internal-error"
+)
+
+// apiv2ErrorStatus maps error codes onto HTTP status codes
+var apiv2ErrorStatus = map[APIv2ErrorCode]int{
+	APIv2BadRequest:          http.StatusBadRequest,
+	APIv2Forbidden:           http.StatusForbidden,
+	APIv2NotFound:            http.StatusNotFound,
+	APIv2MaintenanceConflict: http.StatusConflict,
+	APIv2PreconditionFailed:  http.StatusPreconditionFailed,
+	APIv2InternalError:       http.StatusInternalServerError,
+}
+
+// APIv2Error is the body of a failed v2 API request
+type APIv2Error struct {
+	Code    APIv2ErrorCode
+	Message string
+}
+
+// APIv2Page describes the page of a paginated v2 API response. Pages are numbered from 0.
+type APIv2Page struct {
+	Page    int
+	PerPage int
+	HasMore bool
+}
+
+// APIv2Response is the body of a successful v2 API request
+type APIv2Response struct {
+	Data interface{}
+	Page *APIv2Page `json:",omitempty"`
+}
+
+const (
+	apiv2MaxPerPage = 1000
+)
+
+// apiv2Route describes a v2 API route. The route table is the source for both request registration
+// and the OpenAPI document.
+type apiv2Route struct {
+	method      string
+	path        string
+	summary     string
+	action      string
+	paginated   bool
+	queryParams []string
+	bodyParams  []string
+	response    interface{}
+	errors      []APIv2ErrorCode
+	handler     martini.Handler
+}
+
+type HttpAPIv2 struct{}
+
+var APIv2 HttpAPIv2 = HttpAPIv2{}
+
+// renderError responds with given error code and the HTTP status it maps to
+func (this *HttpAPIv2) renderError(r render.Render, code APIv2ErrorCode, message string) {
+	r.JSON(apiv2ErrorStatus[code], &APIv2Error{Code: code, Message: message})
+}
+
+// renderInternalError responds with an internal error, or with a typed error where err is known to have one
+func (this *HttpAPIv2) renderInternalError(r render.Render, err error) {
+	switch err.(type) {
+	case *inst.MaintenanceConflictError:
+		this.renderError(r, APIv2MaintenanceConflict, err.Error())
+	case *inst.InactiveStateError:
+		this.renderError(r, APIv2PreconditionFailed, err.Error())
+	default:
+		this.renderError(r, APIv2InternalError, err.Error())
+	}
+}
+
+// renderData responds with given data
+func (this *HttpAPIv2) renderData(r render.Render, data interface{}) {
+	r.JSON(http.StatusOK, &APIv2Response{Data: data})
+}
+
+// renderPage responds with a page of given items. items is a slice which, in order to indicate more
+// pages follow, may hold one item beyond the page size.
+func (this *HttpAPIv2) renderPage(r render.Render, page *APIv2Page, items interface{}) {
+	value := reflect.ValueOf(items)
+	if value.Len() > page.PerPage {
+		page.HasMore = true
+		value = value.Slice(0, page.PerPage)
+	}
+	r.JSON(http.StatusOK, &APIv2Response{Data: value.Interface(), Page: page})
+}
+
+// getPage reads the "page" and "perPage" query params. perPage defaults to AuditPageSize.
+func (this *HttpAPIv2) getPage(req *http.Request) (*APIv2Page, error) {
+	page := &APIv2Page{PerPage: config.Config.AuditPageSize}
+	query := req.URL.Query()
+	if query.Get("page") != "" {
+		value, err := strconv.Atoi(query.Get("page"))
+		if err != nil || value < 0 {
+			return page, fmt.Errorf("Invalid page: %s", query.Get("page"))
+		}
+		page.Page = value
+	}
+	if query.Get("perPage") != "" {
+		value, err := strconv.Atoi(query.Get("perPage"))
+		if err != nil || value <= 0 || value > apiv2MaxPerPage {
+			return page, fmt.Errorf("Invalid perPage: %s (expecting 1..%d)", query.Get("perPage"), apiv2MaxPerPage)
+		}
+		page.PerPage = value
+	}
+	return page, nil
+}
+
+// offset returns the index of the first item on the page
+func (this *APIv2Page) offset() int {
+	return this.Page * this.PerPage
+}
+
+// slice returns the items of given slice on the page, plus the first item of the next page, if any
+func (this *APIv2Page) slice(items interface{}) interface{} {
+	value := reflect.ValueOf(items)
+	from := this.offset()
+	if from > value.Len() {
+		from = value.Len()
+	}
+	to := from + this.PerPage + 1
+	if to > value.Len() {
+		to = value.Len()
+	}
+	return value.Slice(from, to).Interface()
+}
+
+// readInstance reads the instance given by host/port route params, responding with an error if no such instance is known
+func (this *HttpAPIv2) readInstance(params martini.Params, r render.Render) (*inst.Instance, bool) {
+	instanceKey, err := inst.NewInstanceKeyFromStrings(params["host"], params["port"])
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return nil, false
+	}
+	instance, found, err := inst.ReadInstance(instanceKey)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return nil, false
+	}
+	if !found {
+		this.renderError(r, APIv2NotFound, fmt.Sprintf("Unknown instance: %+v", *instanceKey))
+		return nil, false
+	}
+	return instance, true
+}
+
+// Clusters lists known clusters along with some metadata per cluster
+func (this *HttpAPIv2) Clusters(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	clustersInfo, err := inst.ReadClustersInfo()
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderPage(r, page, page.slice(clustersInfo))
+}
+
+// Cluster returns metadata of a given cluster
+func (this *HttpAPIv2) Cluster(params martini.Params, r render.Render, req *http.Request) {
+	clusterInfo, err := inst.ReadClusterInfo(params["clusterName"])
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	if clusterInfo.ClusterName == "" {
+		this.renderError(r, APIv2NotFound, fmt.Sprintf("Unknown cluster: %s", params["clusterName"]))
+		return
+	}
+	this.renderData(r, clusterInfo)
+}
+
+// ClusterInstances lists the instances of a given cluster, optionally filtered by tag selector
+func (this *HttpAPIv2) ClusterInstances(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	instances, err := inst.ReadClusterInstances(params["clusterName"])
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	if len(instances) == 0 {
+		this.renderError(r, APIv2NotFound, fmt.Sprintf("Unknown cluster: %s", params["clusterName"]))
+		return
+	}
+	instances, err = inst.FilterInstancesByTagSelector(instances, req.URL.Query().Get("tag"))
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	this.renderPage(r, page, page.slice(instances))
+}
+
+// ClusterRecoveries lists latest recoveries of a given cluster
+func (this *HttpAPIv2) ClusterRecoveries(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	recoveries, err := orchestrator.ReadRecoveries(params["clusterName"], page.offset(), page.PerPage+1)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderPage(r, page, recoveries)
+}
+
+// Instances lists instances matching the "search" query param, optionally filtered by tag selector
+func (this *HttpAPIv2) Instances(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	query := req.URL.Query()
+	if query.Get("search") == "" {
+		this.renderError(r, APIv2BadRequest, "Expecting search query param")
+		return
+	}
+	instances, err := inst.SearchInstances(query.Get("search"))
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	instances, err = inst.FilterInstancesByTagSelector(instances, query.Get("tag"))
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	this.renderPage(r, page, page.slice(instances))
+}
+
+// Instance returns a given instance
+func (this *HttpAPIv2) Instance(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	this.renderData(r, instance)
+}
+
+// InstanceSlaves lists the direct slaves of a given instance
+func (this *HttpAPIv2) InstanceSlaves(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	slaves, err := inst.ReadSlaveInstances(&instance.Key)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderPage(r, page, page.slice(slaves))
+}
+
+// RefreshInstance synchronously re-reads a given instance from the topology
+func (this *HttpAPIv2) RefreshInstance(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	instance, err := inst.RefreshTopologyInstance(&instance.Key)
+	if err != nil {
+		this.renderError(r, APIv2PreconditionFailed, err.Error())
+		return
+	}
+	this.renderData(r, instance)
+}
+
+// ForgetInstance removes a given instance from the backend database
+func (this *HttpAPIv2) ForgetInstance(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	if err := inst.ForgetInstance(&instance.Key); err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderData(r, instance.Key)
+}
+
+// getDuration reads the optional "duration" param
+func (this *HttpAPIv2) getDuration(req *http.Request) (uint, error) {
+	duration := req.URL.Query().Get("duration")
+	if duration == "" {
+		return 0, nil
+	}
+	durationSeconds, err := util.SimpleTimeToSeconds(duration)
+	if err != nil || durationSeconds < 0 {
+		return 0, fmt.Errorf("Invalid duration: %s", duration)
+	}
+	return uint(durationSeconds), nil
+}
+
+// BeginMaintenance begins maintenance mode for a given instance. An instance already under maintenance
+// is a maintenance-conflict error.
+func (this *HttpAPIv2) BeginMaintenance(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	durationSeconds, err := this.getDuration(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	owner, reason := req.URL.Query().Get("owner"), req.URL.Query().Get("reason")
+	if owner == "" || reason == "" {
+		this.renderError(r, APIv2BadRequest, "Expecting owner and reason")
+		return
+	}
+	maintenanceToken, err := inst.BeginBoundedMaintenance(&instance.Key, owner, reason, durationSeconds)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderData(r, maintenanceToken)
+}
+
+// EndMaintenance ends maintenance mode of a given instance. An instance not under maintenance
+// is a precondition-failed error.
+func (this *HttpAPIv2) EndMaintenance(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	if err := inst.EndMaintenanceByInstanceKey(&instance.Key); err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderData(r, instance.Key)
+}
+
+// BeginDowntime sets downtime for a given instance, overriding any existing downtime
+func (this *HttpAPIv2) BeginDowntime(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	durationSeconds, err := this.getDuration(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	owner, reason := req.URL.Query().Get("owner"), req.URL.Query().Get("reason")
+	if owner == "" || reason == "" {
+		this.renderError(r, APIv2BadRequest, "Expecting owner and reason")
+		return
+	}
+	if err := inst.BeginDowntime(&instance.Key, owner, reason, durationSeconds); err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderData(r, instance.Key)
+}
+
+// EndDowntime ends downtime of a given instance. An instance not downtimed is a precondition-failed error.
+func (this *HttpAPIv2) EndDowntime(params martini.Params, r render.Render, req *http.Request) {
+	instance, ok := this.readInstance(params, r)
+	if !ok {
+		return
+	}
+	if err := inst.EndDowntime(&instance.Key); err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderData(r, instance.Key)
+}
+
+// Recoveries lists latest recoveries
+func (this *HttpAPIv2) Recoveries(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	recoveries, err := orchestrator.ReadRecoveries("", page.offset(), page.PerPage+1)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderPage(r, page, recoveries)
+}
+
+// Recovery returns a given recovery
+func (this *HttpAPIv2) Recovery(params martini.Params, r render.Render, req *http.Request) {
+	recoveryId, err := strconv.ParseInt(params["recoveryId"], 10, 0)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, fmt.Sprintf("Invalid recovery id: %s", params["recoveryId"]))
+		return
+	}
+	recoveries, err := orchestrator.ReadRecovery(recoveryId)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	if len(recoveries) == 0 {
+		this.renderError(r, APIv2NotFound, fmt.Sprintf("Unknown recovery: %d", recoveryId))
+		return
+	}
+	this.renderData(r, &recoveries[0])
+}
+
+// verifyAgentsServed responds with an error unless agents are served
+func (this *HttpAPIv2) verifyAgentsServed(r render.Render) {
+	if !config.Config.ServeAgentsHttp {
+		this.renderError(r, APIv2PreconditionFailed, "Agents not served")
+	}
+}
+
+// Seeds lists latest seeds
+func (this *HttpAPIv2) Seeds(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	seeds, err := agent.ReadSeeds(page.offset(), page.PerPage+1)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderPage(r, page, seeds)
+}
+
+// Seed returns a given seed
+func (this *HttpAPIv2) Seed(params martini.Params, r render.Render, req *http.Request) {
+	seedId, err := strconv.ParseInt(params["seedId"], 10, 0)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, fmt.Sprintf("Invalid seed id: %s", params["seedId"]))
+		return
+	}
+	seeds, err := agent.AgentSeedDetails(seedId)
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	if len(seeds) == 0 {
+		this.renderError(r, APIv2NotFound, fmt.Sprintf("Unknown seed: %d", seedId))
+		return
+	}
+	this.renderData(r, &seeds[0])
+}
+
+// Agents lists registered agents
+func (this *HttpAPIv2) Agents(params martini.Params, r render.Render, req *http.Request) {
+	page, err := this.getPage(req)
+	if err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+		return
+	}
+	agents, err := agent.ReadAgents()
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	this.renderPage(r, page, page.slice(agents))
+}
+
+// Agent returns complete information of a given agent
+func (this *HttpAPIv2) Agent(params martini.Params, r render.Render, req *http.Request) {
+	agents, err := agent.ReadAgents()
+	if err != nil {
+		this.renderInternalError(r, err)
+		return
+	}
+	for _, registeredAgent := range agents {
+		if registeredAgent.Hostname == params["host"] {
+			polledAgent, err := agent.GetAgent(params["host"])
+			if err != nil {
+				this.renderError(r, APIv2PreconditionFailed, err.Error())
+				return
+			}
+			this.renderData(r, &polledAgent)
+			return
+		}
+	}
+	this.renderError(r, APIv2NotFound, fmt.Sprintf("Unknown agent: %s", params["host"]))
+}
+
+// OpenAPI returns the OpenAPI document describing the v2 API
+func (this *HttpAPIv2) OpenAPI(params martini.Params, r render.Render, req *http.Request) {
+	r.JSON(http.StatusOK, generateOpenAPIDocument(this.routes()))
+}
+
+// routes returns the v2 API route table
+func (this *HttpAPIv2) routes() []apiv2Route {
+	instanceErrors := []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound}
+	tag := []string{"tag"}
+	return []apiv2Route{
+		{method: "GET", path: "/api/v2/clusters", summary: "List clusters", action: "clusters-info", paginated: true, response: inst.ClusterInfo{}, handler: this.Clusters},
+		{method: "GET", path: "/api/v2/clusters/:clusterName", summary: "Get cluster", action: "cluster-info", response: inst.ClusterInfo{}, errors: []APIv2ErrorCode{APIv2NotFound}, handler: this.Cluster},
+		{method: "GET", path: "/api/v2/clusters/:clusterName/instances", summary: "List cluster instances", action: "cluster", paginated: true, queryParams: tag, response: inst.Instance{}, errors: instanceErrors, handler: this.ClusterInstances},
+		{method: "GET", path: "/api/v2/clusters/:clusterName/recoveries", summary: "List cluster recoveries", action: "audit-recovery", paginated: true, response: orchestrator.TopologyRecovery{}, handler: this.ClusterRecoveries},
+		{method: "GET", path: "/api/v2/instances", summary: "Search instances", action: "search", paginated: true, queryParams: []string{"search", "tag"}, response: inst.Instance{}, errors: []APIv2ErrorCode{APIv2BadRequest}, handler: this.Instances},
+		{method: "GET", path: "/api/v2/instances/:host/:port", summary: "Get instance", action: "instance", response: inst.Instance{}, errors: instanceErrors, handler: this.Instance},
+		{method: "DELETE", path: "/api/v2/instances/:host/:port", summary: "Forget instance", action: "forget", response: inst.InstanceKey{}, errors: instanceErrors, handler: this.ForgetInstance},
+		{method: "GET", path: "/api/v2/instances/:host/:port/slaves", summary: "List instance slaves", action: "instance", paginated: true, response: inst.Instance{}, errors: instanceErrors, handler: this.InstanceSlaves},
+		{method: "POST", path: "/api/v2/instances/:host/:port/refresh", summary: "Refresh instance", action: "refresh", response: inst.Instance{}, errors: []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound, APIv2PreconditionFailed}, handler: this.RefreshInstance},
+		{method: "POST", path: "/api/v2/instances/:host/:port/maintenance", summary: "Begin maintenance", action: "begin-maintenance", bodyParams: []string{"owner", "reason", "duration"}, response: int64(0), errors: []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound, APIv2MaintenanceConflict}, handler: this.BeginMaintenance},
+		{method: "DELETE", path: "/api/v2/instances/:host/:port/maintenance", summary: "End maintenance", action: "end-maintenance", response: inst.InstanceKey{}, errors: []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound, APIv2PreconditionFailed}, handler: this.EndMaintenance},
+		{method: "POST", path: "/api/v2/instances/:host/:port/downtime", summary: "Begin downtime", action: "begin-downtime", bodyParams: []string{"owner", "reason", "duration"}, response: inst.InstanceKey{}, errors: instanceErrors, handler: this.BeginDowntime},
+		{method: "DELETE", path: "/api/v2/instances/:host/:port/downtime", summary: "End downtime", action: "end-downtime", response: inst.InstanceKey{}, errors: []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound, APIv2PreconditionFailed}, handler: this.EndDowntime},
+		{method: "GET", path: "/api/v2/recoveries", summary: "List recoveries", action: "audit-recovery", paginated: true, response: orchestrator.TopologyRecovery{}, handler: this.Recoveries},
+		{method: "GET", path: "/api/v2/recoveries/:recoveryId", summary: "Get recovery", action: "audit-recovery", response: orchestrator.TopologyRecovery{}, errors: []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound}, handler: this.Recovery},
+		{method: "GET", path: "/api/v2/seeds", summary: "List seeds", action: "seeds", paginated: true, response: agent.SeedOperation{}, errors: []APIv2ErrorCode{APIv2PreconditionFailed}, handler: this.Seeds},
+		{method: "GET", path: "/api/v2/seeds/:seedId", summary: "Get seed", action: "agent-seed-details", response: agent.SeedOperation{}, errors: []APIv2ErrorCode{APIv2BadRequest, APIv2NotFound, APIv2PreconditionFailed}, handler: this.Seed},
+		{method: "GET", path: "/api/v2/agents", summary: "List agents", action: "agents", paginated: true, response: agent.Agent{}, errors: []APIv2ErrorCode{APIv2PreconditionFailed}, handler: this.Agents},
+		{method: "GET", path: "/api/v2/agents/:host", summary: "Get agent", action: "agent", response: agent.Agent{}, errors: []APIv2ErrorCode{APIv2NotFound, APIv2PreconditionFailed}, handler: this.Agent},
+		{method: "GET", path: "/api/v2/openapi.json", summary: "Get OpenAPI document", action: "openapi", handler: this.OpenAPI},
+	}
+}
+
+// authorizeRequest returns a handler, preceding the actual handler of given route, which enforces the
+// role required by the route's action on the cluster the request applies to. Agents and seeds, as
+// in the v1 API, require an authorized user.
+func (this *HttpAPIv2) authorizeRequest(route apiv2Route) martini.Handler {
+	return func(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+		if route.action == "agents" || route.action == "agent" || route.action == "seeds" || route.action == "agent-seed-details" {
+			if !isAuthorizedForAction(req, user) {
+				this.renderError(r, APIv2Forbidden, "Unauthorized")
+				return
+			}
+			this.verifyAgentsServed(r)
+			return
+		}
+		role := requiredRole(route.action, params)
+		if role == RoleViewer {
+			return
+		}
+		clusterName := getRequestClusterName(params)
+		if !hasRole(req, user, role, clusterName) {
+			log.Debugf("Unauthorized: %s requires role %s on cluster '%s'; user: %s", route.action, role, clusterName, getUserId(req, user))
+			this.renderError(r, APIv2Forbidden, fmt.Sprintf("Unauthorized: %s requires %s role", route.action, role))
+		}
+	}
+}
+
+// verifyCSRFRequest is a handler, preceding state changing v2 API handlers, which rejects requests failing
+// CSRF validation
+func (this *HttpAPIv2) verifyCSRFRequest(r render.Render, req *http.Request) {
+	if err := verifyCSRF(req); err != nil {
+		this.renderError(r, APIv2Forbidden, err.Error())
+	}
+}
+
+// readJSONBodyParams is a handler, preceding state changing v2 API handlers, which reads a JSON object
+// request body into the query params
+func (this *HttpAPIv2) readJSONBodyParams(r render.Render, req *http.Request) {
+	if err := mergeJSONBodyParams(req); err != nil {
+		this.renderError(r, APIv2BadRequest, err.Error())
+	}
+}
+
+// RegisterRequests registers the v2 API routes
+func (this *HttpAPIv2) RegisterRequests(m *martini.ClassicMartini) {
+	for _, route := range this.routes() {
+		authorize := this.authorizeRequest(route)
+		switch route.method {
+		case "GET":
+			m.Get(route.path, authorize, route.handler)
+		case "POST":
+			m.Post(route.path, this.verifyCSRFRequest, this.readJSONBodyParams, authorize, route.handler)
+		case "DELETE":
+			m.Delete(route.path, this.verifyCSRFRequest, this.readJSONBodyParams, authorize, route.handler)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"github.com/martini-contrib/render"
+	. "gopkg.in/check.v1"
+	"strings"
+)
+
+// jsonRecorder is a render.Render which records the last rendered JSON response
+type jsonRecorder struct {
+	render.Render
+	status int
+	value  interface{}
+}
+
+func (this *jsonRecorder) JSON(status int, v interface{}) {
+	this.status = status
+	this.value = v
+}
+
+func (s *TestSuite) TestAPIv2PageSlice(c *C) {
+	items := []int{0, 1, 2, 3, 4, 5, 6}
+	c.Assert((&APIv2Page{Page: 0, PerPage: 3}).slice(items), DeepEquals, []int{0, 1, 2, 3})
+	c.Assert((&APIv2Page{Page: 1, PerPage: 3}).slice(items), DeepEquals, []int{3, 4, 5, 6})
+	c.Assert((&APIv2Page{Page: 2, PerPage: 3}).slice(items), DeepEquals, []int{6})
+	c.Assert((&APIv2Page{Page: 3, PerPage: 3}).slice(items), DeepEquals, []int{})
+}
+
+func (s *TestSuite) TestAPIv2RenderPage(c *C) {
+	items := []int{0, 1, 2, 3, 4, 5, 6}
+	{
+		// A full page followed by the first item of the next page
+		recorder := &jsonRecorder{}
+		page := &APIv2Page{Page: 1, PerPage: 3}
+		APIv2.renderPage(recorder, page, page.slice(items))
+		c.Assert(recorder.status, Equals, 200)
+		response := recorder.value.(*APIv2Response)
+		c.Assert(response.Data, DeepEquals, []int{3, 4, 5})
+		c.Assert(response.Page.HasMore, Equals, true)
+	}
+	{
+		// Exactly a full last page
+		recorder := &jsonRecorder{}
+		page := &APIv2Page{Page: 0, PerPage: 7}
+		APIv2.renderPage(recorder, page, page.slice(items))
+		response := recorder.value.(*APIv2Response)
+		c.Assert(response.Data, DeepEquals, items)
+		c.Assert(response.Page.HasMore, Equals, false)
+	}
+	{
+		recorder := &jsonRecorder{}
+		page := &APIv2Page{Page: 2, PerPage: 3}
+		APIv2.renderPage(recorder, page, page.slice(items))
+		response := recorder.value.(*APIv2Response)
+		c.Assert(response.Data, DeepEquals, []int{6})
+		c.Assert(response.Page.HasMore, Equals, false)
+	}
+}
+
+func (s *TestSuite) TestGenerateOpenAPIDocument(c *C) {
+	document := generateOpenAPIDocument(APIv2.routes())
+	c.Assert(document["openapi"], Equals, "3.0.0")
+	paths := document["paths"].(map[string]interface{})
+
+	clusterInstances := paths["/api/v2/clusters/{clusterName}/instances"].(map[string]interface{})["get"].(map[string]interface{})
+	responses := clusterInstances["responses"].(map[string]interface{})
+	for _, status := range []string{"200", "400", "403", "404", "500"} {
+		_, found := responses[status]
+		c.Assert(found, Equals, true, Commentf("status %s", status))
+	}
+	parameterNames := []string{}
+	for _, parameter := range clusterInstances["parameters"].([]interface{}) {
+		parameterNames = append(parameterNames, parameter.(map[string]interface{})["name"].(string))
+	}
+	c.Assert(parameterNames, DeepEquals, []string{"clusterName", "tag", "page", "perPage"})
+
+	instances := paths["/api/v2/instances"].(map[string]interface{})["get"].(map[string]interface{})
+	_, found := instances["responses"].(map[string]interface{})["400"]
+	c.Assert(found, Equals, true)
+
+	for _, route := range APIv2.routes() {
+		_, found := paths[openAPIPath(route.path)].(map[string]interface{})[strings.ToLower(route.method)]
+		c.Assert(found, Equals, true, Commentf("route %s %s", route.method, route.path))
+	}
+	schemas := document["components"].(map[string]interface{})["schemas"].(openAPISchemas)
+	_, found = schemas["http.APIv2Error"]
+	c.Assert(found, Equals, true)
+}
@@ -0,0 +1,115 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/martini-contrib/render"
+	"net/http"
+	"strings"
+)
+
+const (
+	csrfCookieName = "orchestrator-csrf-token"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// SetCSRFCookie is a middleware which hands out a CSRF token cookie to browsers not yet having one.
+// The web UI echoes the token in the X-CSRF-Token header of its POST/DELETE requests.
+func SetCSRFCookie(res http.ResponseWriter, req *http.Request) {
+	if cookie, err := req.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return
+	}
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return
+	}
+	http.SetCookie(res, &http.Cookie{Name: csrfCookieName, Value: hex.EncodeToString(token), Path: "/"})
+}
+
+// verifyCSRF validates a state changing request. Requests carrying the CSRF cookie (i.e. made by a browser
+// which visited orchestrator) must present the same token in the X-CSRF-Token header. Other requests (API
+// clients) must have a JSON content type, which cross site forms cannot send.
+func verifyCSRF(req *http.Request) error {
+	if cookie, err := req.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		if req.Header.Get(csrfHeaderName) != cookie.Value {
+			return errors.New("Invalid or missing CSRF token")
+		}
+		return nil
+	}
+	if req.Header.Get(csrfHeaderName) != "" {
+		return errors.New("CSRF token given without CSRF cookie")
+	}
+	if !strings.HasPrefix(strings.ToLower(req.Header.Get("Content-Type")), "application/json") {
+		return errors.New("Expecting Content-Type: application/json")
+	}
+	return nil
+}
+
+// verifyCSRFRequest is a handler, preceding state changing POST/DELETE API handlers, which rejects
+// requests failing CSRF validation
+func verifyCSRFRequest(r render.Render, req *http.Request) {
+	if err := verifyCSRF(req); err != nil {
+		r.JSON(403, &APIResponse{Code: ERROR, Message: err.Error()})
+	}
+}
+
+// readJSONBodyParams is a handler, preceding POST/DELETE API handlers, which reads a JSON object request body
+// into the query params, where API handlers expect their optional arguments.
+func readJSONBodyParams(r render.Render, req *http.Request) {
+	if err := mergeJSONBodyParams(req); err != nil {
+		r.JSON(400, &APIResponse{Code: ERROR, Message: err.Error()})
+	}
+}
+
+// mergeJSONBodyParams merges a JSON object request body, if any, into the query params. Params given in the
+// URL query take precedence. Body params never make it into the route params: these identify the instance or
+// cluster a request applies to, as authorized.
+func mergeJSONBodyParams(req *http.Request) error {
+	if req.Body == nil || req.ContentLength == 0 {
+		return nil
+	}
+	bodyParams := make(map[string]interface{})
+	decoder := json.NewDecoder(req.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&bodyParams); err != nil {
+		return fmt.Errorf("Cannot parse JSON body: %+v", err)
+	}
+	query := req.URL.Query()
+	for key, value := range bodyParams {
+		if query.Get(key) == "" {
+			query.Set(key, fmt.Sprintf("%v", value))
+		}
+	}
+	req.URL.RawQuery = query.Encode()
+	return nil
+}
+
+// rejectMutatingGetRequest is the handler of state changing API routes requested via GET, when such
+// requests are disallowed
+func rejectMutatingGetRequest(r render.Render, req *http.Request) {
+	r.JSON(405, &APIResponse{Code: ERROR, Message: fmt.Sprintf("%s must be requested via POST (GET is disabled by AllowMutatingGetRequests)", req.URL.Path)})
+}
+
+// warnDeprecatedGetRequest flags responses of state changing API routes requested via GET as deprecated
+func warnDeprecatedGetRequest(res http.ResponseWriter) {
+	res.Header().Set("Warning", `299 orchestrator "State changing GET requests are deprecated; use POST"`)
+}
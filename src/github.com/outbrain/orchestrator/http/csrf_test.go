@@ -0,0 +1,45 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	. "gopkg.in/check.v1"
+	"net/http"
+	"strings"
+)
+
+func (s *TestSuite) TestMergeJSONBodyParams(c *C) {
+	body := `{"clusterName": "other-cluster", "owner": "ops", "duration": 60}`
+	req, _ := http.NewRequest("POST", "/api/stop-slave/db1/3306?owner=dba", strings.NewReader(body))
+	c.Assert(mergeJSONBodyParams(req), IsNil)
+
+	query := req.URL.Query()
+	c.Assert(query.Get("owner"), Equals, "dba")
+	c.Assert(query.Get("duration"), Equals, "60")
+	c.Assert(query.Get("clusterName"), Equals, "other-cluster")
+}
+
+func (s *TestSuite) TestMergeJSONBodyParamsInvalidBody(c *C) {
+	req, _ := http.NewRequest("POST", "/api/stop-slave/db1/3306", strings.NewReader(`["not", "an", "object"]`))
+	c.Assert(mergeJSONBodyParams(req), Not(IsNil))
+}
+
+func (s *TestSuite) TestMergeJSONBodyParamsEmptyBody(c *C) {
+	req, _ := http.NewRequest("POST", "/api/stop-slave/db1/3306?owner=dba", nil)
+	c.Assert(mergeJSONBodyParams(req), IsNil)
+	c.Assert(req.URL.RawQuery, Equals, "owner=dba")
+}
@@ -0,0 +1,114 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/render"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/inst"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	eventsRoute             = "/api/events"
+	eventsHeartbeatInterval = 15 * time.Second
+)
+
+// DisableEventStreamCompression is a middleware, to be placed before gzip, which keeps the event stream
+// uncompressed so that each event is flushed to the client as it is published
+func DisableEventStreamCompression(req *http.Request) {
+	if strings.HasPrefix(req.URL.Path, eventsRoute) {
+		req.Header.Del("Accept-Encoding")
+	}
+}
+
+// Events streams topology events as server-sent events (text/event-stream).
+// Optional query params: "cluster" filters by cluster name, "type" by comma delimited event types.
+// A reconnecting client's Last-Event-ID header (or "lastEventId" param) replays recent events it has missed.
+// Events are published by the node observing them, hence clients should connect to the leader.
+func (this *HttpAPI) Events(params martini.Params, r render.Render, req *http.Request, res http.ResponseWriter) {
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		r.JSON(200, &APIResponse{Code: ERROR, Message: "Streaming unsupported"})
+		return
+	}
+	clusterName := req.URL.Query().Get("cluster")
+	eventTypes := []string{}
+	for _, eventType := range strings.Split(req.URL.Query().Get("type"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	lastEventIdParam := req.Header.Get("Last-Event-ID")
+	if lastEventIdParam == "" {
+		lastEventIdParam = req.URL.Query().Get("lastEventId")
+	}
+	lastEventId, _ := strconv.ParseInt(lastEventIdParam, 10, 64)
+
+	subscription, missedEvents := inst.SubscribeTopologyEvents(clusterName, eventTypes, lastEventId)
+	defer inst.UnsubscribeTopologyEvents(subscription)
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	writeEvent := func(event *inst.TopologyEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", event.EventId, event.Type, data)
+		return err
+	}
+	for _, event := range missedEvents {
+		if err := writeEvent(event); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	var closed <-chan bool
+	if closeNotifier, ok := res.(http.CloseNotifier); ok {
+		closed = closeNotifier.CloseNotify()
+	}
+	heartbeat := time.NewTicker(eventsHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case event := <-subscription.Events:
+			if err := writeEvent(event); err != nil {
+				log.Debugf("Events: %+v", err)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(res, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+		flusher.Flush()
+	}
+}
@@ -19,6 +19,7 @@ package http
 import (
 	"github.com/martini-contrib/auth"
 	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/ssl"
 	"net/http"
 	"strings"
 )
@@ -30,9 +31,16 @@ func getProxyAuthUser(req *http.Request) string {
 	return ""
 }
 
-// isAuthorizedForAction checks req to see whether authenticated user has write-privileges.
-// This depends on configured authentication method.
+// isAuthorizedForAction checks req to see whether authenticated user may make any changes at all, i.e.
+// has an operator or admin role on some cluster. Finer grained, per action and per cluster, authorization
+// is enforced via authorizeAPIRequest.
 func isAuthorizedForAction(req *http.Request, user auth.User) bool {
+	return roleLevels[getUserRole(req, user, "", true)] >= roleLevels[RoleOperator]
+}
+
+// isWriterUser checks req to see whether authenticated user has write-privileges, when no role bindings are
+// defined. This depends on configured authentication method.
+func isWriterUser(req *http.Request, user auth.User) bool {
 	if config.Config.ReadOnly {
 		return false
 	}
@@ -62,6 +70,19 @@ func isAuthorizedForAction(req *http.Request, user auth.User) bool {
 			}
 			return false
 		}
+	case "ssl":
+		{
+			authUser := ssl.GetClientCertCommonName(req)
+			if authUser == "" {
+				return false
+			}
+			for _, user := range config.Config.PowerAuthUsers {
+				if user == "*" || user == authUser {
+					return true
+				}
+			}
+			return false
+		}
 	default:
 		{
 			// Default: no authentication method
@@ -89,6 +110,10 @@ func getUserId(req *http.Request, user auth.User) string {
 		{
 			return getProxyAuthUser(req)
 		}
+	case "ssl":
+		{
+			return ssl.GetClientCertCommonName(req)
+		}
 	default:
 		{
 			return ""
@@ -0,0 +1,222 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var routeParamRegexp = regexp.MustCompile(`:([a-zA-Z0-9_]+)`)
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// openAPISchemas collects the component schemas of an OpenAPI document, as generated from Go types
+type openAPISchemas map[string]interface{}
+
+// schemaRef returns a reference to a component schema
+func schemaRef(name string) map[string]interface{} {
+	return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+}
+
+// schemaOf returns the schema of given type as marshalled by encoding/json. Named struct types
+// are registered as component schemas and referenced.
+func (this openAPISchemas) schemaOf(t reflect.Type) map[string]interface{} {
+	if t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType) {
+		// Custom marshalling; cannot tell
+		return map[string]interface{}{}
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return this.schemaOf(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": this.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": this.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return this.structSchema(t)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, found := this[name]; !found {
+			// Register before generating, so as to support recursive types
+			this[name] = map[string]interface{}{}
+			this[name] = this.structSchema(t)
+		}
+		return schemaRef(name)
+	}
+	return map[string]interface{}{}
+}
+
+// structSchema returns the object schema of given struct type, following encoding/json field naming rules
+func (this openAPISchemas) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	this.addStructProperties(t, properties)
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+func (this openAPISchemas) addStructProperties(t reflect.Type, properties map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			// Embedded struct fields are promoted
+			this.addStructProperties(field.Type, properties)
+			continue
+		}
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = this.schemaOf(field.Type)
+	}
+}
+
+// openAPIPath converts a martini route path into an OpenAPI path, e.g. /api/v2/agents/:host => /api/v2/agents/{host}
+func openAPIPath(routePath string) string {
+	return routeParamRegexp.ReplaceAllString(routePath, "{$1}")
+}
+
+// openAPIOperation generates the OpenAPI operation object of a route
+func (this openAPISchemas) openAPIOperation(route apiv2Route) map[string]interface{} {
+	parameters := []interface{}{}
+	for _, match := range routeParamRegexp.FindAllStringSubmatch(route.path, -1) {
+		parameters = append(parameters, map[string]interface{}{
+			"name": match[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, queryParam := range route.queryParams {
+		parameters = append(parameters, map[string]interface{}{
+			"name": queryParam, "in": "query", "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	if route.paginated {
+		for _, pageParam := range []string{"page", "perPage"} {
+			parameters = append(parameters, map[string]interface{}{
+				"name": pageParam, "in": "query", "schema": map[string]interface{}{"type": "integer"},
+			})
+		}
+	}
+
+	// The OpenAPI document itself is returned as is, not enveloped
+	responseSchema := map[string]interface{}{"type": "object"}
+	if route.response != nil {
+		data := this.schemaOf(reflect.TypeOf(route.response))
+		responseProperties := map[string]interface{}{"Data": data}
+		if route.paginated {
+			responseProperties["Data"] = map[string]interface{}{"type": "array", "items": data}
+			responseProperties["Page"] = this.schemaOf(reflect.TypeOf(APIv2Page{}))
+		}
+		responseSchema["properties"] = responseProperties
+	}
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "OK",
+			"content":     jsonContent(responseSchema),
+		},
+	}
+	errorCodes := append([]APIv2ErrorCode{APIv2Forbidden, APIv2InternalError}, route.errors...)
+	if route.paginated {
+		// Invalid page or perPage params
+		errorCodes = append(errorCodes, APIv2BadRequest)
+	}
+	for _, errorCode := range errorCodes {
+		responses[strconv.Itoa(apiv2ErrorStatus[errorCode])] = map[string]interface{}{
+			"description": string(errorCode),
+			"content":     jsonContent(this.schemaOf(reflect.TypeOf(APIv2Error{}))),
+		}
+	}
+
+	operation := map[string]interface{}{
+		"summary":    route.summary,
+		"parameters": parameters,
+		"responses":  responses,
+	}
+	if len(route.bodyParams) > 0 {
+		bodyProperties := map[string]interface{}{}
+		for _, bodyParam := range route.bodyParams {
+			bodyProperties[bodyParam] = map[string]interface{}{"type": "string"}
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"content": jsonContent(map[string]interface{}{"type": "object", "properties": bodyProperties}),
+		}
+	}
+	return operation
+}
+
+func jsonContent(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// generateOpenAPIDocument generates an OpenAPI 3.0 document describing given routes
+func generateOpenAPIDocument(routes []apiv2Route) map[string]interface{} {
+	schemas := openAPISchemas{}
+	paths := map[string]interface{}{}
+	for _, route := range routes {
+		pathItem, found := paths[openAPIPath(route.path)].(map[string]interface{})
+		if !found {
+			pathItem = map[string]interface{}{}
+			paths[openAPIPath(route.path)] = pathItem
+		}
+		pathItem[strings.ToLower(route.method)] = schemas.openAPIOperation(route)
+	}
+
+	errorCodes := []string{}
+	for errorCode := range apiv2ErrorStatus {
+		errorCodes = append(errorCodes, string(errorCode))
+	}
+	sort.Strings(errorCodes)
+	schemas.schemaOf(reflect.TypeOf(APIv2Error{}))
+	schemas["http.APIv2Error"].(map[string]interface{})["properties"].(map[string]interface{})["Code"] = map[string]interface{}{
+		"type": "string", "enum": errorCodes,
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.0",
+		"info": map[string]interface{}{
+			"title":   "orchestrator",
+			"version": "2",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+		},
+	}
+}
@@ -0,0 +1,258 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"fmt"
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	"github.com/martini-contrib/render"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/inst"
+	"github.com/pmylund/go-cache"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Roles, in increasing order of privileges
+const (
+	RoleViewer   string = "viewer"
+	RoleOperator        = "operator"
+	RoleAdmin           = "admin"
+)
+
+var roleLevels = map[string]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// IsValidRole tests whether given role name is known
+func IsValidRole(role string) bool {
+	_, ok := roleLevels[role]
+	return ok
+}
+
+// viewerActions are API actions which only read information
+var viewerActions = map[string]bool{
+	"instance": true, "resolve": true, "last-pseudo-gtid": true, "promotion-rules": true,
+	"maintenance": true, "cluster": true, "cluster-info": true, "cluster-osc-slaves": true,
+	"cluster-replication-filters-divergence": true, "clusters": true, "clusters-info": true, "search": true,
+	"problems": true, "long-queries": true, "audit": true, "audit-recovery": true, "replication-analysis": true,
+	"blocked-recoveries": true, "recovery-blocks": true, "unacknowledged-recoveries": true,
+	"automated-recovery-filters": true, "cluster-pool-instances": true, "hostname-resolve-cache": true,
+	"headers": true, "health": true, "lb-check": true, "agents": true, "agent": true, "agent-active-seeds": true,
+	"agent-recent-seeds": true, "agent-seed-details": true, "agent-seed-states": true, "seeds": true,
+	"query-kill-policies": true, "query-kill-history": true, "failure-detection-snapshots": true,
+	"failure-detection-snapshot": true, "tags": true, "tagged": true, "scheduled-windows": true,
+	"role-bindings": true, "openapi": true, "events": true,
+	"instance-changes": true,
+}
+
+// deleteActions are state changing API actions which remove an entity, and are served via DELETE as well as POST
+var deleteActions = map[string]bool{
+	"forget": true, "end-maintenance": true, "end-downtime": true, "unregister-promotion-rule": true,
+	"unschedule-window": true, "untag": true, "delete-query-kill-policy": true, "revoke-role": true,
+}
+
+// routeAction returns the action of an API route, e.g. "begin-maintenance" for "/api/begin-maintenance/:host/:port"
+func routeAction(route string) string {
+	return strings.Split(strings.TrimPrefix(route, "/api/"), "/")[0]
+}
+
+// isStateChangingAction tests whether an API action may change state, i.e. is not a viewer action
+func isStateChangingAction(action string) bool {
+	return !viewerActions[action]
+}
+
+// operatorActions are API actions which affect an instance's operation but not the topology
+var operatorActions = map[string]bool{
+	"discover": true, "refresh": true, "begin-maintenance": true, "end-maintenance": true,
+	"begin-downtime": true, "end-downtime": true, "schedule-maintenance": true, "schedule-downtime": true,
+	"unschedule-window": true, "start-slave": true, "stop-slave": true, "stop-slave-nice": true,
+	"skip-query": true, "kill-query": true, "ack-recovery": true, "tag": true, "untag": true,
+}
+
+// requiredRole returns the role required for an API action. Actions not known to be viewer/operator
+// actions require the admin role.
+func requiredRole(action string, params martini.Params) string {
+	if action == "bulk" {
+		// Bulk operations are named after their single instance counterparts
+		action = params["operation"]
+	}
+	if viewerActions[action] {
+		return RoleViewer
+	}
+	if operatorActions[action] {
+		return RoleOperator
+	}
+	return RoleAdmin
+}
+
+var roleBindingsCache = cache.New(time.Minute, time.Minute)
+
+// readBackendRoleBindings reads role bindings stored in the backend database
+var readBackendRoleBindings = inst.ReadRoleBindings
+
+// getRoleBindings returns configured role bindings along with those stored in the backend database.
+// Should the backend be unreadable, the last successfully read backend bindings are used. The second
+// return value is false when backend bindings are unknown altogether, in which case authorization must fail closed.
+func getRoleBindings() ([]config.RoleBinding, bool) {
+	if roleBindings, found := roleBindingsCache.Get("roleBindings"); found {
+		return roleBindings.([]config.RoleBinding), true
+	}
+	roleBindings := []config.RoleBinding{}
+	roleBindings = append(roleBindings, config.Config.RoleBindings...)
+	backendRoleBindings, err := readBackendRoleBindings()
+	if err != nil {
+		if lastBackendRoleBindings, found := roleBindingsCache.Get("lastBackendRoleBindings"); found {
+			log.Warningf("Cannot read role bindings; using last known bindings: %+v", err)
+			return append(roleBindings, lastBackendRoleBindings.([]config.RoleBinding)...), true
+		}
+		log.Errorf("Cannot read role bindings; only configured bindings apply: %+v", err)
+		return roleBindings, false
+	}
+	roleBindingsCache.Set("lastBackendRoleBindings", backendRoleBindings, cache.NoExpiration)
+	roleBindings = append(roleBindings, backendRoleBindings...)
+	roleBindingsCache.Set("roleBindings", roleBindings, cache.DefaultExpiration)
+	return roleBindings, true
+}
+
+// isRoleBasedAccessControl tests whether any role bindings are defined. With no bindings, the legacy
+// read-only/writer authorization applies. When bindings cannot be read, role based access control
+// is assumed, such that users are not granted more than their (configured) bindings.
+func isRoleBasedAccessControl() bool {
+	roleBindings, known := getRoleBindings()
+	return !known || len(roleBindings) > 0
+}
+
+// clusterPatternMatches tests whether a role binding's cluster pattern matches the entirety of given name
+func clusterPatternMatches(clusterPattern string, name string) bool {
+	matched, _ := regexp.MatchString(fmt.Sprintf("^(?:%s)$", clusterPattern), name)
+	return matched
+}
+
+// roleBindingAppliesToCluster tests whether given binding's cluster pattern matches a cluster's name or alias.
+// An empty cluster name stands for actions not scoped by cluster, which only unscoped bindings apply to.
+func roleBindingAppliesToCluster(roleBinding *config.RoleBinding, clusterName string) bool {
+	if roleBinding.ClusterPattern == "" {
+		return true
+	}
+	if clusterName == "" {
+		return false
+	}
+	if clusterPatternMatches(roleBinding.ClusterPattern, clusterName) {
+		return true
+	}
+	if clusterAlias := inst.GetClusterAlias(clusterName); clusterAlias != "" {
+		if clusterPatternMatches(roleBinding.ClusterPattern, clusterAlias) {
+			return true
+		}
+	}
+	return false
+}
+
+// getUserRole returns the highest role of the authenticated user on given cluster. An empty cluster name
+// returns the user's role on non-cluster scoped actions; anyCluster returns the user's highest role
+// on any cluster.
+func getUserRole(req *http.Request, user auth.User, clusterName string, anyCluster bool) string {
+	if config.Config.ReadOnly {
+		return RoleViewer
+	}
+	if !isRoleBasedAccessControl() {
+		if isWriterUser(req, user) {
+			return RoleAdmin
+		}
+		return RoleViewer
+	}
+	if strings.ToLower(config.Config.AuthenticationMethod) == "multi" && string(user) == "readonly" {
+		return RoleViewer
+	}
+	userId := getUserId(req, user)
+	role := RoleViewer
+	roleBindings, _ := getRoleBindings()
+	for _, roleBinding := range roleBindings {
+		roleBinding := roleBinding
+		if roleBinding.User != "*" && roleBinding.User != userId {
+			continue
+		}
+		if !IsValidRole(roleBinding.Role) || roleLevels[roleBinding.Role] <= roleLevels[role] {
+			continue
+		}
+		if anyCluster || roleBindingAppliesToCluster(&roleBinding, clusterName) {
+			role = roleBinding.Role
+		}
+	}
+	return role
+}
+
+// hasRole tests whether authenticated user has at least given role on given cluster
+func hasRole(req *http.Request, user auth.User, role string, clusterName string) bool {
+	return roleLevels[getUserRole(req, user, clusterName, false)] >= roleLevels[role]
+}
+
+// getRequestClusterName deduces the cluster an API request applies to, based on its route params
+// (cluster name, alias or instance) alone. An empty result means the request is not scoped by cluster.
+func getRequestClusterName(params martini.Params) string {
+	if clusterName := params["clusterName"]; clusterName != "" {
+		return clusterName
+	}
+	if clusterAlias := params["clusterAlias"]; clusterAlias != "" {
+		clusterName, _ := inst.GetClusterByAlias(clusterAlias)
+		return clusterName
+	}
+	if params["host"] != "" && params["port"] != "" {
+		instanceKey, err := inst.NewInstanceKeyFromStrings(params["host"], params["port"])
+		if err != nil {
+			return ""
+		}
+		instance, found, _ := inst.ReadInstance(instanceKey)
+		if !found {
+			return ""
+		}
+		return instance.ClusterName
+	}
+	return ""
+}
+
+// authorizeAPIRequest returns a handler, preceding the actual API handler of given route, which enforces
+// the role required by the route's action on the cluster the request applies to.
+func authorizeAPIRequest(route string) martini.Handler {
+	action := routeAction(route)
+	return func(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+		role := requiredRole(action, params)
+		if role == RoleViewer {
+			return
+		}
+		clusterName := getRequestClusterName(params)
+		if action == "bulk" {
+			selector, err := getBulkSelector(req)
+			if err != nil {
+				r.JSON(200, &APIResponse{Code: ERROR, Message: err.Error()})
+				return
+			}
+			clusterName = selector.ClusterName
+		}
+		if !hasRole(req, user, role, clusterName) {
+			log.Debugf("Unauthorized: %s requires role %s on cluster '%s'; user: %s", action, role, clusterName, getUserId(req, user))
+			r.JSON(200, &APIResponse{Code: ERROR, Message: fmt.Sprintf("Unauthorized: %s requires %s role", action, role)})
+		}
+	}
+}
@@ -0,0 +1,117 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package http
+
+import (
+	"errors"
+	"github.com/go-martini/martini"
+	"github.com/martini-contrib/auth"
+	"github.com/outbrain/orchestrator/config"
+	. "gopkg.in/check.v1"
+	"net/http"
+	"testing"
+)
+
+func Test(t *testing.T) { TestingT(t) }
+
+type TestSuite struct{}
+
+var _ = Suite(&TestSuite{})
+
+var testRequest, _ = http.NewRequest("GET", "/api/test", nil)
+
+func (s *TestSuite) SetUpTest(c *C) {
+	config.Config.ReadOnly = false
+	config.Config.AuthenticationMethod = "basic"
+	config.Config.RoleBindings = []config.RoleBinding{}
+	roleBindingsCache.Flush()
+}
+
+// setBackendRoleBindings makes for the role bindings read off the backend database
+func setBackendRoleBindings(roleBindings []config.RoleBinding, err error) {
+	readBackendRoleBindings = func() ([]config.RoleBinding, error) {
+		return roleBindings, err
+	}
+	roleBindingsCache.Delete("roleBindings")
+}
+
+func (s *TestSuite) TestRequiredRole(c *C) {
+	c.Assert(requiredRole("cluster", martini.Params{}), Equals, RoleViewer)
+	c.Assert(requiredRole("begin-maintenance", martini.Params{}), Equals, RoleOperator)
+	c.Assert(requiredRole("relocate", martini.Params{}), Equals, RoleAdmin)
+	c.Assert(requiredRole("no-such-action", martini.Params{}), Equals, RoleAdmin)
+	c.Assert(requiredRole("bulk", martini.Params{"operation": "start-slave"}), Equals, RoleOperator)
+	c.Assert(requiredRole("bulk", martini.Params{"operation": "set-writeable"}), Equals, RoleAdmin)
+}
+
+func (s *TestSuite) TestGetUserRoleWithoutRoleBindings(c *C) {
+	setBackendRoleBindings([]config.RoleBinding{}, nil)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "db1", false), Equals, RoleAdmin)
+
+	config.Config.ReadOnly = true
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "db1", false), Equals, RoleViewer)
+}
+
+func (s *TestSuite) TestGetUserRoleByCluster(c *C) {
+	setBackendRoleBindings([]config.RoleBinding{
+		{User: "alice", Role: RoleOperator, ClusterPattern: "db1"},
+		{User: "carol", Role: RoleAdmin, ClusterPattern: "db1.*|archive"},
+		{User: "*", Role: RoleViewer},
+	}, nil)
+
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "db1", false), Equals, RoleOperator)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "db10", false), Equals, RoleViewer)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "xdb1-archive", false), Equals, RoleViewer)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "", false), Equals, RoleViewer)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "", true), Equals, RoleOperator)
+	c.Assert(getUserRole(testRequest, auth.User("carol"), "db10", false), Equals, RoleAdmin)
+	c.Assert(getUserRole(testRequest, auth.User("carol"), "archive", false), Equals, RoleAdmin)
+	c.Assert(getUserRole(testRequest, auth.User("carol"), "xdb1-archive", false), Equals, RoleViewer)
+	c.Assert(getUserRole(testRequest, auth.User("bob"), "db1", false), Equals, RoleViewer)
+}
+
+func (s *TestSuite) TestGetUserRoleUnreadableBackend(c *C) {
+	config.Config.RoleBindings = []config.RoleBinding{{User: "alice", Role: RoleAdmin, ClusterPattern: "db1"}}
+	setBackendRoleBindings(nil, errors.New("backend unavailable"))
+
+	c.Assert(isRoleBasedAccessControl(), Equals, true)
+	c.Assert(getUserRole(testRequest, auth.User("bob"), "db1", false), Equals, RoleViewer)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "db1", false), Equals, RoleAdmin)
+}
+
+func (s *TestSuite) TestGetUserRoleLastKnownBackendBindings(c *C) {
+	setBackendRoleBindings([]config.RoleBinding{{User: "bob", Role: RoleOperator}}, nil)
+	c.Assert(getUserRole(testRequest, auth.User("bob"), "db1", false), Equals, RoleOperator)
+
+	setBackendRoleBindings(nil, errors.New("backend unavailable"))
+	c.Assert(getUserRole(testRequest, auth.User("bob"), "db1", false), Equals, RoleOperator)
+	c.Assert(getUserRole(testRequest, auth.User("alice"), "db1", false), Equals, RoleViewer)
+}
+
+func (s *TestSuite) TestGetBulkSelector(c *C) {
+	req, _ := http.NewRequest("GET", "/api/bulk/stop-slave?cluster=db1:3306&pattern=db", nil)
+	selector, err := getBulkSelector(req)
+	c.Assert(err, IsNil)
+	c.Assert(selector.ClusterName, Equals, "db1:3306")
+	c.Assert(selector.Pattern, Equals, "db")
+}
+
+func (s *TestSuite) TestGetBulkSelectorClusterAndAlias(c *C) {
+	req, _ := http.NewRequest("GET", "/api/bulk/stop-slave?cluster=db1:3306&alias=other", nil)
+	_, err := getBulkSelector(req)
+	c.Assert(err, Not(IsNil))
+}
@@ -169,6 +169,20 @@ func (this *HttpWeb) Audit(params martini.Params, r render.Render, req *http.Req
 		"userId":              getUserId(req, user),
 		"autoshow_problems":   false,
 		"page":                page,
+		"unacknowledgedOnly":  false,
+	})
+}
+
+func (this *HttpWeb) UnacknowledgedRecoveries(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	r.HTML(200, "templates/audit_recovery", map[string]interface{}{
+		"agentsHttpActive":    config.Config.ServeAgentsHttp,
+		"title":               "unacknowledged-recoveries",
+		"activePage":          "audit-recovery",
+		"authorizedForAction": isAuthorizedForAction(req, user),
+		"userId":              getUserId(req, user),
+		"autoshow_problems":   false,
+		"page":                0,
+		"unacknowledgedOnly":  true,
 	})
 }
 
@@ -189,6 +203,38 @@ func (this *HttpWeb) AuditRecovery(params martini.Params, r render.Render, req *
 	})
 }
 
+// ScheduledWindows lists scheduled maintenance & downtime windows
+func (this *HttpWeb) ScheduledWindows(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	r.HTML(200, "templates/scheduled_windows", map[string]interface{}{
+		"agentsHttpActive":    config.Config.ServeAgentsHttp,
+		"title":               "scheduled windows",
+		"activePage":          "extra",
+		"authorizedForAction": isAuthorizedForAction(req, user),
+		"userId":              getUserId(req, user),
+		"autoshow_problems":   false,
+		"clusterName":         params["clusterName"],
+	})
+}
+
+// FailureDetectionSnapshots lists processlist & status snapshots captured upon failure detection
+func (this *HttpWeb) FailureDetectionSnapshots(params martini.Params, r render.Render, req *http.Request, user auth.User) {
+	page, err := strconv.Atoi(req.URL.Query().Get("page"))
+	if err != nil || page < 0 {
+		page = 0
+	}
+
+	r.HTML(200, "templates/failure_detection_snapshots", map[string]interface{}{
+		"agentsHttpActive":    config.Config.ServeAgentsHttp,
+		"title":               "failure detection snapshots",
+		"activePage":          "audit",
+		"authorizedForAction": isAuthorizedForAction(req, user),
+		"userId":              getUserId(req, user),
+		"autoshow_problems":   false,
+		"page":                page,
+		"clusterName":         params["clusterName"],
+	})
+}
+
 func (this *HttpWeb) Agents(params martini.Params, r render.Render, req *http.Request, user auth.User) {
 	r.HTML(200, "templates/agents", map[string]interface{}{
 		"agentsHttpActive":    config.Config.ServeAgentsHttp,
@@ -303,7 +349,12 @@ func (this *HttpWeb) RegisterRequests(m *martini.ClassicMartini) {
 	m.Get("/web/audit", this.Audit)
 	m.Get("/web/audit/:page", this.Audit)
 	m.Get("/web/audit-recovery", this.AuditRecovery)
+	m.Get("/web/audit-recovery/unacknowledged", this.UnacknowledgedRecoveries)
 	m.Get("/web/audit-recovery/:page", this.AuditRecovery)
+	m.Get("/web/scheduled-windows", this.ScheduledWindows)
+	m.Get("/web/scheduled-windows/:clusterName", this.ScheduledWindows)
+	m.Get("/web/failure-detection-snapshots", this.FailureDetectionSnapshots)
+	m.Get("/web/failure-detection-snapshots/cluster/:clusterName", this.FailureDetectionSnapshots)
 	m.Get("/web/agents", this.Agents)
 	m.Get("/web/agent/:host", this.Agent)
 	m.Get("/web/seed-details/:seedId", this.AgentSeedDetails)
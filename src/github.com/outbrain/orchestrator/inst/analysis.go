@@ -39,6 +39,7 @@ const (
 	UnreachableIntermediateMaster                          = "UnreachableIntermediateMaster"
 	AllIntermediateMasterSlavesNotReplicating              = "AllIntermediateMasterSlavesNotReplicating"
 	FirstTierSlaveFailingToConnectToMaster                 = "FirstTierSlaveFailingToConnectToMaster"
+	MasterWithTooFewSemiSyncSlaves                         = "MasterWithTooFewSemiSyncSlaves"
 )
 
 // ReplicationAnalysis notes analysis on replication chain status, per instance
@@ -56,6 +57,9 @@ type ReplicationAnalysis struct {
 	ReplicationDepth            uint
 	SlaveHosts                  InstanceKeyMap
 	IsFailingToConnectToMaster  bool
+	IsSemiSyncMaster            bool
+	CountSemiSyncSlaves         uint
+	SemiSyncRequiredSlaves      uint
 	Analysis                    AnalysisCode
 	Description                 string
 	IsDowntimed                 bool
@@ -58,6 +58,12 @@ func GetReplicationAnalysis(includeDowntimed bool) ([]ReplicationAnalysis, error
 		            AND master_instance.slave_io_running = 0
 		            AND master_instance.last_io_error RLIKE 'error (connecting|reconnecting) to master'
 		          ) AS is_failing_to_connect_to_master,
+		        MIN(master_instance.semi_sync_master_enabled) AS is_semi_sync_master,
+		        MIN(master_instance.semi_sync_master_wait_for_slave_count) AS semi_sync_master_wait_for_slave_count,
+		        IFNULL(SUM(slave_instance.last_checked <= slave_instance.last_seen
+		                    AND slave_instance.slave_io_running != 0
+		                    AND slave_instance.semi_sync_slave_enabled != 0),
+		                0) AS count_semi_sync_slaves,
 		        MIN(
 		    		database_instance_downtime.downtime_active IS NULL
 		    		OR database_instance_downtime.end_timestamp < NOW()
@@ -116,6 +122,9 @@ func GetReplicationAnalysis(includeDowntimed bool) ([]ReplicationAnalysis, error
 		a.CountValidReplicatingSlaves = m.GetUint("count_valid_replicating_slaves")
 		a.ReplicationDepth = m.GetUint("replication_depth")
 		a.IsFailingToConnectToMaster = m.GetBool("is_failing_to_connect_to_master")
+		a.IsSemiSyncMaster = m.GetBool("is_semi_sync_master")
+		a.CountSemiSyncSlaves = m.GetUint("count_semi_sync_slaves")
+		a.SemiSyncRequiredSlaves = semiSyncRequiredSlaves(m.GetUint("semi_sync_master_wait_for_slave_count"))
 		a.IsDowntimed = m.GetBool("is_downtimed")
 		a.DowntimeEndTimestamp = m.GetString("downtime_end_timestamp")
 		a.DowntimeRemainingSeconds = m.GetInt("downtime_remaining_seconds")
@@ -166,6 +175,9 @@ func GetReplicationAnalysis(includeDowntimed bool) ([]ReplicationAnalysis, error
 		} else if a.ReplicationDepth == 1 && a.IsFailingToConnectToMaster {
 			a.Analysis = FirstTierSlaveFailingToConnectToMaster
 			a.Description = "1st tier slave (directly replicating from topology master) is unable to connect to the master"
+		} else if a.IsMaster && a.LastCheckValid && a.IsSemiSyncMaster && a.CountSemiSyncSlaves < a.SemiSyncRequiredSlaves {
+			a.Analysis = MasterWithTooFewSemiSyncSlaves
+			a.Description = "Master has semi-sync enabled but fewer replicating semi-sync slaves than required"
 		}
 		//		 else if a.IsMaster && a.CountSlaves == 0 {
 		//			a.Analysis = MasterWithoutSlaves
@@ -0,0 +1,252 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/config"
+	"sync"
+	"time"
+)
+
+// Operations supported by ExecuteBulkOperation
+const (
+	BulkSetReadOnly      string = "set-read-only"
+	BulkSetWriteable            = "set-writeable"
+	BulkStartSlave              = "start-slave"
+	BulkStopSlave               = "stop-slave"
+	BulkStopSlaveNicely         = "stop-slave-nice"
+	BulkBeginDowntime           = "begin-downtime"
+	BulkEndDowntime             = "end-downtime"
+	BulkBeginMaintenance        = "begin-maintenance"
+	BulkEndMaintenance          = "end-maintenance"
+	BulkRefresh                 = "refresh"
+)
+
+var bulkOperations = map[string]bool{
+	BulkSetReadOnly:      true,
+	BulkSetWriteable:     true,
+	BulkStartSlave:       true,
+	BulkStopSlave:        true,
+	BulkStopSlaveNicely:  true,
+	BulkBeginDowntime:    true,
+	BulkEndDowntime:      true,
+	BulkBeginMaintenance: true,
+	BulkEndMaintenance:   true,
+	BulkRefresh:          true,
+}
+
+// IsBulkOperation tests whether given operation name is supported as bulk operation
+func IsBulkOperation(operation string) bool {
+	return bulkOperations[operation]
+}
+
+// InstanceSelector describes a set of instances: instances of a cluster, instances whose name matches a pattern,
+// instances of a pool, and/or instances matching a tag selector. All given criteria must hold.
+type InstanceSelector struct {
+	ClusterName string
+	Pattern     string
+	Pool        string
+	Tag         string
+}
+
+// IsEmpty returns true when no criteria is given. An empty selector selects nothing.
+func (this *InstanceSelector) IsEmpty() bool {
+	return this.ClusterName == "" && this.Pattern == "" && this.Pool == "" && this.Tag == ""
+}
+
+// Criteria of an InstanceSelector, in order of precedence
+const (
+	selectByCluster string = "cluster"
+	selectByPattern        = "pattern"
+	selectByPool           = "pool"
+	selectByTag            = "tag"
+)
+
+// primaryCriterion returns the criterion by which instances are initially read; remaining criteria filter them
+func (this *InstanceSelector) primaryCriterion() string {
+	switch {
+	case this.ClusterName != "":
+		return selectByCluster
+	case this.Pattern != "":
+		return selectByPattern
+	case this.Pool != "":
+		return selectByPool
+	}
+	return selectByTag
+}
+
+// ReadSelectedInstances reads all instances matching given selector
+func ReadSelectedInstances(selector *InstanceSelector) ([](*Instance), error) {
+	if selector.IsEmpty() {
+		return [](*Instance){}, fmt.Errorf("Empty instance selector: expecting cluster, pattern, pool or tag")
+	}
+	var poolInstanceKeys [](*InstanceKey)
+	if selector.Pool != "" {
+		var err error
+		if poolInstanceKeys, err = ReadPoolInstanceKeys(selector.Pool); err != nil {
+			return [](*Instance){}, err
+		}
+	}
+	instances := [](*Instance){}
+	var err error
+	switch selector.primaryCriterion() {
+	case selectByCluster:
+		instances, err = ReadClusterInstances(selector.ClusterName)
+	case selectByPattern:
+		instances, err = FindInstances(selector.Pattern)
+	case selectByPool:
+		for _, instanceKey := range poolInstanceKeys {
+			instance, found, err := ReadInstance(instanceKey)
+			if err != nil {
+				return instances, err
+			}
+			if found {
+				instances = append(instances, instance)
+			}
+		}
+	case selectByTag:
+		instances, err = ReadTaggedInstances(selector.Tag)
+	}
+	if err != nil {
+		return instances, err
+	}
+	return filterSelectedInstances(instances, selector, poolInstanceKeys)
+}
+
+// filterSelectedInstances applies the criteria of given selector other than its primary criterion, by which
+// given instances were read. poolInstanceKeys are the instances of the selector's pool, if any.
+func filterSelectedInstances(instances [](*Instance), selector *InstanceSelector, poolInstanceKeys [](*InstanceKey)) ([](*Instance), error) {
+	primaryCriterion := selector.primaryCriterion()
+	if selector.Pattern != "" && primaryCriterion != selectByPattern {
+		instances = filterInstancesByPattern(instances, selector.Pattern)
+	}
+	if selector.Pool != "" && primaryCriterion != selectByPool {
+		poolInstancesMap := make(map[InstanceKey]bool)
+		for _, instanceKey := range poolInstanceKeys {
+			poolInstancesMap[*instanceKey] = true
+		}
+		filtered := [](*Instance){}
+		for _, instance := range instances {
+			if poolInstancesMap[instance.Key] {
+				filtered = append(filtered, instance)
+			}
+		}
+		instances = filtered
+	}
+	if selector.Tag != "" && primaryCriterion != selectByTag {
+		return FilterInstancesByTagSelector(instances, selector.Tag)
+	}
+	return instances, nil
+}
+
+// BulkOperationParams are the operation specific arguments of a bulk operation
+type BulkOperationParams struct {
+	Owner           string
+	Reason          string
+	DurationSeconds uint
+}
+
+// BulkOperationResult is the outcome of a bulk operation on a single instance
+type BulkOperationResult struct {
+	Key     InstanceKey
+	Success bool
+	Message string
+}
+
+// executeSingleBulkOperation applies given operation on a single instance
+func executeSingleBulkOperation(instanceKey *InstanceKey, operation string, operationParams *BulkOperationParams) (message string, err error) {
+	switch operation {
+	case BulkSetReadOnly:
+		_, err = SetReadOnly(instanceKey, true)
+		return "read-only", err
+	case BulkSetWriteable:
+		_, err = SetReadOnly(instanceKey, false)
+		return "writeable", err
+	case BulkStartSlave:
+		_, err = StartSlave(instanceKey)
+		return "slave started", err
+	case BulkStopSlave:
+		_, err = StopSlave(instanceKey)
+		return "slave stopped", err
+	case BulkStopSlaveNicely:
+		_, err = StopSlaveNicely(instanceKey, time.Duration(config.Config.InstanceBulkOperationsWaitTimeoutSeconds)*time.Second)
+		return "slave stopped nicely", err
+	case BulkBeginDowntime:
+		err = BeginDowntime(instanceKey, operationParams.Owner, operationParams.Reason, operationParams.DurationSeconds)
+		return "downtime begun", err
+	case BulkEndDowntime:
+		err = EndDowntime(instanceKey)
+		return "downtime ended", err
+	case BulkBeginMaintenance:
+		var maintenanceKey int64
+		maintenanceKey, err = BeginBoundedMaintenance(instanceKey, operationParams.Owner, operationParams.Reason, operationParams.DurationSeconds)
+		return fmt.Sprintf("maintenance begun: %d", maintenanceKey), err
+	case BulkEndMaintenance:
+		err = EndMaintenanceByInstanceKey(instanceKey)
+		return "maintenance ended", err
+	case BulkRefresh:
+		_, err = RefreshTopologyInstance(instanceKey)
+		return "refreshed", err
+	}
+	return "", fmt.Errorf("Unsupported bulk operation: %s", operation)
+}
+
+// ExecuteBulkOperation applies an operation on all instances matching given selector, running at most
+// `concurrency` operations at any given time (bounded by BulkOperationsMaxConcurrency).
+// Results are listed in instances order; each action is audited.
+func ExecuteBulkOperation(selector *InstanceSelector, operation string, operationParams *BulkOperationParams, concurrency int) ([]BulkOperationResult, error) {
+	results := []BulkOperationResult{}
+	if !IsBulkOperation(operation) {
+		return results, fmt.Errorf("Unsupported bulk operation: %s", operation)
+	}
+	instances, err := ReadSelectedInstances(selector)
+	if err != nil {
+		return results, log.Errore(err)
+	}
+	if concurrency <= 0 || concurrency > int(config.Config.BulkOperationsMaxConcurrency) {
+		concurrency = int(config.Config.BulkOperationsMaxConcurrency)
+	}
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	log.Infof("Bulk %s on %d instances with concurrency %d; selector: %+v", operation, len(instances), concurrency, *selector)
+
+	results = make([]BulkOperationResult, len(instances))
+	semaphore := make(chan bool, concurrency)
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		i, instanceKey := i, instance.Key
+		wg.Add(1)
+		semaphore <- true
+		go func() {
+			defer func() { <-semaphore }()
+			defer wg.Done()
+
+			message, err := executeSingleBulkOperation(&instanceKey, operation, operationParams)
+			result := BulkOperationResult{Key: instanceKey, Success: (err == nil), Message: message}
+			if err != nil {
+				result.Message = err.Error()
+			}
+			results[i] = result
+			AuditOperation(fmt.Sprintf("bulk-%s", operation), &instanceKey, fmt.Sprintf("success: %t; %s", result.Success, result.Message))
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
@@ -40,6 +40,13 @@ func ApplyClusterAlias(clusterInfo *ClusterInfo) {
 	clusterAliasMapMutex.Unlock()
 }
 
+// GetClusterAlias returns the alias of given cluster, or an empty string when the cluster has no alias
+func GetClusterAlias(clusterName string) string {
+	clusterInfo := &ClusterInfo{ClusterName: clusterName}
+	ApplyClusterAlias(clusterInfo)
+	return clusterInfo.ClusterAlias
+}
+
 // SetClusterAlias will write (and override) a single cluster name mapping
 func SetClusterAlias(clusterName string, alias string) error {
 	err := WriteClusterAlias(clusterName, alias)
@@ -59,10 +59,53 @@ func BeginDowntime(instanceKey *InstanceKey, owner string, reason string, durati
 	}
 
 	AuditOperation("begin-downtime", instanceKey, fmt.Sprintf("owner: %s, reason: %s", owner, reason))
+	PublishInstanceTopologyEvent(EventDowntimeBegun, instanceKey, fmt.Sprintf("owner: %s, reason: %s", owner, reason))
 
 	return nil
 }
 
+// hasLongerDowntime tests whether an instance is downtimed for at least given number of seconds from now
+func hasLongerDowntime(instanceKey *InstanceKey, durationSeconds uint) (bool, error) {
+	hasLonger := false
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return hasLonger, log.Errore(err)
+	}
+
+	err = sqlutils.QueryRowsMap(db, `
+			select 
+				end_timestamp >= NOW() + INTERVAL ? SECOND as has_longer
+			from 
+				database_instance_downtime
+			where
+				hostname = ?
+				and port = ?
+				and downtime_active = 1
+			`, func(m sqlutils.RowMap) error {
+		hasLonger = m.GetBool("has_longer")
+		return nil
+	}, durationSeconds, instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return hasLonger, log.Errore(err)
+	}
+	return hasLonger, nil
+}
+
+// BeginDowntimeUnlessLonger marks an instance as downtimed for given duration, unless it is already downtimed
+// for at least as long. An existing (e.g. manual) downtime is thus never cut short. Returns true when
+// downtime was applied.
+func BeginDowntimeUnlessLonger(instanceKey *InstanceKey, owner string, reason string, durationSeconds uint) (bool, error) {
+	hasLonger, err := hasLongerDowntime(instanceKey, durationSeconds)
+	if err != nil {
+		return false, err
+	}
+	if hasLonger {
+		log.Debugf("BeginDowntimeUnlessLonger: %+v already downtimed for longer than %d seconds", *instanceKey, durationSeconds)
+		return false, nil
+	}
+	return true, BeginDowntime(instanceKey, owner, reason, durationSeconds)
+}
+
 // EndDowntime will remove downtime flag from an instance
 func EndDowntime(instanceKey *InstanceKey) error {
 	db, err := db.OpenOrchestrator()
@@ -89,10 +132,11 @@ func EndDowntime(instanceKey *InstanceKey) error {
 	}
 
 	if affected, _ := res.RowsAffected(); affected == 0 {
-		err = fmt.Errorf("Instance is not in downtime mode: %+v", instanceKey)
+		err = &InactiveStateError{InstanceKey: instanceKey, State: "downtime"}
 	} else {
 		// success
 		AuditOperation("end-downtime", instanceKey, "")
+		PublishInstanceTopologyEvent(EventDowntimeEnded, instanceKey, "")
 	}
 	return err
 }
@@ -140,5 +184,8 @@ func ExpireDowntime() error {
 		}
 	}
 
+	// Activate & expire windows scheduled ahead of time
+	ApplyScheduledMaintenanceWindows(ScheduledDowntime)
+
 	return err
 }
@@ -0,0 +1,282 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/db"
+	"strings"
+)
+
+// FailureDetectionSnapshot is a capture of what an instance was doing at the time a failure was detected
+// on it, or on its master
+type FailureDetectionSnapshot struct {
+	SnapshotId          int64
+	Key                 InstanceKey
+	AnalyzedInstanceKey InstanceKey
+	Analysis            AnalysisCode
+	ClusterName         string
+	CapturedAt          string
+	Processlist         []Process
+	InnoDBStatus        string
+	GlobalStatus        map[string]string
+	CaptureError        string
+}
+
+// CaptureFailureDetectionSnapshot reads processlist, InnoDB status and selected global status off a topology
+// instance. Capture is best effort; errors are noted in the snapshot itself.
+func CaptureFailureDetectionSnapshot(instanceKey *InstanceKey, analysisEntry *ReplicationAnalysis) *FailureDetectionSnapshot {
+	snapshot := &FailureDetectionSnapshot{
+		Key:                 *instanceKey,
+		AnalyzedInstanceKey: analysisEntry.AnalyzedInstanceKey,
+		Analysis:            analysisEntry.Analysis,
+		ClusterName:         analysisEntry.ClusterName,
+		Processlist:         []Process{},
+		GlobalStatus:        make(map[string]string),
+	}
+	captureErrors := []string{}
+	noteError := func(err error) {
+		if err != nil {
+			captureErrors = append(captureErrors, err.Error())
+		}
+	}
+
+	db, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		snapshot.CaptureError = err.Error()
+		return snapshot
+	}
+	err = sqlutils.QueryRowsMap(db, `
+			select 
+				id,
+				user,
+				host,
+				db,
+				command,
+				time,
+				state,
+				left(processlist.info, 1024) as info,
+				now() - interval time second as started_at
+			from 
+				information_schema.processlist 
+			where
+				id != connection_id()
+			order by
+				time desc
+			`,
+		func(m sqlutils.RowMap) error {
+			process := Process{}
+			process.InstanceHostname = instanceKey.Hostname
+			process.InstancePort = instanceKey.Port
+			process.Id = m.GetInt64("id")
+			process.User = m.GetString("user")
+			process.Host = m.GetString("host")
+			process.Db = m.GetString("db")
+			process.Command = m.GetString("command")
+			process.Time = m.GetInt64("time")
+			process.State = m.GetString("state")
+			process.Info = m.GetString("info")
+			process.StartedAt = m.GetString("started_at")
+
+			snapshot.Processlist = append(snapshot.Processlist, process)
+			return nil
+		})
+	noteError(err)
+
+	err = sqlutils.QueryRowsMap(db, "show engine innodb status", func(m sqlutils.RowMap) error {
+		snapshot.InnoDBStatus = m.GetString("Status")
+		return nil
+	})
+	noteError(err)
+
+	statusVariables := make(map[string]bool)
+	for _, variableName := range config.Config.FailureDetectionSnapshotStatusVariables {
+		statusVariables[strings.ToLower(variableName)] = true
+	}
+	err = sqlutils.QueryRowsMap(db, "show global status", func(m sqlutils.RowMap) error {
+		variableName := m.GetString("Variable_name")
+		if statusVariables[strings.ToLower(variableName)] {
+			snapshot.GlobalStatus[variableName] = m.GetString("Value")
+		}
+		return nil
+	})
+	noteError(err)
+
+	snapshot.CaptureError = strings.Join(captureErrors, "; ")
+	return snapshot
+}
+
+// WriteFailureDetectionSnapshot persists a snapshot
+func WriteFailureDetectionSnapshot(snapshot *FailureDetectionSnapshot) error {
+	processlistJSON, err := json.Marshal(snapshot.Processlist)
+	if err != nil {
+		return log.Errore(err)
+	}
+	globalStatusJSON, err := json.Marshal(snapshot.GlobalStatus)
+	if err != nil {
+		return log.Errore(err)
+	}
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			insert 
+				into failure_detection_snapshot (
+					snapshot_id, hostname, port, analyzed_hostname, analyzed_port, analysis, cluster_name,
+					captured_at, processlist, innodb_status, global_status, capture_error
+				) VALUES (
+					NULL, ?, ?, ?, ?, ?, ?, NOW(), ?, ?, ?, ?
+				)
+			`,
+			snapshot.Key.Hostname,
+			snapshot.Key.Port,
+			snapshot.AnalyzedInstanceKey.Hostname,
+			snapshot.AnalyzedInstanceKey.Port,
+			string(snapshot.Analysis),
+			snapshot.ClusterName,
+			string(processlistJSON),
+			snapshot.InnoDBStatus,
+			string(globalStatusJSON),
+			snapshot.CaptureError,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		AuditOperation("failure-detection-snapshot", &snapshot.Key, fmt.Sprintf("captured on %s of %+v", snapshot.Analysis, snapshot.AnalyzedInstanceKey))
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// readFailureDetectionSnapshots reads snapshots by given condition, most recent first. The (potentially large)
+// processlist and InnoDB status are only read when includeContent is true.
+func readFailureDetectionSnapshots(whereCondition string, includeContent bool, page int, args ...interface{}) ([]FailureDetectionSnapshot, error) {
+	res := []FailureDetectionSnapshot{}
+	contentColumns := ``
+	if includeContent {
+		contentColumns = `,
+			processlist,
+			innodb_status`
+	}
+	query := fmt.Sprintf(`
+		select 
+			snapshot_id,
+			hostname,
+			port,
+			analyzed_hostname,
+			analyzed_port,
+			analysis,
+			cluster_name,
+			captured_at,
+			global_status,
+			capture_error%s
+		from 
+			failure_detection_snapshot
+		%s
+		order by
+			snapshot_id desc
+		limit %d
+		offset %d
+		`, contentColumns, whereCondition, config.Config.AuditPageSize, page*config.Config.AuditPageSize)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		snapshot := FailureDetectionSnapshot{}
+		snapshot.SnapshotId = m.GetInt64("snapshot_id")
+		snapshot.Key.Hostname = m.GetString("hostname")
+		snapshot.Key.Port = m.GetInt("port")
+		snapshot.AnalyzedInstanceKey.Hostname = m.GetString("analyzed_hostname")
+		snapshot.AnalyzedInstanceKey.Port = m.GetInt("analyzed_port")
+		snapshot.Analysis = AnalysisCode(m.GetString("analysis"))
+		snapshot.ClusterName = m.GetString("cluster_name")
+		snapshot.CapturedAt = m.GetString("captured_at")
+		snapshot.CaptureError = m.GetString("capture_error")
+		if includeContent {
+			snapshot.InnoDBStatus = m.GetString("innodb_status")
+			if err := json.Unmarshal([]byte(m.GetString("processlist")), &snapshot.Processlist); err != nil {
+				log.Errore(err)
+			}
+		}
+		if err := json.Unmarshal([]byte(m.GetString("global_status")), &snapshot.GlobalStatus); err != nil {
+			log.Errore(err)
+		}
+
+		res = append(res, snapshot)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
+
+// ReadFailureDetectionSnapshots returns a page of most recent snapshots, on all clusters.
+// Processlist and InnoDB status are not included; see ReadFailureDetectionSnapshot.
+func ReadFailureDetectionSnapshots(page int) ([]FailureDetectionSnapshot, error) {
+	return readFailureDetectionSnapshots(``, false, page)
+}
+
+// ReadClusterFailureDetectionSnapshots returns a page of most recent snapshots on given cluster.
+// Processlist and InnoDB status are not included; see ReadFailureDetectionSnapshot.
+func ReadClusterFailureDetectionSnapshots(clusterName string, page int) ([]FailureDetectionSnapshot, error) {
+	return readFailureDetectionSnapshots(`where cluster_name = ?`, false, page, clusterName)
+}
+
+// ReadFailureDetectionSnapshot returns a single snapshot by id, including its processlist and InnoDB status
+func ReadFailureDetectionSnapshot(snapshotId int64) (*FailureDetectionSnapshot, error) {
+	snapshots, err := readFailureDetectionSnapshots(`where snapshot_id = ?`, true, 0, snapshotId)
+	if err != nil {
+		return nil, err
+	}
+	if len(snapshots) == 0 {
+		return nil, fmt.Errorf("Failure detection snapshot not found: %d", snapshotId)
+	}
+	return &snapshots[0], nil
+}
+
+// ExpireFailureDetectionSnapshots purges old snapshots
+func ExpireFailureDetectionSnapshots() error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			delete from failure_detection_snapshot
+				where captured_at < NOW() - INTERVAL ? DAY
+			`, config.Config.FailureDetectionSnapshotExpiryDays,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
@@ -0,0 +1,189 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/outbrain/orchestrator/config"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HostnameResolver normalizes a hostname into the name by which orchestrator identifies an instance.
+// A resolver which has no knowledge of a hostname returns it unchanged.
+type HostnameResolver interface {
+	Resolve(hostname string) (string, error)
+}
+
+var hostnameResolversMutex = &sync.Mutex{}
+var hostnameResolvers = map[string]HostnameResolver{
+	"none":        &noneHostnameResolver{},
+	"default":     &noneHostnameResolver{},
+	"cname":       &cnameHostnameResolver{},
+	"srv":         &srvHostnameResolver{},
+	"reverse-dns": &reverseDNSHostnameResolver{},
+	"static":      &staticHostnameResolver{},
+	"http":        &httpHostnameResolver{},
+}
+
+// RegisterHostnameResolver makes a resolver available under given method name, as selected by HostnameResolveMethod
+func RegisterHostnameResolver(method string, resolver HostnameResolver) {
+	hostnameResolversMutex.Lock()
+	defer hostnameResolversMutex.Unlock()
+
+	hostnameResolvers[strings.ToLower(method)] = resolver
+}
+
+// getHostnameResolver returns the resolver selected by HostnameResolveMethod. Unknown methods do not resolve.
+func getHostnameResolver() HostnameResolver {
+	hostnameResolversMutex.Lock()
+	defer hostnameResolversMutex.Unlock()
+
+	if resolver, found := hostnameResolvers[strings.ToLower(config.Config.HostnameResolveMethod)]; found {
+		return resolver
+	}
+	return hostnameResolvers["none"]
+}
+
+// noneHostnameResolver leaves hostnames as they are
+type noneHostnameResolver struct{}
+
+func (this *noneHostnameResolver) Resolve(hostname string) (string, error) {
+	return hostname, nil
+}
+
+// cnameHostnameResolver resolves a hostname via its CNAME record
+type cnameHostnameResolver struct{}
+
+func (this *cnameHostnameResolver) Resolve(hostname string) (string, error) {
+	return GetCNAME(hostname)
+}
+
+// srvHostnameResolver resolves a service name into the target host of its (first) SRV record
+type srvHostnameResolver struct{}
+
+func (this *srvHostnameResolver) Resolve(hostname string) (string, error) {
+	_, addrs, err := net.LookupSRV("", "", hostname)
+	if err != nil {
+		return hostname, err
+	}
+	if len(addrs) == 0 {
+		return hostname, fmt.Errorf("No SRV records found for %s", hostname)
+	}
+	return strings.TrimRight(addrs[0].Target, "."), nil
+}
+
+// reverseDNSHostnameResolver normalizes a hostname or IP address into the name its address reverse-resolves to
+type reverseDNSHostnameResolver struct{}
+
+func (this *reverseDNSHostnameResolver) Resolve(hostname string) (string, error) {
+	addresses, err := net.LookupHost(hostname)
+	if err != nil {
+		return hostname, err
+	}
+	if len(addresses) == 0 {
+		return hostname, fmt.Errorf("No addresses found for %s", hostname)
+	}
+	names, err := net.LookupAddr(addresses[0])
+	if err != nil {
+		return hostname, err
+	}
+	if len(names) == 0 {
+		return hostname, fmt.Errorf("No reverse DNS names found for %s (%s)", hostname, addresses[0])
+	}
+	return strings.TrimRight(names[0], "."), nil
+}
+
+// staticHostnameResolver maps hostnames via a JSON file (HostnameResolveStaticMapFile) of the form
+// {"hostname": "resolved-hostname", ...}. The file is re-read whenever it changes.
+type staticHostnameResolver struct {
+	mutex       sync.Mutex
+	fileName    string
+	fileModTime time.Time
+	hostnameMap map[string]string
+}
+
+func (this *staticHostnameResolver) Resolve(hostname string) (string, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+
+	fileName := config.Config.HostnameResolveStaticMapFile
+	if fileName == "" {
+		return hostname, fmt.Errorf("HostnameResolveStaticMapFile is not configured")
+	}
+	fileInfo, err := os.Stat(fileName)
+	if err != nil {
+		return hostname, err
+	}
+	if fileName != this.fileName || !fileInfo.ModTime().Equal(this.fileModTime) {
+		contents, err := ioutil.ReadFile(fileName)
+		if err != nil {
+			return hostname, err
+		}
+		hostnameMap := make(map[string]string)
+		if err := json.Unmarshal(contents, &hostnameMap); err != nil {
+			return hostname, fmt.Errorf("Cannot parse %s: %+v", fileName, err)
+		}
+		this.fileName = fileName
+		this.fileModTime = fileInfo.ModTime()
+		this.hostnameMap = hostnameMap
+	}
+	if resolvedHostname, found := this.hostnameMap[hostname]; found {
+		return resolvedHostname, nil
+	}
+	return hostname, nil
+}
+
+// httpHostnameResolver looks up hostnames on an HTTP key/value service, such as Consul's KV store.
+// HostnameResolveHTTPURL has "{hostname}" substituted, e.g. "http://127.0.0.1:8500/v1/kv/mysql/hostnames/{hostname}?raw".
+// The response body is the resolved hostname; a 404 response, or an empty body, leaves the hostname unchanged.
+type httpHostnameResolver struct{}
+
+func (this *httpHostnameResolver) Resolve(hostname string) (string, error) {
+	if config.Config.HostnameResolveHTTPURL == "" {
+		return hostname, fmt.Errorf("HostnameResolveHTTPURL is not configured")
+	}
+	lookupURL := strings.Replace(config.Config.HostnameResolveHTTPURL, "{hostname}", url.PathEscape(hostname), -1)
+	client := &http.Client{Timeout: time.Duration(config.Config.HostnameResolveHTTPTimeoutSeconds) * time.Second}
+	response, err := client.Get(lookupURL)
+	if err != nil {
+		return hostname, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return hostname, nil
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return hostname, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return hostname, fmt.Errorf("Hostname resolve of %s via %s returned status %d", hostname, lookupURL, response.StatusCode)
+	}
+	if resolvedHostname := strings.TrimSpace(string(body)); resolvedHostname != "" {
+		return resolvedHostname, nil
+	}
+	return hostname, nil
+}
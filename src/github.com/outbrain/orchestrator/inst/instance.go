@@ -200,6 +200,43 @@ func (this *InstanceKeyMap) MarshalJSON() ([]byte, error) {
 	return json.Marshal(this.GetInstanceKeys())
 }
 
+// UnmarshalJSON reads this map from a JSON list of keys, as marshalled by MarshalJSON
+func (this *InstanceKeyMap) UnmarshalJSON(b []byte) error {
+	var keys []InstanceKey
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return err
+	}
+	*this = make(InstanceKeyMap)
+	for _, key := range keys {
+		(*this)[key] = true
+	}
+	return nil
+}
+
+// ToCommaDelimitedList returns a comma delimited list of the keys in this map
+func (this *InstanceKeyMap) ToCommaDelimitedList() string {
+	keyDisplays := []string{}
+	for key := range *this {
+		keyDisplays = append(keyDisplays, key.DisplayString())
+	}
+	return strings.Join(keyDisplays, ",")
+}
+
+// ReadCommaDelimitedList parses and adds instance keys from a comma delimited string
+func (this *InstanceKeyMap) ReadCommaDelimitedList(list string) error {
+	if list == "" {
+		return nil
+	}
+	for _, token := range strings.Split(list, ",") {
+		key, err := ParseInstanceKey(token)
+		if err != nil {
+			return err
+		}
+		(*this)[*key] = true
+	}
+	return nil
+}
+
 // Instance represents a database instance, including its current configuration & status.
 // It presents important replication configuration and detailed replication status.
 type Instance struct {
@@ -216,6 +253,7 @@ type Instance struct {
 	Slave_SQL_Running      bool
 	Slave_IO_Running       bool
 	HasReplicationFilters  bool
+	ReplicationFilters     ReplicationFilters
 	UsingOracleGTID        bool
 	UsingMariaDBGTID       bool
 	UsingPseudoGTID        bool
@@ -226,6 +264,14 @@ type Instance struct {
 	LastIOError            string
 	SecondsBehindMaster    sql.NullInt64
 	SQLDelay               uint
+	ReplicationChannels    []ReplicationChannel
+
+	SemiSyncMasterEnabled           bool
+	SemiSyncSlaveEnabled            bool
+	SemiSyncMasterStatus            bool
+	SemiSyncSlaveStatus             bool
+	SemiSyncMasterClients           uint
+	SemiSyncMasterWaitForSlaveCount uint
 
 	SlaveLagSeconds     sql.NullInt64
 	SlaveHosts          InstanceKeyMap
@@ -241,7 +287,10 @@ type Instance struct {
 	SecondsSinceLastSeen sql.NullInt64
 	CountMySQLSnapshots  int
 
-	IsCandidate bool
+	IsCandidate   bool
+	PromotionRule CandidatePromotionRule
+
+	Tags map[string]string
 }
 
 // NewInstance creates a new, empty instance
@@ -300,6 +349,31 @@ func (this *Instance) SlaveRunning() bool {
 	return this.IsSlave() && this.Slave_SQL_Running && this.Slave_IO_Running
 }
 
+// IsReplicatingSemiSyncSlave returns true when this slave has semi-sync enabled and its IO thread running
+func (this *Instance) IsReplicatingSemiSyncSlave() bool {
+	return this.SemiSyncSlaveEnabled && this.Slave_IO_Running
+}
+
+// semiSyncRequiredSlaves returns the number of semi-sync slaves a semi-sync master with given
+// rpl_semi_sync_master_wait_for_slave_count is expected to have attached; MinSemiSyncSlaves if greater.
+func semiSyncRequiredSlaves(waitForSlaveCount uint) uint {
+	requiredSlaves := waitForSlaveCount
+	if requiredSlaves < config.Config.MinSemiSyncSlaves {
+		requiredSlaves = config.Config.MinSemiSyncSlaves
+	}
+	if requiredSlaves == 0 {
+		// Pre 5.7 semi-sync acknowledges a single slave
+		requiredSlaves = 1
+	}
+	return requiredSlaves
+}
+
+// SemiSyncRequiredSlaves returns the number of semi-sync slaves this instance, as a semi-sync master, is expected
+// to have attached, see semiSyncRequiredSlaves.
+func (this *Instance) SemiSyncRequiredSlaves() uint {
+	return semiSyncRequiredSlaves(this.SemiSyncMasterWaitForSlaveCount)
+}
+
 // SQLThreadUpToDate returns true when the instance had consumed all relay logs.
 func (this *Instance) SQLThreadUpToDate() bool {
 	return this.ReadBinlogCoordinates.Equals(&this.ExecBinlogCoordinates)
@@ -378,8 +452,8 @@ func (this *Instance) CanReplicateFrom(other *Instance) (bool, error) {
 		}
 	}
 	if config.Config.VerifyReplicationFilters {
-		if other.HasReplicationFilters && !this.HasReplicationFilters {
-			return false, fmt.Errorf("%+v has replication filters", other.Key)
+		if other.HasReplicationFilters && !this.ReplicationFilters.Equals(&other.ReplicationFilters) {
+			return false, fmt.Errorf("%+v has replication filters which differ from those of %+v: %s", other.Key, this.Key, strings.Join(other.ReplicationFilters.Diff(&this.ReplicationFilters), "; "))
 		}
 	}
 	if this.ServerID == other.ServerID {
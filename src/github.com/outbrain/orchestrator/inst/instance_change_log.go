@@ -0,0 +1,224 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// InstanceChange is a recorded transition of a single instance attribute, e.g. a version upgrade or
+// a change of binlog_format, as detected between two consecutive polls of the instance
+type InstanceChange struct {
+	ChangeId        int64
+	Key             InstanceKey
+	ClusterName     string
+	ChangeTimestamp string
+	AttributeName   string
+	OldValue        string
+	NewValue        string
+}
+
+// readInstanceChangeBaseline reads the tracked attributes of an instance as last written to the backend database
+func readInstanceChangeBaseline(instanceKey *InstanceKey) (*Instance, bool, error) {
+	instance := NewInstance()
+	instanceFound := false
+	query := `
+		select
+			server_id,
+			version,
+			read_only,
+			binlog_format,
+			log_slave_updates,
+			master_host,
+			master_port,
+			slave_sql_running,
+			slave_io_running
+		from
+			database_instance
+		where
+			hostname = ?
+			and port = ?
+		`
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return instance, false, log.Errore(err)
+	}
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		instance.ServerID = m.GetUint("server_id")
+		instance.Version = m.GetString("version")
+		instance.ReadOnly = m.GetBool("read_only")
+		instance.Binlog_format = m.GetString("binlog_format")
+		instance.LogSlaveUpdatesEnabled = m.GetBool("log_slave_updates")
+		instance.MasterKey.Hostname = m.GetString("master_host")
+		instance.MasterKey.Port = m.GetInt("master_port")
+		instance.Slave_SQL_Running = m.GetBool("slave_sql_running")
+		instance.Slave_IO_Running = m.GetBool("slave_io_running")
+		instanceFound = true
+		return nil
+	}, instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return instance, false, log.Errore(err)
+	}
+	return instance, instanceFound, nil
+}
+
+// diffInstanceChanges lists the tracked attributes which differ between a previous and a current reading of an instance
+func diffInstanceChanges(previous *Instance, instance *Instance) []InstanceChange {
+	changes := []InstanceChange{}
+	compare := func(attributeName string, oldValue string, newValue string) {
+		if oldValue != newValue {
+			changes = append(changes, InstanceChange{
+				Key:           instance.Key,
+				ClusterName:   instance.ClusterName,
+				AttributeName: attributeName,
+				OldValue:      oldValue,
+				NewValue:      newValue,
+			})
+		}
+	}
+	masterDisplayString := func(masterKey InstanceKey) string {
+		if masterKey.Hostname == "" || masterKey.Hostname == "_" {
+			return ""
+		}
+		return masterKey.DisplayString()
+	}
+	compare("master", masterDisplayString(previous.MasterKey), masterDisplayString(instance.MasterKey))
+	compare("read_only", fmt.Sprintf("%t", previous.ReadOnly), fmt.Sprintf("%t", instance.ReadOnly))
+	compare("version", previous.Version, instance.Version)
+	compare("binlog_format", previous.Binlog_format, instance.Binlog_format)
+	compare("log_slave_updates", fmt.Sprintf("%t", previous.LogSlaveUpdatesEnabled), fmt.Sprintf("%t", instance.LogSlaveUpdatesEnabled))
+	compare("server_id", fmt.Sprintf("%d", previous.ServerID), fmt.Sprintf("%d", instance.ServerID))
+	compare("slave_sql_running", fmt.Sprintf("%t", previous.Slave_SQL_Running), fmt.Sprintf("%t", instance.Slave_SQL_Running))
+	compare("slave_io_running", fmt.Sprintf("%t", previous.Slave_IO_Running), fmt.Sprintf("%t", instance.Slave_IO_Running))
+	return changes
+}
+
+// writeInstanceChanges appends given changes to the instance change log
+func writeInstanceChanges(changes []InstanceChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+	for _, change := range changes {
+		_, err = sqlutils.Exec(db, `
+			insert 
+				into database_instance_change_log (
+					change_id, hostname, port, cluster_name, change_timestamp, attribute_name, old_value, new_value
+				) VALUES (
+					NULL, ?, ?, ?, NOW(), ?, ?, ?
+				)
+			`,
+			change.Key.Hostname,
+			change.Key.Port,
+			change.ClusterName,
+			change.AttributeName,
+			change.OldValue,
+			change.NewValue,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		log.Infof("Instance change on %+v: %s changed from %s to %s", change.Key, change.AttributeName, change.OldValue, change.NewValue)
+	}
+	return nil
+}
+
+// readInstanceChanges reads change log entries by given condition, most recent first
+func readInstanceChanges(whereCondition string, page int, args ...interface{}) ([]InstanceChange, error) {
+	res := []InstanceChange{}
+	query := fmt.Sprintf(`
+		select 
+			change_id,
+			hostname,
+			port,
+			cluster_name,
+			change_timestamp,
+			attribute_name,
+			old_value,
+			new_value
+		from 
+			database_instance_change_log
+		%s
+		order by
+			change_id desc
+		limit %d
+		offset %d
+		`, whereCondition, config.Config.AuditPageSize, page*config.Config.AuditPageSize)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		change := InstanceChange{}
+		change.ChangeId = m.GetInt64("change_id")
+		change.Key.Hostname = m.GetString("hostname")
+		change.Key.Port = m.GetInt("port")
+		change.ClusterName = m.GetString("cluster_name")
+		change.ChangeTimestamp = m.GetString("change_timestamp")
+		change.AttributeName = m.GetString("attribute_name")
+		change.OldValue = m.GetString("old_value")
+		change.NewValue = m.GetString("new_value")
+
+		res = append(res, change)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
+
+// ReadInstanceChanges returns a page of most recent changes on given instance, optionally of a given attribute only
+func ReadInstanceChanges(instanceKey *InstanceKey, attributeName string, page int) ([]InstanceChange, error) {
+	return readInstanceChanges(`where hostname = ? and port = ? and ? in ('', attribute_name)`, page, instanceKey.Hostname, instanceKey.Port, attributeName)
+}
+
+// ReadClusterInstanceChanges returns a page of most recent changes on instances of given cluster, optionally of a given attribute only
+func ReadClusterInstanceChanges(clusterName string, attributeName string, page int) ([]InstanceChange, error) {
+	return readInstanceChanges(`where cluster_name = ? and ? in ('', attribute_name)`, page, clusterName, attributeName)
+}
+
+// ExpireInstanceChanges purges old change log entries
+func ExpireInstanceChanges() error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			delete from database_instance_change_log
+				where change_timestamp < NOW() - INTERVAL ? DAY
+			`, config.Config.InstanceChangeLogExpiryDays,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
@@ -199,6 +199,32 @@ func ReadTopologyInstance(instanceKey *InstanceKey) (*Instance, error) {
 	}
 
 	err = sqlutils.QueryRowsMap(db, "show slave status", func(m sqlutils.RowMap) error {
+		channel := ReplicationChannel{ChannelName: m.GetStringD("Channel_Name", "")}
+		channel.Slave_IO_Running = (m.GetString("Slave_IO_Running") == "Yes")
+		channel.Slave_SQL_Running = (m.GetString("Slave_SQL_Running") == "Yes")
+		channel.ReadBinlogCoordinates.LogFile = m.GetString("Master_Log_File")
+		channel.ReadBinlogCoordinates.LogPos = m.GetInt64("Read_Master_Log_Pos")
+		channel.ExecBinlogCoordinates.LogFile = m.GetString("Relay_Master_Log_File")
+		channel.ExecBinlogCoordinates.LogPos = m.GetInt64("Exec_Master_Log_Pos")
+		channel.LastSQLError = m.GetString("Last_SQL_Error")
+		channel.LastIOError = m.GetString("Last_IO_Error")
+		channel.SecondsBehindMaster = m.GetNullInt64("Seconds_Behind_Master")
+		channelMasterKey, err := NewInstanceKeyFromStrings(m.GetString("Master_Host"), m.GetString("Master_Port"))
+		if err != nil {
+			log.Errore(err)
+		}
+		channelMasterKey.Hostname, resolveErr = ResolveHostname(channelMasterKey.Hostname)
+		if resolveErr != nil {
+			log.Errore(resolveErr)
+		}
+		channel.MasterKey = *channelMasterKey
+		instance.ReplicationChannels = append(instance.ReplicationChannels, channel)
+		if len(instance.ReplicationChannels) > 1 {
+			// Multi-source replication: the default (first listed) channel makes for the instance's master.
+			// Other channels are only recorded as such.
+			return nil
+		}
+
 		instance.Slave_IO_Running = (m.GetString("Slave_IO_Running") == "Yes")
 		instance.Slave_SQL_Running = (m.GetString("Slave_SQL_Running") == "Yes")
 		instance.ReadBinlogCoordinates.LogFile = m.GetString("Master_Log_File")
@@ -213,7 +239,8 @@ func ReadTopologyInstance(instanceKey *InstanceKey) (*Instance, error) {
 		instance.SQLDelay = m.GetUintD("SQL_Delay", 0)
 		instance.UsingOracleGTID = (m.GetIntD("Auto_Position", 0) == 1)
 		instance.UsingMariaDBGTID = (m.GetStringD("Using_Gtid", "No") != "No")
-		instance.HasReplicationFilters = ((m.GetStringD("Replicate_Do_DB", "") != "") || (m.GetStringD("Replicate_Ignore_DB", "") != "") || (m.GetStringD("Replicate_Do_Table", "") != "") || (m.GetStringD("Replicate_Ignore_Table", "") != "") || (m.GetStringD("Replicate_Wild_Do_Table", "") != "") || (m.GetStringD("Replicate_Wild_Ignore_Table", "") != ""))
+		instance.ReplicationFilters = NewReplicationFilters(m.GetStringD("Replicate_Do_DB", ""), m.GetStringD("Replicate_Ignore_DB", ""), m.GetStringD("Replicate_Do_Table", ""), m.GetStringD("Replicate_Ignore_Table", ""), m.GetStringD("Replicate_Wild_Do_Table", ""), m.GetStringD("Replicate_Wild_Ignore_Table", ""))
+		instance.HasReplicationFilters = !instance.ReplicationFilters.IsEmpty()
 
 		masterKey, err := NewInstanceKeyFromStrings(m.GetString("Master_Host"), m.GetString("Master_Port"))
 		if err != nil {
@@ -355,6 +382,38 @@ func ReadTopologyInstance(instanceKey *InstanceKey) (*Instance, error) {
 		}
 	}
 
+	if !isMaxScale {
+		// Semi-sync plugins may not be installed, in which case these simply yield no rows
+		err = sqlutils.QueryRowsMap(db, "show global variables like 'rpl_semi_sync_%'", func(m sqlutils.RowMap) error {
+			switch m.GetString("Variable_name") {
+			case "rpl_semi_sync_master_enabled":
+				instance.SemiSyncMasterEnabled = (m.GetString("Value") == "ON")
+			case "rpl_semi_sync_slave_enabled":
+				instance.SemiSyncSlaveEnabled = (m.GetString("Value") == "ON")
+			case "rpl_semi_sync_master_wait_for_slave_count":
+				instance.SemiSyncMasterWaitForSlaveCount = m.GetUint("Value")
+			}
+			return nil
+		})
+		if err != nil {
+			log.Errore(err)
+		}
+		err = sqlutils.QueryRowsMap(db, "show global status like 'rpl_semi_sync_%'", func(m sqlutils.RowMap) error {
+			switch m.GetString("Variable_name") {
+			case "Rpl_semi_sync_master_status":
+				instance.SemiSyncMasterStatus = (m.GetString("Value") == "ON")
+			case "Rpl_semi_sync_slave_status":
+				instance.SemiSyncSlaveStatus = (m.GetString("Value") == "ON")
+			case "Rpl_semi_sync_master_clients":
+				instance.SemiSyncMasterClients = m.GetUint("Value")
+			}
+			return nil
+		})
+		if err != nil {
+			log.Errore(err)
+		}
+	}
+
 	if config.Config.SlaveLagQuery != "" && !isMaxScale {
 		err := db.QueryRow(config.Config.SlaveLagQuery).Scan(&instance.SlaveLagSeconds)
 		if err != nil {
@@ -389,6 +448,7 @@ Cleanup:
 		WriteLongRunningProcesses(&instance.Key, longRunningProcesses)
 	} else {
 		_ = UpdateInstanceLastChecked(&instance.Key)
+		detectInstanceUnreachableEvent(&instance.Key)
 	}
 	if err != nil {
 		log.Errore(err)
@@ -458,6 +518,7 @@ func readInstanceRow(m sqlutils.RowMap) *Instance {
 	instance.Slave_SQL_Running = m.GetBool("slave_sql_running")
 	instance.Slave_IO_Running = m.GetBool("slave_io_running")
 	instance.HasReplicationFilters = m.GetBool("has_replication_filters")
+	instance.ReplicationFilters = NewReplicationFilters(m.GetString("replicate_do_db"), m.GetString("replicate_ignore_db"), m.GetString("replicate_do_table"), m.GetString("replicate_ignore_table"), m.GetString("replicate_wild_do_table"), m.GetString("replicate_wild_ignore_table"))
 	instance.UsingOracleGTID = m.GetBool("oracle_gtid")
 	instance.UsingMariaDBGTID = m.GetBool("mariadb_gtid")
 	instance.UsingPseudoGTID = m.GetBool("pseudo_gtid")
@@ -481,16 +542,41 @@ func readInstanceRow(m sqlutils.RowMap) *Instance {
 	instance.PhysicalEnvironment = m.GetString("physical_environment")
 	instance.ReplicationDepth = m.GetUint("replication_depth")
 	instance.IsCoMaster = m.GetBool("is_co_master")
+	instance.SemiSyncMasterEnabled = m.GetBool("semi_sync_master_enabled")
+	instance.SemiSyncSlaveEnabled = m.GetBool("semi_sync_slave_enabled")
+	instance.SemiSyncMasterStatus = m.GetBool("semi_sync_master_status")
+	instance.SemiSyncSlaveStatus = m.GetBool("semi_sync_slave_status")
+	instance.SemiSyncMasterClients = m.GetUint("semi_sync_master_clients")
+	instance.SemiSyncMasterWaitForSlaveCount = m.GetUint("semi_sync_master_wait_for_slave_count")
 	instance.IsUpToDate = (m.GetUint("seconds_since_last_checked") <= config.Config.InstancePollSeconds)
 	instance.IsRecentlyChecked = (m.GetUint("seconds_since_last_checked") <= config.Config.InstancePollSeconds*5)
 	instance.IsLastCheckValid = m.GetBool("is_last_check_valid")
 	instance.SecondsSinceLastSeen = m.GetNullInt64("seconds_since_last_seen")
 	instance.IsCandidate = m.GetBool("is_candidate")
+	instance.PromotionRule = CandidatePromotionRule(m.GetString("registered_promotion_rule"))
+	if instance.PromotionRule == "" {
+		instance.PromotionRule = NeutralPromoteRule
+		if instance.IsCandidate {
+			// Legacy candidate registration implies a preference
+			instance.PromotionRule = PreferPromoteRule
+		}
+	}
 
 	instance.ReadSlaveHostsFromJson(slaveHostsJSON)
 	return instance
 }
 
+// instanceKeysCondition returns a condition matching the hostname & port of given instances, along with its args
+func instanceKeysCondition(instances [](*Instance)) (string, []interface{}) {
+	placeholders := []string{}
+	args := []interface{}{}
+	for _, instance := range instances {
+		placeholders = append(placeholders, "(?, ?)")
+		args = append(args, instance.Key.Hostname, instance.Key.Port)
+	}
+	return fmt.Sprintf("(hostname, port) in (%s)", strings.Join(placeholders, ", ")), args
+}
+
 // readInstancesByCondition is a generic function to read instances from the backend database
 func readInstancesByCondition(condition string) ([](*Instance), error) {
 	readFunc := func() ([](*Instance), error) {
@@ -507,10 +593,12 @@ func readInstancesByCondition(condition string) ([](*Instance), error) {
 			timestampdiff(second, last_checked, now()) as seconds_since_last_checked,
 			(last_checked <= last_seen) is true as is_last_check_valid,
 			timestampdiff(second, last_seen, now()) as seconds_since_last_seen,
-			candidate_database_instance.last_suggested is not null as is_candidate 
+			candidate_database_instance.last_suggested is not null as is_candidate,
+			ifnull(database_instance_promotion_rule.promotion_rule, '') as registered_promotion_rule
 		from 
 			database_instance 
 			left join candidate_database_instance using (hostname, port)
+			left join database_instance_promotion_rule using (hostname, port)
 		where
 			%s
 		order by
@@ -528,6 +616,14 @@ func readInstancesByCondition(condition string) ([](*Instance), error) {
 		if err != nil {
 			return instances, log.Errore(err)
 		}
+		err = readReplicationChannels(instances)
+		if err != nil {
+			return instances, log.Errore(err)
+		}
+		err = readInstancesTags(instances)
+		if err != nil {
+			return instances, log.Errore(err)
+		}
 		return instances, err
 	}
 	instanceReadChan <- true
@@ -628,6 +724,16 @@ func filterOSCInstances(instances [](*Instance)) [](*Instance) {
 		if instance.IsMaxScale() {
 			skipThisHost = true
 		}
+		if config.Config.OSCTagSelector != "" {
+			tagSelector, err := ParseTagSelector(config.Config.OSCTagSelector)
+			if err != nil {
+				// A malformed selector matches no instance
+				log.Errorf("Invalid OSCTagSelector %s: %+v", config.Config.OSCTagSelector, err)
+				skipThisHost = true
+			} else if !tagSelector.Matches(instance) {
+				skipThisHost = true
+			}
+		}
 
 		if !instance.IsLastCheckValid {
 			skipThisHost = true
@@ -1102,6 +1208,14 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 			return log.Errore(err)
 		}
 
+		// Changes are detected against the instance as last written, before it is overwritten
+		var previous *Instance
+		if instanceWasActuallyFound && lastError == nil {
+			if baseline, found, err := readInstanceChangeBaseline(&instance.Key); err == nil && found {
+				previous = baseline
+			}
+		}
+
 		insertIgnore := ""
 		onDuplicateKeyUpdate := ""
 		if instanceWasActuallyFound {
@@ -1124,6 +1238,12 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 					slave_sql_running=VALUES(slave_sql_running),
 					slave_io_running=VALUES(slave_io_running),
 					has_replication_filters=VALUES(has_replication_filters),
+					replicate_do_db=VALUES(replicate_do_db),
+					replicate_ignore_db=VALUES(replicate_ignore_db),
+					replicate_do_table=VALUES(replicate_do_table),
+					replicate_ignore_table=VALUES(replicate_ignore_table),
+					replicate_wild_do_table=VALUES(replicate_wild_do_table),
+					replicate_wild_ignore_table=VALUES(replicate_wild_ignore_table),
 					oracle_gtid=VALUES(oracle_gtid),
 					mariadb_gtid=VALUES(mariadb_gtid),
 					pseudo_gtid=values(pseudo_gtid),
@@ -1144,7 +1264,13 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 					data_center=VALUES(data_center),
 					physical_environment=values(physical_environment),
 					replication_depth=VALUES(replication_depth),
-					is_co_master=VALUES(is_co_master)
+					is_co_master=VALUES(is_co_master),
+					semi_sync_master_enabled=VALUES(semi_sync_master_enabled),
+					semi_sync_slave_enabled=VALUES(semi_sync_slave_enabled),
+					semi_sync_master_status=VALUES(semi_sync_master_status),
+					semi_sync_slave_status=VALUES(semi_sync_slave_status),
+					semi_sync_master_clients=VALUES(semi_sync_master_clients),
+					semi_sync_master_wait_for_slave_count=VALUES(semi_sync_master_wait_for_slave_count)
 				`
 		} else {
 			// Scenario: some slave reported a master of his; but the master cannot be contacted.
@@ -1172,6 +1298,12 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 				slave_sql_running,
 				slave_io_running,
 				has_replication_filters,
+				replicate_do_db,
+				replicate_ignore_db,
+				replicate_do_table,
+				replicate_ignore_table,
+				replicate_wild_do_table,
+				replicate_wild_ignore_table,
 				oracle_gtid,
 				mariadb_gtid,
 				pseudo_gtid,
@@ -1192,8 +1324,14 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 				data_center,
 				physical_environment,
 				replication_depth,
-				is_co_master
-			) values (?, ?, NOW(), NOW(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				is_co_master,
+				semi_sync_master_enabled,
+				semi_sync_slave_enabled,
+				semi_sync_master_status,
+				semi_sync_slave_status,
+				semi_sync_master_clients,
+				semi_sync_master_wait_for_slave_count
+			) values (?, ?, NOW(), NOW(), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 			%s
 			`, insertIgnore, onDuplicateKeyUpdate)
 
@@ -1214,6 +1352,12 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 			instance.Slave_SQL_Running,
 			instance.Slave_IO_Running,
 			instance.HasReplicationFilters,
+			strings.Join(instance.ReplicationFilters.DoDB, ","),
+			strings.Join(instance.ReplicationFilters.IgnoreDB, ","),
+			strings.Join(instance.ReplicationFilters.DoTable, ","),
+			strings.Join(instance.ReplicationFilters.IgnoreTable, ","),
+			strings.Join(instance.ReplicationFilters.WildDoTable, ","),
+			strings.Join(instance.ReplicationFilters.WildIgnoreTable, ","),
 			instance.UsingOracleGTID,
 			instance.UsingMariaDBGTID,
 			instance.UsingPseudoGTID,
@@ -1235,6 +1379,12 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
 			instance.PhysicalEnvironment,
 			instance.ReplicationDepth,
 			instance.IsCoMaster,
+			instance.SemiSyncMasterEnabled,
+			instance.SemiSyncSlaveEnabled,
+			instance.SemiSyncMasterStatus,
+			instance.SemiSyncSlaveStatus,
+			instance.SemiSyncMasterClients,
+			instance.SemiSyncMasterWaitForSlaveCount,
 		)
 		if err != nil {
 			return log.Errore(err)
@@ -1245,6 +1395,13 @@ func writeInstance(instance *Instance, instanceWasActuallyFound bool, lastError
         	update database_instance set last_seen = NOW() where hostname=? and port=?
         	`, instance.Key.Hostname, instance.Key.Port,
 			)
+			writeReplicationChannels(instance)
+			var instanceChanges []InstanceChange
+			if previous != nil {
+				instanceChanges = diffInstanceChanges(previous, instance)
+				writeInstanceChanges(instanceChanges)
+			}
+			publishInstanceEvents(previous, instance, instanceChanges)
 		} else {
 			log.Debugf("writeInstance: will not update database_instance due to error: %+v", lastError)
 		}
@@ -1333,6 +1490,7 @@ func ForgetInstance(instanceKey *InstanceKey) error {
 		instanceKey.Hostname,
 		instanceKey.Port,
 	)
+	forgetInstanceEventState(instanceKey)
 	AuditOperation("forget", instanceKey, "")
 	return err
 }
@@ -1498,6 +1656,11 @@ func StopSlavesNicely(slaves [](*Instance), timeout time.Duration) [](*Instance)
 
 // StopSlave stops replication on a given instance
 func StopSlave(instanceKey *InstanceKey) (*Instance, error) {
+	return StopSlaveChannel(instanceKey, "")
+}
+
+// StopSlaveChannel stops replication on a given instance, limited to given channel when not empty
+func StopSlaveChannel(instanceKey *InstanceKey, channelName string) (*Instance, error) {
 	instance, err := ReadTopologyInstance(instanceKey)
 	if err != nil {
 		return instance, log.Errore(err)
@@ -1506,7 +1669,12 @@ func StopSlave(instanceKey *InstanceKey) (*Instance, error) {
 	if !instance.IsSlave() {
 		return instance, fmt.Errorf("instance is not a slave: %+v", instanceKey)
 	}
-	_, err = ExecInstanceNoPrepare(instanceKey, `stop slave`)
+	if channelName != "" {
+		if _, err := instance.GetReplicationChannel(channelName); err != nil {
+			return instance, err
+		}
+	}
+	_, err = ExecInstanceNoPrepare(instanceKey, `stop slave`+forChannelClause(channelName))
 	if err != nil {
 		return instance, log.Errore(err)
 	}
@@ -1518,6 +1686,11 @@ func StopSlave(instanceKey *InstanceKey) (*Instance, error) {
 
 // StartSlave starts replication on a given instance
 func StartSlave(instanceKey *InstanceKey) (*Instance, error) {
+	return StartSlaveChannel(instanceKey, "")
+}
+
+// StartSlaveChannel starts replication on a given instance, limited to given channel when not empty
+func StartSlaveChannel(instanceKey *InstanceKey, channelName string) (*Instance, error) {
 	instance, err := ReadTopologyInstance(instanceKey)
 	if err != nil {
 		return instance, log.Errore(err)
@@ -1526,8 +1699,13 @@ func StartSlave(instanceKey *InstanceKey) (*Instance, error) {
 	if !instance.IsSlave() {
 		return instance, fmt.Errorf("instance is not a slave: %+v", instanceKey)
 	}
+	if channelName != "" {
+		if _, err := instance.GetReplicationChannel(channelName); err != nil {
+			return instance, err
+		}
+	}
 
-	_, err = ExecInstanceNoPrepare(instanceKey, `start slave`)
+	_, err = ExecInstanceNoPrepare(instanceKey, `start slave`+forChannelClause(channelName))
 	if err != nil {
 		return instance, log.Errore(err)
 	}
@@ -1610,13 +1788,48 @@ func StartSlaveUntilMasterCoordinates(instanceKey *InstanceKey, masterCoordinate
 
 // ChangeMasterTo changes the given instance's master according to given input.
 func ChangeMasterTo(instanceKey *InstanceKey, masterKey *InstanceKey, masterBinlogCoordinates *BinlogCoordinates) (*Instance, error) {
+	return ChangeMasterToChannel(instanceKey, masterKey, masterBinlogCoordinates, "")
+}
+
+// masterSSLClause returns the MASTER_SSL options to add to a CHANGE MASTER TO statement, as configured.
+// Empty when ReplicationUseSSL is disabled, in which case a slave's existing SSL settings are kept.
+func masterSSLClause() string {
+	if !config.Config.ReplicationUseSSL {
+		return ""
+	}
+	quote := func(value string) string {
+		return strings.Replace(value, "'", "\\'", -1)
+	}
+	clause := ", master_ssl=1"
+	if config.Config.ReplicationSSLCAFile != "" {
+		clause += fmt.Sprintf(", master_ssl_ca='%s'", quote(config.Config.ReplicationSSLCAFile))
+	}
+	if config.Config.ReplicationSSLCertFile != "" {
+		clause += fmt.Sprintf(", master_ssl_cert='%s'", quote(config.Config.ReplicationSSLCertFile))
+	}
+	if config.Config.ReplicationSSLPrivateKeyFile != "" {
+		clause += fmt.Sprintf(", master_ssl_key='%s'", quote(config.Config.ReplicationSSLPrivateKeyFile))
+	}
+	if config.Config.ReplicationSSLVerifyServerCert {
+		clause += ", master_ssl_verify_server_cert=1"
+	}
+	return clause
+}
+
+// ChangeMasterToChannel changes the master of given channel on a given instance; the default channel when empty.
+// Replication on that channel is expected to be stopped.
+func ChangeMasterToChannel(instanceKey *InstanceKey, masterKey *InstanceKey, masterBinlogCoordinates *BinlogCoordinates, channelName string) (*Instance, error) {
 	instance, err := ReadTopologyInstance(instanceKey)
 	if err != nil {
 		return instance, log.Errore(err)
 	}
 
-	if instance.SlaveRunning() {
-		return instance, fmt.Errorf("Cannot change master on: %+v because slave is running", *instanceKey)
+	if channelName == "" {
+		if instance.SlaveRunning() {
+			return instance, fmt.Errorf("Cannot change master on: %+v because slave is running", *instanceKey)
+		}
+	} else if channel, err := instance.GetReplicationChannel(channelName); err == nil && channel.SlaveRunning() {
+		return instance, fmt.Errorf("Cannot change master on: %+v channel %s because slave is running", *instanceKey, channelName)
 	}
 	unresolvedMasterKey, err := UnresolveHostname(masterKey)
 	if err != nil {
@@ -1629,13 +1842,13 @@ func ChangeMasterTo(instanceKey *InstanceKey, masterKey *InstanceKey, masterBinl
 
 	if instance.UsingMariaDBGTID {
 		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d",
-			unresolvedMasterKey.Hostname, unresolvedMasterKey.Port))
+			unresolvedMasterKey.Hostname, unresolvedMasterKey.Port)+masterSSLClause()+forChannelClause(channelName))
 	} else {
 		// MariaDB has a bug: a CHANGE MASTER TO statement does not work properly with prepared statement... :P
 		// See https://mariadb.atlassian.net/browse/MDEV-7640
 		// This is the reason for ExecInstanceNoPrepare
 		_, err = ExecInstanceNoPrepare(instanceKey, fmt.Sprintf("change master to master_host='%s', master_port=%d, master_log_file='%s', master_log_pos=%d",
-			unresolvedMasterKey.Hostname, unresolvedMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos))
+			unresolvedMasterKey.Hostname, unresolvedMasterKey.Port, masterBinlogCoordinates.LogFile, masterBinlogCoordinates.LogPos)+masterSSLClause()+forChannelClause(channelName))
 	}
 	if err != nil {
 		return instance, log.Errore(err)
@@ -1814,6 +2027,61 @@ func SetReadOnly(instanceKey *InstanceKey, readOnly bool) (*Instance, error) {
 	return instance, err
 }
 
+// SetSemiSyncMaster enables or disables semi-sync replication on the master side of given instance
+func SetSemiSyncMaster(instanceKey *InstanceKey, enabled bool) (*Instance, error) {
+	instance, err := ReadTopologyInstance(instanceKey)
+	if err != nil {
+		return instance, log.Errore(err)
+	}
+
+	if *config.RuntimeCLIFlags.Noop {
+		return instance, fmt.Errorf("noop: aborting set-semi-sync-master operation on %+v; signalling error but nothing went wrong.", *instanceKey)
+	}
+
+	_, err = ExecInstance(instanceKey, fmt.Sprintf("set global rpl_semi_sync_master_enabled = %t", enabled))
+	if err != nil {
+		return instance, log.Errore(err)
+	}
+	instance, err = ReadTopologyInstance(instanceKey)
+
+	log.Infof("instance %+v rpl_semi_sync_master_enabled: %t", instanceKey, enabled)
+	AuditOperation("semi-sync-master", instanceKey, fmt.Sprintf("set as %t", enabled))
+
+	return instance, err
+}
+
+// SetSemiSyncSlave enables or disables semi-sync replication on the slave side of given instance.
+// A running IO thread is restarted for the change to take effect.
+func SetSemiSyncSlave(instanceKey *InstanceKey, enabled bool) (*Instance, error) {
+	instance, err := ReadTopologyInstance(instanceKey)
+	if err != nil {
+		return instance, log.Errore(err)
+	}
+
+	if *config.RuntimeCLIFlags.Noop {
+		return instance, fmt.Errorf("noop: aborting set-semi-sync-slave operation on %+v; signalling error but nothing went wrong.", *instanceKey)
+	}
+
+	_, err = ExecInstance(instanceKey, fmt.Sprintf("set global rpl_semi_sync_slave_enabled = %t", enabled))
+	if err != nil {
+		return instance, log.Errore(err)
+	}
+	if instance.Slave_IO_Running {
+		if _, err := ExecInstanceNoPrepare(instanceKey, `stop slave io_thread`); err != nil {
+			return instance, log.Errore(err)
+		}
+		if _, err := ExecInstanceNoPrepare(instanceKey, `start slave io_thread`); err != nil {
+			return instance, log.Errore(err)
+		}
+	}
+	instance, err = ReadTopologyInstance(instanceKey)
+
+	log.Infof("instance %+v rpl_semi_sync_slave_enabled: %t", instanceKey, enabled)
+	AuditOperation("semi-sync-slave", instanceKey, fmt.Sprintf("set as %t", enabled))
+
+	return instance, err
+}
+
 // KillQuery stops replication on a given instance
 func KillQuery(instanceKey *InstanceKey, process int64) (*Instance, error) {
 	instance, err := ReadTopologyInstance(instanceKey)
@@ -1961,7 +2229,11 @@ func ExpireCandidateInstances() error {
 func ReadClusterCandidateInstances(clusterName string) ([](*Instance), error) {
 	condition := fmt.Sprintf(`
 			cluster_name = '%s'
-			and (hostname, port) in (select hostname, port from candidate_database_instance)
-			`, clusterName)
+			and (
+				(hostname, port) in (select hostname, port from candidate_database_instance)
+				or (hostname, port) in (select hostname, port from database_instance_promotion_rule where promotion_rule = '%s')
+			)
+			and (hostname, port) not in (select hostname, port from database_instance_promotion_rule where promotion_rule in ('%s', '%s'))
+			`, clusterName, PreferPromoteRule, PreferNotPromoteRule, MustNotPromoteRule)
 	return readInstancesByCondition(condition)
 }
@@ -17,14 +17,21 @@
 package inst
 
 import (
+	"fmt"
 	"github.com/outbrain/orchestrator/config"
-	"github.com/outbrain/orchestrator/inst"
 	. "gopkg.in/check.v1"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
+	"time"
 )
 
 func init() {
 	config.Config.HostnameResolveMethod = "none"
+	// Do not look up resolved hostnames in the backend database
+	hostnameResolvesLightweightCacheLoadedOnceFromDB = true
 }
 
 func Test(t *testing.T) { TestingT(t) }
@@ -34,15 +41,15 @@ type TestSuite struct{}
 var _ = Suite(&TestSuite{})
 
 func (s *TestSuite) TestInstanceKeyEquals(c *C) {
-	i1 := inst.Instance{
-		Key: inst.InstanceKey{
+	i1 := Instance{
+		Key: InstanceKey{
 			Hostname: "sql00.db",
 			Port:     3306,
 		},
 		Version: "5.6",
 	}
-	i2 := inst.Instance{
-		Key: inst.InstanceKey{
+	i2 := Instance{
+		Key: InstanceKey{
 			Hostname: "sql00.db",
 			Port:     3306,
 		},
@@ -56,9 +63,9 @@ func (s *TestSuite) TestInstanceKeyEquals(c *C) {
 }
 
 func (s *TestSuite) TestIsSmallerMajorVersion(c *C) {
-	i55 := inst.Instance{Version: "5.5"}
-	i5517 := inst.Instance{Version: "5.5.17"}
-	i56 := inst.Instance{Version: "5.6"}
+	i55 := Instance{Version: "5.5"}
+	i5517 := Instance{Version: "5.5.17"}
+	i56 := Instance{Version: "5.6"}
 
 	c.Assert(i55.IsSmallerMajorVersion(&i5517), Not(Equals), true)
 	c.Assert(i56.IsSmallerMajorVersion(&i5517), Not(Equals), true)
@@ -66,10 +73,10 @@ func (s *TestSuite) TestIsSmallerMajorVersion(c *C) {
 }
 
 func (s *TestSuite) TestBinlogCoordinates(c *C) {
-	c1 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
-	c2 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
-	c3 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 5000}
-	c4 := inst.BinlogCoordinates{LogFile: "mysql-bin.00112", LogPos: 104}
+	c1 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
+	c2 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
+	c3 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 5000}
+	c4 := BinlogCoordinates{LogFile: "mysql-bin.00112", LogPos: 104}
 
 	c.Assert(c1.Equals(&c2), Equals, true)
 	c.Assert(c1.Equals(&c3), Equals, false)
@@ -84,40 +91,40 @@ func (s *TestSuite) TestBinlogCoordinates(c *C) {
 }
 
 func (s *TestSuite) TestBinlogPrevious(c *C) {
-	c1 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
+	c1 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
 	cres, err := c1.PreviousFileCoordinates()
 
 	c.Assert(err, IsNil)
 	c.Assert(c1.Type, Equals, cres.Type)
 	c.Assert(cres.LogFile, Equals, "mysql-bin.00016")
 
-	c2 := inst.BinlogCoordinates{LogFile: "mysql-bin.00100", LogPos: 104}
+	c2 := BinlogCoordinates{LogFile: "mysql-bin.00100", LogPos: 104}
 	cres, err = c2.PreviousFileCoordinates()
 
 	c.Assert(err, IsNil)
 	c.Assert(c1.Type, Equals, cres.Type)
 	c.Assert(cres.LogFile, Equals, "mysql-bin.00099")
 
-	c3 := inst.BinlogCoordinates{LogFile: "mysql.00.prod.com.00100", LogPos: 104}
+	c3 := BinlogCoordinates{LogFile: "mysql.00.prod.com.00100", LogPos: 104}
 	cres, err = c3.PreviousFileCoordinates()
 
 	c.Assert(err, IsNil)
 	c.Assert(c1.Type, Equals, cres.Type)
 	c.Assert(cres.LogFile, Equals, "mysql.00.prod.com.00099")
 
-	c4 := inst.BinlogCoordinates{LogFile: "mysql.00.prod.com.00000", LogPos: 104}
+	c4 := BinlogCoordinates{LogFile: "mysql.00.prod.com.00000", LogPos: 104}
 	_, err = c4.PreviousFileCoordinates()
 
 	c.Assert(err, Not(IsNil))
 }
 
 func (s *TestSuite) TestBinlogCoordinatesAsKey(c *C) {
-	m := make(map[inst.BinlogCoordinates]bool)
+	m := make(map[BinlogCoordinates]bool)
 
-	c1 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
-	c2 := inst.BinlogCoordinates{LogFile: "mysql-bin.00022", LogPos: 104}
-	c3 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
-	c4 := inst.BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 222}
+	c1 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
+	c2 := BinlogCoordinates{LogFile: "mysql-bin.00022", LogPos: 104}
+	c3 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 104}
+	c4 := BinlogCoordinates{LogFile: "mysql-bin.00017", LogPos: 222}
 
 	m[c1] = true
 	m[c2] = true
@@ -128,8 +135,8 @@ func (s *TestSuite) TestBinlogCoordinatesAsKey(c *C) {
 }
 
 func (s *TestSuite) TestCanReplicateFrom(c *C) {
-	i55 := inst.Instance{Version: "5.5"}
-	i56 := inst.Instance{Version: "5.6"}
+	i55 := Instance{Key: InstanceKey{Hostname: "i55", Port: 3306}, Version: "5.5"}
+	i56 := Instance{Key: InstanceKey{Hostname: "i56", Port: 3306}, Version: "5.6"}
 
 	var canReplicate bool
 	canReplicate, _ = i56.CanReplicateFrom(&i55)
@@ -150,29 +157,527 @@ func (s *TestSuite) TestCanReplicateFrom(c *C) {
 	canReplicate, _ = i55.CanReplicateFrom(&i56)
 	c.Assert(canReplicate, Equals, false)
 
-	iStatement := inst.Instance{Binlog_format: "STATEMENT", ServerID: 1, Version: "5.5", LogBinEnabled: true, LogSlaveUpdatesEnabled: true}
-	iRow := inst.Instance{Binlog_format: "ROW", ServerID: 2, Version: "5.5", LogBinEnabled: true, LogSlaveUpdatesEnabled: true}
+	iStatement := Instance{Key: InstanceKey{Hostname: "iStatement", Port: 3306}, Binlog_format: "STATEMENT", ServerID: 1, Version: "5.5", LogBinEnabled: true, LogSlaveUpdatesEnabled: true}
+	iRow := Instance{Key: InstanceKey{Hostname: "iRow", Port: 3306}, Binlog_format: "ROW", ServerID: 2, Version: "5.5", LogBinEnabled: true, LogSlaveUpdatesEnabled: true}
 	canReplicate, _ = iRow.CanReplicateFrom(&iStatement)
 	c.Assert(canReplicate, Equals, true)
 	canReplicate, _ = iStatement.CanReplicateFrom(&iRow)
 	c.Assert(canReplicate, Equals, false)
+
+	defer func(verifyReplicationFilters bool) {
+		config.Config.VerifyReplicationFilters = verifyReplicationFilters
+	}(config.Config.VerifyReplicationFilters)
+	iFiltered := Instance{Key: InstanceKey{Hostname: "iFiltered", Port: 3306}, Binlog_format: "STATEMENT", ServerID: 3, Version: "5.5", LogBinEnabled: true, LogSlaveUpdatesEnabled: true}
+	iFiltered.HasReplicationFilters = true
+	iFiltered.ReplicationFilters = NewReplicationFilters("sales", "", "", "", "", "")
+
+	config.Config.VerifyReplicationFilters = false
+	canReplicate, _ = iStatement.CanReplicateFrom(&iFiltered)
+	c.Assert(canReplicate, Equals, true)
+
+	config.Config.VerifyReplicationFilters = true
+	canReplicate, err := iStatement.CanReplicateFrom(&iFiltered)
+	c.Assert(canReplicate, Equals, false)
+	c.Assert(err, Not(IsNil))
+	// A filtered instance may serve as master of a slave with same filters, and may itself replicate from an unfiltered master
+	iStatement.ReplicationFilters = NewReplicationFilters(" sales", "", "", "", "", "")
+	canReplicate, _ = iStatement.CanReplicateFrom(&iFiltered)
+	c.Assert(canReplicate, Equals, true)
+	canReplicate, _ = iFiltered.CanReplicateFrom(&i55)
+	c.Assert(canReplicate, Equals, true)
 }
 
 func (s *TestSuite) TestNewInstanceKeyFromStrings(c *C) {
-	i, err := inst.NewInstanceKeyFromStrings("127.0.0.1", "3306")
+	i, err := NewInstanceKeyFromStrings("127.0.0.1", "3306")
 	c.Assert(err, IsNil)
 	c.Assert(i.Hostname, Equals, "127.0.0.1")
 	c.Assert(i.Port, Equals, 3306)
 }
 
 func (s *TestSuite) TestNewInstanceKeyFromStringsFail(c *C) {
-	_, err := inst.NewInstanceKeyFromStrings("127.0.0.1", "3306x")
+	_, err := NewInstanceKeyFromStrings("127.0.0.1", "3306x")
 	c.Assert(err, Not(IsNil))
 }
 
 func (s *TestSuite) TestParseInstanceKey(c *C) {
-	i, err := inst.ParseInstanceKey("127.0.0.1:3306")
+	i, err := ParseInstanceKey("127.0.0.1:3306")
 	c.Assert(err, IsNil)
 	c.Assert(i.Hostname, Equals, "127.0.0.1")
 	c.Assert(i.Port, Equals, 3306)
 }
+
+func (s *TestSuite) TestParseTag(c *C) {
+	tag, err := ParseTag("role=reporting", false)
+	c.Assert(err, IsNil)
+	c.Assert(tag.TagName, Equals, "role")
+	c.Assert(tag.TagValue, Equals, "reporting")
+	c.Assert(tag.String(), Equals, "role=reporting")
+
+	tag, err = ParseTag(" hw = ssd=gen3 ", false)
+	c.Assert(err, IsNil)
+	c.Assert(tag.TagName, Equals, "hw")
+	c.Assert(tag.TagValue, Equals, "ssd=gen3")
+
+	_, err = ParseTag("role", false)
+	c.Assert(err, Not(IsNil))
+	tag, err = ParseTag("role", true)
+	c.Assert(err, IsNil)
+	c.Assert(tag.TagName, Equals, "role")
+	c.Assert(tag.TagValue, Equals, "")
+
+	_, err = ParseTag("bad name=x", false)
+	c.Assert(err, Not(IsNil))
+	_, err = ParseTag("=x", false)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *TestSuite) TestParseTagSelector(c *C) {
+	_, err := ParseTagSelector("role=reporting,hw!=hdd, backup ,!decommissioned")
+	c.Assert(err, IsNil)
+
+	_, err = ParseTagSelector("")
+	c.Assert(err, Not(IsNil))
+	_, err = ParseTagSelector(" , ")
+	c.Assert(err, Not(IsNil))
+	_, err = ParseTagSelector("role=reporting,bad name")
+	c.Assert(err, Not(IsNil))
+	_, err = ParseTagSelector("!")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *TestSuite) TestTagSelectorMatches(c *C) {
+	reporting := &Instance{Tags: map[string]string{"role": "reporting", "hw": "ssd"}}
+	backup := &Instance{Tags: map[string]string{"role": "backup", "decommissioned": ""}}
+	untagged := &Instance{}
+
+	matches := func(selector string, instance *Instance) bool {
+		tagSelector, err := ParseTagSelector(selector)
+		c.Assert(err, IsNil)
+		return tagSelector.Matches(instance)
+	}
+	c.Assert(matches("role=reporting", reporting), Equals, true)
+	c.Assert(matches("role=reporting", backup), Equals, false)
+	c.Assert(matches("role=reporting", untagged), Equals, false)
+
+	c.Assert(matches("role!=reporting", reporting), Equals, false)
+	c.Assert(matches("role!=reporting", backup), Equals, true)
+	c.Assert(matches("role!=reporting", untagged), Equals, true)
+
+	c.Assert(matches("decommissioned", backup), Equals, true)
+	c.Assert(matches("decommissioned", reporting), Equals, false)
+	c.Assert(matches("!decommissioned", backup), Equals, false)
+	c.Assert(matches("!decommissioned", untagged), Equals, true)
+
+	c.Assert(matches("role=reporting,hw=ssd", reporting), Equals, true)
+	c.Assert(matches("role=reporting,hw=hdd", reporting), Equals, false)
+	c.Assert(matches("role,!decommissioned", reporting), Equals, true)
+	c.Assert(matches("role,!decommissioned", backup), Equals, false)
+}
+
+func (s *TestSuite) TestFilterInstancesByTagSelector(c *C) {
+	reporting := &Instance{Key: InstanceKey{Hostname: "db-1", Port: 3306}, Tags: map[string]string{"role": "reporting"}}
+	backup := &Instance{Key: InstanceKey{Hostname: "db-2", Port: 3306}, Tags: map[string]string{"role": "backup"}}
+	untagged := &Instance{Key: InstanceKey{Hostname: "db-3", Port: 3306}}
+	instances := [](*Instance){reporting, backup, untagged}
+
+	filtered, err := FilterInstancesByTagSelector(instances, "")
+	c.Assert(err, IsNil)
+	c.Assert(len(filtered), Equals, 3)
+
+	filtered, err = FilterInstancesByTagSelector(instances, "role=reporting")
+	c.Assert(err, IsNil)
+	c.Assert(len(filtered), Equals, 1)
+	c.Assert(filtered[0].Key, Equals, reporting.Key)
+
+	filtered, err = FilterInstancesByTagSelector(instances, "role!=reporting")
+	c.Assert(err, IsNil)
+	c.Assert(len(filtered), Equals, 2)
+	c.Assert(filtered[0].Key, Equals, backup.Key)
+	c.Assert(filtered[1].Key, Equals, untagged.Key)
+
+	_, err = FilterInstancesByTagSelector(instances, "bad name")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *TestSuite) TestFilterSelectedInstances(c *C) {
+	db1 := &Instance{Key: InstanceKey{Hostname: "db1", Port: 3306}, Tags: map[string]string{"role": "reporting"}}
+	db2 := &Instance{Key: InstanceKey{Hostname: "db2", Port: 3306}}
+	db10 := &Instance{Key: InstanceKey{Hostname: "db10", Port: 3306}, Tags: map[string]string{"role": "reporting"}}
+	poolInstanceKeys := [](*InstanceKey){&db1.Key, &db2.Key}
+
+	// instances are those read by the selector's primary criterion
+	selectedKeys := func(instances [](*Instance), selector *InstanceSelector) []InstanceKey {
+		selected, err := filterSelectedInstances(instances, selector, poolInstanceKeys)
+		c.Assert(err, IsNil)
+		keys := []InstanceKey{}
+		for _, instance := range selected {
+			keys = append(keys, instance.Key)
+		}
+		return keys
+	}
+	clusterInstances := [](*Instance){db1, db2, db10}
+	poolInstances := [](*Instance){db1, db2}
+
+	// Pool applies along with pattern, even with no cluster
+	c.Assert(selectedKeys([](*Instance){db1, db10}, &InstanceSelector{Pattern: "db1", Pool: "reporting"}), DeepEquals, []InstanceKey{db1.Key})
+	// Pattern is not re-applied (on host:port) when instances were read by it (on hostname)
+	c.Assert(selectedKeys([](*Instance){db1, db10}, &InstanceSelector{Pattern: "^db1.*$"}), DeepEquals, []InstanceKey{db1.Key, db10.Key})
+	c.Assert(selectedKeys(clusterInstances, &InstanceSelector{ClusterName: "db1:3306", Pattern: "db1"}), DeepEquals, []InstanceKey{db1.Key, db10.Key})
+	c.Assert(selectedKeys(clusterInstances, &InstanceSelector{ClusterName: "db1:3306", Pool: "reporting", Tag: "role=reporting"}), DeepEquals, []InstanceKey{db1.Key})
+	c.Assert(selectedKeys(poolInstances, &InstanceSelector{Pool: "reporting", Tag: "!role"}), DeepEquals, []InstanceKey{db2.Key})
+	c.Assert(selectedKeys(clusterInstances, &InstanceSelector{ClusterName: "db1:3306", Pattern: "db1", Pool: "reporting", Tag: "role=reporting"}), DeepEquals, []InstanceKey{db1.Key})
+
+	_, err := filterSelectedInstances(clusterInstances, &InstanceSelector{ClusterName: "db1:3306", Tag: "bad name"}, nil)
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *TestSuite) TestScheduledMaintenanceWindowValidate(c *C) {
+	newWindow := func() *ScheduledMaintenanceWindow {
+		return &ScheduledMaintenanceWindow{
+			WindowType:      ScheduledDowntime,
+			Key:             InstanceKey{Hostname: "db-1", Port: 3306},
+			StartTimestamp:  "2016-03-01 02:00:00",
+			DurationSeconds: 3600,
+			Owner:           "dba",
+			Reason:          "backup",
+		}
+	}
+	c.Assert(newWindow().Validate(), IsNil)
+
+	window := newWindow()
+	window.Key = InstanceKey{}
+	window.ClusterName = "db-1:3306"
+	window.WindowType = ScheduledMaintenance
+	c.Assert(window.Validate(), IsNil)
+
+	// Either an instance or a cluster
+	window = newWindow()
+	window.ClusterName = "db-1:3306"
+	c.Assert(window.Validate(), Not(IsNil))
+	window = newWindow()
+	window.Key = InstanceKey{}
+	c.Assert(window.Validate(), Not(IsNil))
+
+	window = newWindow()
+	window.WindowType = "outage"
+	c.Assert(window.Validate(), Not(IsNil))
+
+	window = newWindow()
+	window.StartTimestamp = "2016-03-01T02:00:00Z"
+	c.Assert(window.Validate(), Not(IsNil))
+	window.StartTimestamp = "tomorrow"
+	c.Assert(window.Validate(), Not(IsNil))
+
+	window = newWindow()
+	window.DurationSeconds = 0
+	c.Assert(window.Validate(), Not(IsNil))
+
+	window = newWindow()
+	window.Recurrence = "daily"
+	c.Assert(window.Validate(), IsNil)
+	window.DurationSeconds = 24 * 60 * 60
+	c.Assert(window.Validate(), Not(IsNil))
+	window.Recurrence = "weekly"
+	c.Assert(window.Validate(), IsNil)
+	window.DurationSeconds = 7 * 24 * 60 * 60
+	c.Assert(window.Validate(), Not(IsNil))
+	window.Recurrence = "monthly"
+	window.DurationSeconds = 3600
+	c.Assert(window.Validate(), Not(IsNil))
+
+	window = newWindow()
+	window.Owner = ""
+	c.Assert(window.Validate(), Not(IsNil))
+}
+
+func (s *TestSuite) TestQueryKillPolicyValidate(c *C) {
+	policy := QueryKillPolicy{UserPattern: "^app_", MaxTimeSeconds: 60, ApplyTo: QueryKillApplyToSlaves}
+	c.Assert(policy.Validate(), IsNil)
+
+	policy.ApplyTo = "everywhere"
+	c.Assert(policy.Validate(), Not(IsNil))
+	policy.ApplyTo = QueryKillApplyToAll
+
+	policy.MaxTimeSeconds = 0
+	c.Assert(policy.Validate(), Not(IsNil))
+	policy.MaxTimeSeconds = 60
+
+	policy.DbPattern = "(unclosed"
+	c.Assert(policy.Validate(), Not(IsNil))
+}
+
+func (s *TestSuite) TestQueryKillPolicyMatches(c *C) {
+	process := &Process{User: "app_reports", Db: "sales", Command: "Query", Time: 120}
+	policy := QueryKillPolicy{ClusterName: "c1", UserPattern: "^app_", MaxTimeSeconds: 60, ApplyTo: QueryKillApplyToAll}
+	c.Assert(policy.Matches("c1", true, process), Equals, true)
+	c.Assert(policy.Matches("c1", false, process), Equals, true)
+	c.Assert(policy.Matches("c2", false, process), Equals, false)
+
+	policy.ClusterName = ""
+	c.Assert(policy.Matches("c2", false, process), Equals, true)
+
+	policy.ApplyTo = QueryKillApplyToMaster
+	c.Assert(policy.Matches("c1", true, process), Equals, true)
+	c.Assert(policy.Matches("c1", false, process), Equals, false)
+	policy.ApplyTo = QueryKillApplyToSlaves
+	c.Assert(policy.Matches("c1", true, process), Equals, false)
+	c.Assert(policy.Matches("c1", false, process), Equals, true)
+
+	policy.MaxTimeSeconds = 120
+	c.Assert(policy.Matches("c1", false, process), Equals, true)
+	policy.MaxTimeSeconds = 121
+	c.Assert(policy.Matches("c1", false, process), Equals, false)
+	policy.MaxTimeSeconds = 60
+
+	policy.UserPattern = "^root$"
+	c.Assert(policy.Matches("c1", false, process), Equals, false)
+	policy.UserPattern = ""
+	policy.DbPattern = "^sales$"
+	policy.CommandPattern = "Query"
+	c.Assert(policy.Matches("c1", false, process), Equals, true)
+	policy.CommandPattern = "Sleep"
+	c.Assert(policy.Matches("c1", false, process), Equals, false)
+}
+
+func (s *TestSuite) TestDiffInstanceChanges(c *C) {
+	previous := NewInstance()
+	previous.Key = InstanceKey{Hostname: "db-2", Port: 3306}
+	previous.MasterKey = InstanceKey{Hostname: "db-1", Port: 3306}
+	previous.Version = "5.6.28-log"
+	previous.Binlog_format = "STATEMENT"
+	previous.ServerID = 2
+	previous.Slave_SQL_Running = true
+	previous.Slave_IO_Running = true
+
+	instance := *previous
+	c.Assert(len(diffInstanceChanges(previous, &instance)), Equals, 0)
+
+	instance.ClusterName = "db-1:3306"
+	instance.Version = "5.7.11-log"
+	instance.Binlog_format = "ROW"
+	instance.ReadOnly = true
+	instance.Slave_IO_Running = false
+	changes := diffInstanceChanges(previous, &instance)
+	c.Assert(len(changes), Equals, 4)
+	changesByAttribute := make(map[string]InstanceChange)
+	for _, change := range changes {
+		c.Assert(change.Key, Equals, instance.Key)
+		c.Assert(change.ClusterName, Equals, "db-1:3306")
+		changesByAttribute[change.AttributeName] = change
+	}
+	c.Assert(changesByAttribute["version"].OldValue, Equals, "5.6.28-log")
+	c.Assert(changesByAttribute["version"].NewValue, Equals, "5.7.11-log")
+	c.Assert(changesByAttribute["binlog_format"].NewValue, Equals, "ROW")
+	c.Assert(changesByAttribute["read_only"].OldValue, Equals, "false")
+	c.Assert(changesByAttribute["read_only"].NewValue, Equals, "true")
+	c.Assert(changesByAttribute["slave_io_running"].NewValue, Equals, "false")
+
+	// Detaching from master; an empty or "_" master are one and the same
+	instance = *previous
+	instance.MasterKey = InstanceKey{Hostname: "_", Port: 3306}
+	changes = diffInstanceChanges(previous, &instance)
+	c.Assert(len(changes), Equals, 1)
+	c.Assert(changes[0].AttributeName, Equals, "master")
+	c.Assert(changes[0].OldValue, Equals, "db-1:3306")
+	c.Assert(changes[0].NewValue, Equals, "")
+	previous.MasterKey = InstanceKey{}
+	c.Assert(len(diffInstanceChanges(previous, &instance)), Equals, 0)
+}
+
+func (s *TestSuite) TestHTTPHostnameResolver(c *C) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/kv/db-1":
+			fmt.Fprintln(w, "db-1.dc1.example.com")
+		case "/kv/db 2":
+			fmt.Fprint(w, "db-2.dc1.example.com")
+		case "/kv/db-3":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	defer func(url string, timeout int) {
+		config.Config.HostnameResolveHTTPURL = url
+		config.Config.HostnameResolveHTTPTimeoutSeconds = timeout
+	}(config.Config.HostnameResolveHTTPURL, config.Config.HostnameResolveHTTPTimeoutSeconds)
+
+	resolver := &httpHostnameResolver{}
+	_, err := resolver.Resolve("db-1")
+	c.Assert(err, Not(IsNil))
+
+	config.Config.HostnameResolveHTTPURL = server.URL + "/kv/{hostname}?raw"
+	config.Config.HostnameResolveHTTPTimeoutSeconds = 1
+
+	resolvedHostname, err := resolver.Resolve("db-1")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-1.dc1.example.com")
+
+	// Hostname is escaped as a path segment
+	resolvedHostname, err = resolver.Resolve("db 2")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-2.dc1.example.com")
+
+	resolvedHostname, err = resolver.Resolve("db-unknown")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-unknown")
+
+	resolvedHostname, err = resolver.Resolve("db-3")
+	c.Assert(err, Not(IsNil))
+	c.Assert(resolvedHostname, Equals, "db-3")
+}
+
+func (s *TestSuite) TestStaticHostnameResolver(c *C) {
+	mapFile, err := ioutil.TempFile("", "orchestrator-hostname-map")
+	c.Assert(err, IsNil)
+	defer os.Remove(mapFile.Name())
+	mapFile.Close()
+	defer func(fileName string) {
+		config.Config.HostnameResolveStaticMapFile = fileName
+	}(config.Config.HostnameResolveStaticMapFile)
+
+	resolver := &staticHostnameResolver{}
+	config.Config.HostnameResolveStaticMapFile = ""
+	_, err = resolver.Resolve("db-1")
+	c.Assert(err, Not(IsNil))
+
+	config.Config.HostnameResolveStaticMapFile = mapFile.Name()
+	c.Assert(ioutil.WriteFile(mapFile.Name(), []byte(`{"db-1": "db-1.dc1.example.com"}`), 0644), IsNil)
+
+	resolvedHostname, err := resolver.Resolve("db-1")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-1.dc1.example.com")
+	resolvedHostname, err = resolver.Resolve("db-2")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-2")
+
+	// The map is re-read once the file is modified
+	c.Assert(ioutil.WriteFile(mapFile.Name(), []byte(`{"db-1": "db-1.dc2.example.com", "db-2": "db-2.dc2.example.com"}`), 0644), IsNil)
+	modTime := time.Now().Add(time.Minute)
+	c.Assert(os.Chtimes(mapFile.Name(), modTime, modTime), IsNil)
+
+	resolvedHostname, err = resolver.Resolve("db-1")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-1.dc2.example.com")
+	resolvedHostname, err = resolver.Resolve("db-2")
+	c.Assert(err, IsNil)
+	c.Assert(resolvedHostname, Equals, "db-2.dc2.example.com")
+
+	// A malformed map is an error
+	c.Assert(ioutil.WriteFile(mapFile.Name(), []byte(`{"db-1": `), 0644), IsNil)
+	modTime = modTime.Add(time.Minute)
+	c.Assert(os.Chtimes(mapFile.Name(), modTime, modTime), IsNil)
+	_, err = resolver.Resolve("db-1")
+	c.Assert(err, Not(IsNil))
+}
+
+func (s *TestSuite) TestReplicationFiltersEquals(c *C) {
+	filters := NewReplicationFilters("sales,hr", "", "", "", "%.tmp_%", "")
+	c.Assert(filters.IsEmpty(), Equals, false)
+	noFilters := NewReplicationFilters("", "", "", "", "", "")
+	c.Assert(noFilters.IsEmpty(), Equals, true)
+
+	// Lists are normalized
+	other := NewReplicationFilters(" hr, sales ,", "", "", "", "%.tmp_%", "")
+	c.Assert(filters.Equals(&other), Equals, true)
+	c.Assert(len(filters.Diff(&other)), Equals, 0)
+
+	// The same values under different filter types are not equal
+	other = NewReplicationFilters("sales,hr", "", "", "", "", "%.tmp_%")
+	c.Assert(filters.Equals(&other), Equals, false)
+	c.Assert(other.Equals(&filters), Equals, false)
+}
+
+func (s *TestSuite) TestReplicationFiltersDiff(c *C) {
+	filters := NewReplicationFilters("sales,hr", "", "", "", "%.tmp_%", "")
+	other := NewReplicationFilters("sales", "mysql", "", "", "%.tmp_%", "")
+	c.Assert(filters.Diff(&other), DeepEquals, []string{
+		"Replicate_Do_DB: 'hr,sales' vs. 'sales'",
+		"Replicate_Ignore_DB: '' vs. 'mysql'",
+	})
+	c.Assert(other.Diff(&filters), DeepEquals, []string{
+		"Replicate_Do_DB: 'sales' vs. 'hr,sales'",
+		"Replicate_Ignore_DB: 'mysql' vs. ''",
+	})
+	c.Assert(filters.String(), Equals, "Replicate_Do_DB=hr,sales; Replicate_Wild_Do_Table=%.tmp_%")
+}
+
+func (s *TestSuite) TestGetReplicationFiltersDivergence(c *C) {
+	masterKey := InstanceKey{Hostname: "db-1", Port: 3306}
+	otherMasterKey := InstanceKey{Hostname: "db-2", Port: 3306}
+	newSlave := func(hostname string, masterKey InstanceKey, doDB string) *Instance {
+		instance := NewInstance()
+		instance.Key = InstanceKey{Hostname: hostname, Port: 3306}
+		instance.MasterKey = masterKey
+		instance.ReadBinlogCoordinates = BinlogCoordinates{LogFile: "mysql-bin.000012", LogPos: 4}
+		instance.ReplicationFilters = NewReplicationFilters(doDB, "", "", "", "", "")
+		return instance
+	}
+	master := NewInstance()
+	master.Key = masterKey
+
+	// The diverging slave is the minority one, wherever it is listed
+	instances := [](*Instance){
+		master,
+		newSlave("db-1a", masterKey, "sales"),
+		newSlave("db-1b", masterKey, ""),
+		newSlave("db-1c", masterKey, ""),
+		// A single slave has no siblings to diverge from
+		newSlave("db-2a", otherMasterKey, "hr"),
+	}
+	divergence := getReplicationFiltersDivergence(instances)
+	c.Assert(len(divergence), Equals, 1)
+	c.Assert(divergence[0].Key.Hostname, Equals, "db-1a")
+	c.Assert(divergence[0].MasterKey, Equals, masterKey)
+	c.Assert(divergence[0].SiblingsReplicationFilters.IsEmpty(), Equals, true)
+	c.Assert(divergence[0].Diff, DeepEquals, []string{"Replicate_Do_DB: 'sales' vs. ''"})
+
+	instances = [](*Instance){
+		newSlave("db-1a", masterKey, ""),
+		newSlave("db-1b", masterKey, "sales"),
+		newSlave("db-1c", masterKey, "sales"),
+		newSlave("db-1d", masterKey, "hr"),
+	}
+	divergence = getReplicationFiltersDivergence(instances)
+	c.Assert(len(divergence), Equals, 2)
+	for _, slaveDivergence := range divergence {
+		c.Assert(slaveDivergence.SiblingsReplicationFilters.DoDB, DeepEquals, []string{"sales"})
+		c.Assert(slaveDivergence.Key.Hostname == "db-1a" || slaveDivergence.Key.Hostname == "db-1d", Equals, true)
+	}
+
+	// Agreeing siblings do not diverge
+	instances = [](*Instance){
+		newSlave("db-1a", masterKey, "sales"),
+		newSlave("db-1b", masterKey, "sales"),
+	}
+	c.Assert(len(getReplicationFiltersDivergence(instances)), Equals, 0)
+}
+
+func (s *TestSuite) TestSemiSyncRequiredSlaves(c *C) {
+	defer func(minSemiSyncSlaves uint) {
+		config.Config.MinSemiSyncSlaves = minSemiSyncSlaves
+	}(config.Config.MinSemiSyncSlaves)
+	master := Instance{SemiSyncMasterEnabled: true}
+
+	config.Config.MinSemiSyncSlaves = 0
+	c.Assert(master.SemiSyncRequiredSlaves(), Equals, uint(1))
+	config.Config.MinSemiSyncSlaves = 2
+	c.Assert(master.SemiSyncRequiredSlaves(), Equals, uint(2))
+	master.SemiSyncMasterWaitForSlaveCount = 3
+	c.Assert(master.SemiSyncRequiredSlaves(), Equals, uint(3))
+}
+
+func (s *TestSuite) TestCheckSemiSyncSlavesRemain(c *C) {
+	masterKey := InstanceKey{Hostname: "db-1", Port: 3306}
+	slave := &Instance{Key: InstanceKey{Hostname: "db-2", Port: 3306}, MasterKey: masterKey, Slave_IO_Running: true}
+	c.Assert(slave.IsReplicatingSemiSyncSlave(), Equals, false)
+	// Moving slaves which do not acknowledge semi-sync never reduces semi-sync durability
+	c.Assert(checkSemiSyncSlavesRemain(&masterKey, [](*Instance){slave}), IsNil)
+
+	slave.SemiSyncSlaveEnabled = true
+	c.Assert(slave.IsReplicatingSemiSyncSlave(), Equals, true)
+	slave.Slave_IO_Running = false
+	c.Assert(slave.IsReplicatingSemiSyncSlave(), Equals, false)
+	c.Assert(checkSemiSyncSlavesRemain(&masterKey, [](*Instance){slave}), IsNil)
+}
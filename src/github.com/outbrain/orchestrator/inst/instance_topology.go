@@ -80,7 +80,12 @@ func ASCIITopology(instanceKey *InstanceKey, historyTimestampPattern string) (st
 	if err != nil {
 		return "", err
 	}
+	return ASCIITopologyOfInstances(instances, historyTimestampPattern == ""), nil
+}
 
+// ASCIITopologyOfInstances returns a string representation of the topology of given cluster instances.
+// extendedOutput adds instance status details.
+func ASCIITopologyOfInstances(instances [](*Instance), extendedOutput bool) string {
 	instancesMap := make(map[InstanceKey](*Instance))
 	for _, instance := range instances {
 		log.Debugf("instanceKey: %+v", instance.Key)
@@ -102,11 +107,10 @@ func ASCIITopology(instanceKey *InstanceKey, historyTimestampPattern string) (st
 		}
 	}
 	if masterInstance == nil {
-		return "", nil
+		return ""
 	}
-	resultArray := getASCIITopologyEntry(0, masterInstance, replicationMap, historyTimestampPattern == "")
-	result := strings.Join(resultArray, "\n")
-	return result, nil
+	resultArray := getASCIITopologyEntry(0, masterInstance, replicationMap, extendedOutput)
+	return strings.Join(resultArray, "\n")
 }
 
 // filterInstancesByPattern will filter given array of instances according to regular expression pattern
@@ -157,6 +161,42 @@ func InstanceIsMasterOf(instance0, instance1 *Instance) bool {
 	return instance0.Key.Equals(&instance1.MasterKey)
 }
 
+// checkSemiSyncSlavesRemain verifies that a semi-sync master is left with enough semi-sync slaves once given
+// slaves move away from it. Only a master whose last check is valid is protected: a failed master has no
+// durability left to preserve, and its slaves must be free to move in recovery.
+func checkSemiSyncSlavesRemain(masterKey *InstanceKey, movingSlaves [](*Instance)) error {
+	movingSemiSyncSlaves := make(InstanceKeyMap)
+	for _, slave := range movingSlaves {
+		if slave.IsReplicatingSemiSyncSlave() {
+			movingSemiSyncSlaves[slave.Key] = true
+		}
+	}
+	if len(movingSemiSyncSlaves) == 0 {
+		return nil
+	}
+	master, found, err := ReadInstance(masterKey)
+	if err != nil || !found {
+		return err
+	}
+	if !master.SemiSyncMasterEnabled || !master.IsLastCheckValid {
+		return nil
+	}
+	slaves, err := ReadSlaveInstances(masterKey)
+	if err != nil {
+		return err
+	}
+	countRemainingSemiSyncSlaves := uint(0)
+	for _, slave := range slaves {
+		if slave.IsReplicatingSemiSyncSlave() && !movingSemiSyncSlaves[slave.Key] {
+			countRemainingSemiSyncSlaves++
+		}
+	}
+	if countRemainingSemiSyncSlaves < master.SemiSyncRequiredSlaves() {
+		return fmt.Errorf("Moving %s would leave semi-sync master %+v with %d semi-sync slaves; %d required", movingSemiSyncSlaves.ToCommaDelimitedList(), *masterKey, countRemainingSemiSyncSlaves, master.SemiSyncRequiredSlaves())
+	}
+	return nil
+}
+
 // MoveUp will attempt moving instance indicated by instanceKey up the topology hierarchy.
 // It will perform all safety and sanity checks and will tamper with this instance's replication
 // as well as its master.
@@ -184,6 +224,9 @@ func MoveUp(instanceKey *InstanceKey) (*Instance, error) {
 	if canReplicate, err := instance.CanReplicateFrom(master); canReplicate == false {
 		return instance, err
 	}
+	if err := checkSemiSyncSlavesRemain(&master.Key, [](*Instance){instance}); err != nil {
+		return instance, err
+	}
 
 	log.Infof("Will move %+v up the topology", *instanceKey)
 
@@ -273,6 +316,9 @@ func MoveUpSlaves(instanceKey *InstanceKey, pattern string) ([](*Instance), *Ins
 	if len(slaves) == 0 {
 		return res, instance, nil, errs
 	}
+	if err := checkSemiSyncSlavesRemain(instanceKey, slaves); err != nil {
+		return res, instance, err, errs
+	}
 	log.Infof("Will move slaves of %+v up the topology", *instanceKey)
 
 	if maintenanceToken, merr := BeginMaintenance(instanceKey, GetMaintenanceOwner(), "move up slaves"); merr != nil {
@@ -395,6 +441,9 @@ func MoveBelow(instanceKey, siblingKey *InstanceKey) (*Instance, error) {
 	if canReplicate, err := instance.CanReplicateFrom(sibling); !canReplicate {
 		return instance, err
 	}
+	if err := checkSemiSyncSlavesRemain(&instance.MasterKey, [](*Instance){instance}); err != nil {
+		return instance, err
+	}
 	log.Infof("Will move %+v below its sibling %+v", instanceKey, siblingKey)
 
 	if maintenanceToken, merr := BeginMaintenance(instanceKey, GetMaintenanceOwner(), fmt.Sprintf("move below %+v", *siblingKey)); merr != nil {
@@ -465,6 +514,12 @@ Cleanup:
 // - masterKey is nil: use case is corrupted relay logs on slave
 // - masterKey is not nil: using MaxScale and Binlog servers (coordinates remain the same)
 func Repoint(instanceKey *InstanceKey, masterKey *InstanceKey) (*Instance, error) {
+	return RepointChannel(instanceKey, masterKey, "")
+}
+
+// RepointChannel connects a slave to a master on given replication channel (the default channel when empty),
+// using its exact same executing coordinates of that channel.
+func RepointChannel(instanceKey *InstanceKey, masterKey *InstanceKey, channelName string) (*Instance, error) {
 	instance, err := ReadTopologyInstance(instanceKey)
 	if err != nil {
 		return instance, err
@@ -472,6 +527,17 @@ func Repoint(instanceKey *InstanceKey, masterKey *InstanceKey) (*Instance, error
 	if !instance.IsSlave() {
 		return instance, fmt.Errorf("instance is not a slave: %+v", *instanceKey)
 	}
+	execBinlogCoordinates := instance.ExecBinlogCoordinates
+	if channelName != "" {
+		channel, err := instance.GetReplicationChannel(channelName)
+		if err != nil {
+			return instance, err
+		}
+		if masterKey == nil {
+			masterKey = &channel.MasterKey
+		}
+		execBinlogCoordinates = channel.ExecBinlogCoordinates
+	}
 
 	if masterKey == nil {
 		masterKey = &instance.MasterKey
@@ -493,23 +559,28 @@ func Repoint(instanceKey *InstanceKey, masterKey *InstanceKey) (*Instance, error
 		defer EndMaintenance(maintenanceToken)
 	}
 
-	instance, err = StopSlave(instanceKey)
+	instance, err = StopSlaveChannel(instanceKey, channelName)
 	if err != nil {
 		goto Cleanup
 	}
+	if channelName == "" {
+		execBinlogCoordinates = instance.ExecBinlogCoordinates
+	} else if channel, cerr := instance.GetReplicationChannel(channelName); cerr == nil {
+		execBinlogCoordinates = channel.ExecBinlogCoordinates
+	}
 
-	instance, err = ChangeMasterTo(instanceKey, masterKey, &instance.ExecBinlogCoordinates)
+	instance, err = ChangeMasterToChannel(instanceKey, masterKey, &execBinlogCoordinates, channelName)
 	if err != nil {
 		goto Cleanup
 	}
 
 Cleanup:
-	instance, _ = StartSlave(instanceKey)
+	instance, _ = StartSlaveChannel(instanceKey, channelName)
 	if err != nil {
 		return instance, log.Errore(err)
 	}
 	// and we're done (pending deferred functions)
-	AuditOperation("repoint", instanceKey, fmt.Sprintf("slave %+v repointed to master: %+v", *instanceKey, *masterKey))
+	AuditOperation("repoint", instanceKey, fmt.Sprintf("slave %+v repointed to master: %+v%s", *instanceKey, *masterKey, forChannelClause(channelName)))
 
 	return instance, err
 
@@ -791,6 +862,11 @@ func MatchBelow(instanceKey, otherKey *InstanceKey, requireInstanceMaintenance b
 	if canReplicate, err := instance.CanReplicateFrom(otherInstance); !canReplicate {
 		return instance, nil, err
 	}
+	if !otherKey.Equals(&instance.MasterKey) {
+		if err := checkSemiSyncSlavesRemain(&instance.MasterKey, [](*Instance){instance}); err != nil {
+			return instance, nil, err
+		}
+	}
 	var instancePseudoGtidText string
 	var instancePseudoGtidCoordinates *BinlogCoordinates
 	var otherInstancePseudoGtidCoordinates *BinlogCoordinates
@@ -925,7 +1001,7 @@ func MakeMaster(instanceKey *InstanceKey) (*Instance, error) {
 		}
 	}
 
-	if maintenanceToken, merr := BeginMaintenance(instanceKey, GetMaintenanceOwner(), fmt.Sprintf("siblings match below this", *instanceKey)); merr != nil {
+	if maintenanceToken, merr := BeginMaintenance(instanceKey, GetMaintenanceOwner(), fmt.Sprintf("siblings match below this: %+v", *instanceKey)); merr != nil {
 		err = fmt.Errorf("Cannot begin maintenance on %+v", *instanceKey)
 		goto Cleanup
 	} else {
@@ -1273,6 +1349,9 @@ func MultiMatchSlaves(masterKey *InstanceKey, belowKey *InstanceKey, pattern str
 		return res, belowInstance, err, errs
 	}
 	slaves = filterInstancesByPattern(slaves, pattern)
+	if err := checkSemiSyncSlavesRemain(masterKey, slaves); err != nil {
+		return res, belowInstance, err, errs
+	}
 	matchedSlaves, belowInstance, err, errs := MultiMatchBelow(slaves, &belowInstance.Key, false)
 
 	if len(matchedSlaves) != len(slaves) {
@@ -1324,15 +1403,57 @@ func isGenerallyValidAsCandidateSlave(slave *Instance) bool {
 	if !slave.LogSlaveUpdatesEnabled {
 		return false
 	}
+	if slave.PromotionRule == MustNotPromoteRule {
+		return false
+	}
 	for _, filter := range config.Config.PromotionIgnoreHostnameFilters {
 		if matched, _ := regexp.MatchString(filter, slave.Key.Hostname); matched {
 			return false
 		}
 	}
+	for _, selector := range config.Config.PromotionIgnoreTagSelectors {
+		tagSelector, err := ParseTagSelector(selector)
+		if err != nil {
+			// Err on the side of caution: a malformed selector ignores all slaves
+			log.Errorf("Invalid PromotionIgnoreTagSelectors entry %s: %+v", selector, err)
+			return false
+		}
+		if tagSelector.Matches(slave) {
+			return false
+		}
+	}
+
+	return true
+}
 
+// isValidAsCandidateSlaveOfMaster checks whether given slave may be promoted in place of given (possibly dead) master,
+// taking data center policies into account
+func isValidAsCandidateSlaveOfMaster(masterInstance *Instance, slave *Instance) bool {
+	if !isGenerallyValidAsCandidateSlave(slave) {
+		return false
+	}
+	if masterInstance != nil && masterInstance.DataCenter != "" && !config.Config.AllowCrossDataCenterMasterFailover {
+		if slave.DataCenter != masterInstance.DataCenter {
+			return false
+		}
+	}
 	return true
 }
 
+// isBetterCandidateSlave compares two equally advanced slaves and tells whether the first makes for a better
+// promotion candidate than the second. Slaves in same data center as the master are favored, and then
+// slaves with more favorable promotion rules.
+func isBetterCandidateSlave(masterInstance *Instance, slave *Instance, candidateSlave *Instance) bool {
+	if masterInstance != nil && masterInstance.DataCenter != "" {
+		slaveIsLocal := (slave.DataCenter == masterInstance.DataCenter)
+		candidateIsLocal := (candidateSlave.DataCenter == masterInstance.DataCenter)
+		if slaveIsLocal != candidateIsLocal {
+			return slaveIsLocal
+		}
+	}
+	return slave.PromotionRule.BetterThan(candidateSlave.PromotionRule)
+}
+
 // GetCandidateSlave chooses the best slave to promote given a (possibly dead) master
 func GetCandidateSlave(masterKey *InstanceKey, forRematchPurposes bool) (*Instance, [](*Instance), [](*Instance), [](*Instance), error) {
 	var candidateSlave *Instance
@@ -1347,16 +1468,27 @@ func GetCandidateSlave(masterKey *InstanceKey, forRematchPurposes bool) (*Instan
 	if len(slaves) == 0 {
 		return candidateSlave, aheadSlaves, equalSlaves, laterSlaves, fmt.Errorf("No slaves found for %+v", *masterKey)
 	}
+	// The master may well be dead; we only look for its last known data center
+	masterInstance, _, _ := ReadInstance(masterKey)
 	for _, slave := range slaves {
 		slave := slave
-		if isGenerallyValidAsCandidateSlave(slave) {
+		if isValidAsCandidateSlaveOfMaster(masterInstance, slave) {
 			// this is the one
 			candidateSlave = slave
 			break
 		}
 	}
 	if candidateSlave == nil {
-		return candidateSlave, aheadSlaves, equalSlaves, laterSlaves, fmt.Errorf("No slaves found with log_slave_updates for %+v", *masterKey)
+		return candidateSlave, aheadSlaves, equalSlaves, laterSlaves, fmt.Errorf("No promotable slaves found (log_slave_updates, promotion rules, data center) for %+v", *masterKey)
+	}
+	// Among valid slaves which are as advanced as the chosen one, pick the most favorable
+	for _, slave := range slaves {
+		slave := slave
+		if slave.ExecBinlogCoordinates.Equals(&candidateSlave.ExecBinlogCoordinates) &&
+			isBetterCandidateSlave(masterInstance, slave, candidateSlave) &&
+			isValidAsCandidateSlaveOfMaster(masterInstance, slave) {
+			candidateSlave = slave
+		}
 	}
 	slaves = removeInstance(slaves, &candidateSlave.Key)
 	for _, slave := range slaves {
@@ -1375,6 +1507,8 @@ func GetCandidateSlave(masterKey *InstanceKey, forRematchPurposes bool) (*Instan
 
 // RegroupSlaves will choose a candidate slave of a given instance, and enslave its siblings using
 // either simple CHANGE MASTER TO, where possible, or pseudo-gtid
+// Unlike other refactoring operations, regrouping makes no semi-sync check: it is a failover step, and all slaves are
+// stopped as the candidate is chosen. Semi-sync is restored on the promoted master by post-promotion actions.
 func RegroupSlaves(masterKey *InstanceKey, onCandidateSlaveChosen func(*Instance)) ([](*Instance), [](*Instance), [](*Instance), *Instance, error) {
 	candidateSlave, aheadSlaves, equalSlaves, laterSlaves, err := GetCandidateSlave(masterKey, true)
 	if err != nil {
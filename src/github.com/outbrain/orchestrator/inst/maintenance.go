@@ -17,6 +17,7 @@
 package inst
 
 import (
+	"fmt"
 	"github.com/outbrain/orchestrator/config"
 )
 
@@ -43,3 +44,24 @@ func GetMaintenanceOwner() string {
 func SetMaintenanceOwner(owner string) {
 	maintenanceOwner = owner
 }
+
+// MaintenanceConflictError is returned when attempting to begin maintenance on an instance
+// which is already under maintenance
+type MaintenanceConflictError struct {
+	InstanceKey *InstanceKey
+}
+
+func (this *MaintenanceConflictError) Error() string {
+	return fmt.Sprintf("Cannot begin maintenance for instance: %+v", this.InstanceKey)
+}
+
+// InactiveStateError is returned when attempting to end maintenance or downtime of an instance
+// which is not in such state
+type InactiveStateError struct {
+	InstanceKey *InstanceKey
+	State       string
+}
+
+func (this *InactiveStateError) Error() string {
+	return fmt.Sprintf("Instance is not in %s mode: %+v", this.State, this.InstanceKey)
+}
@@ -102,11 +102,12 @@ func BeginBoundedMaintenance(instanceKey *InstanceKey, owner string, reason stri
 	}
 
 	if affected, _ := res.RowsAffected(); affected == 0 {
-		err = fmt.Errorf("Cannot begin maintenance for instance: %+v", instanceKey)
+		err = &MaintenanceConflictError{InstanceKey: instanceKey}
 	} else {
 		// success
 		maintenanceToken, _ = res.LastInsertId()
 		AuditOperation("begin-maintenance", instanceKey, fmt.Sprintf("maintenanceToken: %d, owner: %s, reason: %s", maintenanceToken, owner, reason))
+		PublishInstanceTopologyEvent(EventMaintenanceBegun, instanceKey, fmt.Sprintf("owner: %s, reason: %s", owner, reason))
 	}
 	return maintenanceToken, err
 }
@@ -142,10 +143,11 @@ func EndMaintenanceByInstanceKey(instanceKey *InstanceKey) error {
 	}
 
 	if affected, _ := res.RowsAffected(); affected == 0 {
-		err = fmt.Errorf("Instance is not in maintenance mode: %+v", instanceKey)
+		err = &InactiveStateError{InstanceKey: instanceKey, State: "maintenance"}
 	} else {
 		// success
 		AuditOperation("end-maintenance", instanceKey, "")
+		PublishInstanceTopologyEvent(EventMaintenanceEnded, instanceKey, "")
 	}
 	return err
 }
@@ -210,6 +212,7 @@ func EndMaintenance(maintenanceToken int64) error {
 		// success
 		instanceKey, _ := ReadMaintenanceInstanceKey(maintenanceToken)
 		AuditOperation("end-maintenance", instanceKey, fmt.Sprintf("maintenanceToken: %d", maintenanceToken))
+		PublishInstanceTopologyEvent(EventMaintenanceEnded, instanceKey, "")
 	}
 	return err
 }
@@ -257,5 +260,8 @@ func ExpireMaintenance() error {
 		}
 	}
 
+	// Activate & expire windows scheduled ahead of time
+	ApplyScheduledMaintenanceWindows(ScheduledMaintenance)
+
 	return err
 }
@@ -131,3 +131,32 @@ Cleanup:
 	return result, nil
 
 }
+
+// ReadPoolInstanceKeys returns the keys of all instances associated with given pool
+func ReadPoolInstanceKeys(pool string) ([](*InstanceKey), error) {
+	var result [](*InstanceKey) = [](*InstanceKey){}
+	query := `
+		select 
+			hostname, port
+		from 
+			database_instance_pool
+		where
+			pool = ?
+		`
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		result = append(result, &InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")})
+		return nil
+	}, pool)
+Cleanup:
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,72 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+)
+
+// CandidatePromotionRule describe the promotion preference/rule for an instance.
+// It maps to promotion_rule column in database_instance_promotion_rule
+type CandidatePromotionRule string
+
+const (
+	PreferPromoteRule    CandidatePromotionRule = "prefer"
+	NeutralPromoteRule   CandidatePromotionRule = "neutral"
+	PreferNotPromoteRule CandidatePromotionRule = "prefer_not"
+	MustNotPromoteRule   CandidatePromotionRule = "must_not"
+)
+
+// ParseCandidatePromotionRule returns a CandidatePromotionRule by name.
+// It returns an error if there is no known rule by the given name.
+func ParseCandidatePromotionRule(ruleName string) (CandidatePromotionRule, error) {
+	switch ruleName {
+	case "prefer", "neutral", "prefer_not", "must_not":
+		return CandidatePromotionRule(ruleName), nil
+	case "prefer-not":
+		return PreferNotPromoteRule, nil
+	case "must-not":
+		return MustNotPromoteRule, nil
+	}
+	return CandidatePromotionRule(""), fmt.Errorf("Invalid CandidatePromotionRule: %v", ruleName)
+}
+
+// BetterThan tells whether this rule is more favorable for promotion than the other rule
+func (this CandidatePromotionRule) BetterThan(other CandidatePromotionRule) bool {
+	return this.rank() < other.rank()
+}
+
+// rank returns a numeric ordering of rules, lower being more favorable
+func (this CandidatePromotionRule) rank() int {
+	switch this {
+	case PreferPromoteRule:
+		return 0
+	case PreferNotPromoteRule:
+		return 2
+	case MustNotPromoteRule:
+		return 3
+	}
+	return 1
+}
+
+// PromotionRule is a registered, persistent promotion rule for a given instance
+type PromotionRule struct {
+	Key                 InstanceKey
+	Rule                CandidatePromotionRule
+	RegisteredTimestamp string
+	ExpireTimestamp     string
+}
@@ -0,0 +1,152 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// RegisterPromotionRule persists a promotion rule for a given instance. When durationSeconds is zero
+// the rule never expires; otherwise it is removed after given number of seconds.
+func RegisterPromotionRule(instanceKey *InstanceKey, promotionRule CandidatePromotionRule, durationSeconds uint) error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			insert 
+				into database_instance_promotion_rule (
+					hostname, port, promotion_rule, registered_timestamp, expire_timestamp
+				) VALUES (
+					?, ?, ?, NOW(), if(? > 0, NOW() + INTERVAL ? SECOND, NULL)
+				)
+				on duplicate key update
+					promotion_rule=values(promotion_rule),
+					registered_timestamp=values(registered_timestamp),
+					expire_timestamp=values(expire_timestamp)
+			`,
+			instanceKey.Hostname,
+			instanceKey.Port,
+			string(promotionRule),
+			durationSeconds,
+			durationSeconds,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		AuditOperation("register-promotion-rule", instanceKey, fmt.Sprintf("promotion rule: %s, duration: %d seconds", promotionRule, durationSeconds))
+
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// UnregisterPromotionRule removes any persisted promotion rule for the given instance
+func UnregisterPromotionRule(instanceKey *InstanceKey) error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			delete from database_instance_promotion_rule
+				where hostname = ? and port = ?
+			`, instanceKey.Hostname, instanceKey.Port,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		AuditOperation("unregister-promotion-rule", instanceKey, "promotion rule removed")
+
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// ExpirePromotionRules removes promotion rules whose expiry time has passed
+func ExpirePromotionRules() error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			delete from database_instance_promotion_rule
+				where expire_timestamp < NOW()
+			`,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// ReadPromotionRules returns all registered promotion rules, optionally limited to a given cluster
+func ReadPromotionRules(clusterName string) ([]PromotionRule, error) {
+	res := []PromotionRule{}
+	clusterCondition := ""
+	if clusterName != "" {
+		clusterCondition = fmt.Sprintf(`
+			where (hostname, port) in (select hostname, port from database_instance where cluster_name = '%s')
+			`, clusterName)
+	}
+	query := fmt.Sprintf(`
+		select 
+			hostname,
+			port,
+			promotion_rule,
+			registered_timestamp,
+			ifnull(expire_timestamp, '') as expire_timestamp
+		from 
+			database_instance_promotion_rule
+		%s
+		order by
+			hostname, port
+		`, clusterCondition)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		promotionRule := PromotionRule{}
+		promotionRule.Key.Hostname = m.GetString("hostname")
+		promotionRule.Key.Port = m.GetInt("port")
+		promotionRule.Rule = CandidatePromotionRule(m.GetString("promotion_rule"))
+		promotionRule.RegisteredTimestamp = m.GetString("registered_timestamp")
+		promotionRule.ExpireTimestamp = m.GetString("expire_timestamp")
+
+		res = append(res, promotionRule)
+		return err
+	})
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
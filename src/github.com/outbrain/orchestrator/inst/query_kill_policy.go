@@ -0,0 +1,101 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Query kill policies apply to masters, slaves, or both
+const (
+	QueryKillApplyToAll    = "all"
+	QueryKillApplyToMaster = "master"
+	QueryKillApplyToSlaves = "slaves"
+)
+
+// QueryKillPolicy describes which long running queries get to be killed on a cluster's instances.
+// Empty patterns match anything. An empty cluster name applies the policy to all clusters.
+// A dry-run policy only records the queries it would have killed.
+type QueryKillPolicy struct {
+	PolicyId       int64
+	ClusterName    string
+	UserPattern    string
+	DbPattern      string
+	CommandPattern string
+	MaxTimeSeconds int64
+	ApplyTo        string
+	DryRun         bool
+	CreatedBy      string
+	CreatedAt      string
+}
+
+// QueryKill is a record of a query killed (or would-be killed, on dry-run) by a policy
+type QueryKill struct {
+	KillId      int64
+	PolicyId    int64
+	Key         InstanceKey
+	ClusterName string
+	Process     Process
+	IsDryRun    bool
+	KilledAt    string
+	KillError   string
+}
+
+// Validate checks policy values and patterns
+func (this *QueryKillPolicy) Validate() error {
+	switch this.ApplyTo {
+	case QueryKillApplyToAll, QueryKillApplyToMaster, QueryKillApplyToSlaves:
+	default:
+		return fmt.Errorf("Unsupported query kill policy apply-to: %s (expected %s|%s|%s)", this.ApplyTo, QueryKillApplyToAll, QueryKillApplyToMaster, QueryKillApplyToSlaves)
+	}
+	if this.MaxTimeSeconds <= 0 {
+		return fmt.Errorf("Query kill policy max time must be positive")
+	}
+	for _, pattern := range []string{this.UserPattern, this.DbPattern, this.CommandPattern} {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// matchesPattern returns true when given pattern is empty or matches given value
+func matchesPattern(pattern string, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := regexp.MatchString(pattern, value)
+	return matched
+}
+
+// Matches returns true when given process, running on an instance of given cluster and role, is subject to this policy
+func (this *QueryKillPolicy) Matches(clusterName string, isMaster bool, process *Process) bool {
+	if this.ClusterName != "" && this.ClusterName != clusterName {
+		return false
+	}
+	if this.ApplyTo == QueryKillApplyToMaster && !isMaster {
+		return false
+	}
+	if this.ApplyTo == QueryKillApplyToSlaves && isMaster {
+		return false
+	}
+	if process.Time < this.MaxTimeSeconds {
+		return false
+	}
+	return matchesPattern(this.UserPattern, process.User) && matchesPattern(this.DbPattern, process.Db) && matchesPattern(this.CommandPattern, process.Command)
+}
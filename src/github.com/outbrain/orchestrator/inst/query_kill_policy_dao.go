@@ -0,0 +1,344 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// CreateQueryKillPolicy persists a new query kill policy, returning its id
+func CreateQueryKillPolicy(policy *QueryKillPolicy) (int64, error) {
+	if err := policy.Validate(); err != nil {
+		return 0, err
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+
+	sqlResult, err := sqlutils.Exec(db, `
+			insert 
+				into query_kill_policy (
+					policy_id, cluster_name, user_pattern, db_pattern, command_pattern, max_time_seconds, apply_to, dry_run, created_by, created_at
+				) VALUES (
+					NULL, ?, ?, ?, ?, ?, ?, ?, ?, NOW()
+				)
+			`,
+		policy.ClusterName,
+		policy.UserPattern,
+		policy.DbPattern,
+		policy.CommandPattern,
+		policy.MaxTimeSeconds,
+		policy.ApplyTo,
+		policy.DryRun,
+		policy.CreatedBy,
+	)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	policyId, err := sqlResult.LastInsertId()
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	AuditOperation("create-query-kill-policy", nil, fmt.Sprintf("policy %d on cluster '%s': user=%s, db=%s, command=%s, max time=%ds, apply to=%s, dry run=%t, by %s",
+		policyId, policy.ClusterName, policy.UserPattern, policy.DbPattern, policy.CommandPattern, policy.MaxTimeSeconds, policy.ApplyTo, policy.DryRun, policy.CreatedBy))
+	return policyId, nil
+}
+
+// DeleteQueryKillPolicy removes a query kill policy
+func DeleteQueryKillPolicy(policyId int64, owner string) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	sqlResult, err := sqlutils.Exec(db, `
+			delete from query_kill_policy
+				where policy_id = ?
+			`, policyId,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	if rows, _ := sqlResult.RowsAffected(); rows == 0 {
+		return fmt.Errorf("No query kill policy found: %d", policyId)
+	}
+	AuditOperation("delete-query-kill-policy", nil, fmt.Sprintf("policy %d, by %s", policyId, owner))
+	return nil
+}
+
+// ReadQueryKillPolicies returns the policies applying to given cluster, or all policies when cluster name is empty
+func ReadQueryKillPolicies(clusterName string) ([]QueryKillPolicy, error) {
+	res := []QueryKillPolicy{}
+	whereCondition := ``
+	args := sqlutils.Args()
+	if clusterName != "" {
+		whereCondition = `where cluster_name in ('', ?)`
+		args = append(args, clusterName)
+	}
+	query := fmt.Sprintf(`
+		select 
+			policy_id,
+			cluster_name,
+			user_pattern,
+			db_pattern,
+			command_pattern,
+			max_time_seconds,
+			apply_to,
+			dry_run,
+			created_by,
+			created_at
+		from 
+			query_kill_policy
+		%s
+		order by
+			policy_id
+		`, whereCondition)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		policy := QueryKillPolicy{}
+		policy.PolicyId = m.GetInt64("policy_id")
+		policy.ClusterName = m.GetString("cluster_name")
+		policy.UserPattern = m.GetString("user_pattern")
+		policy.DbPattern = m.GetString("db_pattern")
+		policy.CommandPattern = m.GetString("command_pattern")
+		policy.MaxTimeSeconds = m.GetInt64("max_time_seconds")
+		policy.ApplyTo = m.GetString("apply_to")
+		policy.DryRun = m.GetBool("dry_run")
+		policy.CreatedBy = m.GetString("created_by")
+		policy.CreatedAt = m.GetString("created_at")
+
+		res = append(res, policy)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
+
+// ReadQueryKillHistory returns a page of query kills on given cluster, or on all clusters when cluster name is empty
+func ReadQueryKillHistory(clusterName string, page int) ([]QueryKill, error) {
+	res := []QueryKill{}
+	whereCondition := ``
+	args := sqlutils.Args()
+	if clusterName != "" {
+		whereCondition = `where cluster_name = ?`
+		args = append(args, clusterName)
+	}
+	query := fmt.Sprintf(`
+		select 
+			*
+		from 
+			query_kill_history
+		%s
+		order by
+			kill_id desc
+		limit %d
+		offset %d
+		`, whereCondition, config.Config.AuditPageSize, page*config.Config.AuditPageSize)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		kill := QueryKill{}
+		kill.KillId = m.GetInt64("kill_id")
+		kill.PolicyId = m.GetInt64("policy_id")
+		kill.Key.Hostname = m.GetString("hostname")
+		kill.Key.Port = m.GetInt("port")
+		kill.ClusterName = m.GetString("cluster_name")
+		kill.Process.InstanceHostname = kill.Key.Hostname
+		kill.Process.InstancePort = kill.Key.Port
+		kill.Process.Id = m.GetInt64("process_id")
+		kill.Process.StartedAt = m.GetString("process_started_at")
+		kill.Process.User = m.GetString("process_user")
+		kill.Process.Host = m.GetString("process_host")
+		kill.Process.Db = m.GetString("process_db")
+		kill.Process.Command = m.GetString("process_command")
+		kill.Process.Time = m.GetInt64("process_time_seconds")
+		kill.Process.Info = m.GetString("process_info")
+		kill.IsDryRun = m.GetBool("is_dry_run")
+		kill.KilledAt = m.GetString("killed_at")
+		kill.KillError = m.GetString("kill_error")
+
+		res = append(res, kill)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
+
+// registerQueryKill records a kill of given process by given policy. It returns false when this very process
+// has already been handled by the policy, in which case it should not be handled again.
+func registerQueryKill(policy *QueryKillPolicy, clusterName string, process *Process) (bool, error) {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return false, log.Errore(err)
+	}
+
+	sqlResult, err := sqlutils.Exec(db, `
+			insert ignore
+				into query_kill_history (
+					kill_id, policy_id, hostname, port, cluster_name, process_id, process_started_at, process_user, process_host,
+					process_db, process_command, process_time_seconds, process_info, is_dry_run, killed_at, kill_error
+				) VALUES (
+					NULL, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW(), ''
+				)
+			`,
+		policy.PolicyId,
+		process.InstanceHostname,
+		process.InstancePort,
+		clusterName,
+		process.Id,
+		process.StartedAt,
+		process.User,
+		process.Host,
+		process.Db,
+		process.Command,
+		process.Time,
+		process.Info,
+		policy.DryRun,
+	)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	return rows > 0, err
+}
+
+// writeQueryKillError notes the error of a failed kill
+func writeQueryKillError(policy *QueryKillPolicy, process *Process, killError error) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			update query_kill_history 
+				set kill_error = ?
+				where
+					hostname = ? and port = ? and process_id = ? and process_started_at = ? and policy_id = ?
+			`, killError.Error(), process.InstanceHostname, process.InstancePort, process.Id, process.StartedAt, policy.PolicyId,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// killPolicyProcess kills a query on the topology instance, after verifying the process is still the one
+// that was observed (process ids may be reused)
+func killPolicyProcess(process *Process) error {
+	instanceKey := &InstanceKey{Hostname: process.InstanceHostname, Port: process.InstancePort}
+	if *config.RuntimeCLIFlags.Noop {
+		return fmt.Errorf("noop: aborting kill-query operation on %+v; signalling error but nothing went wrong.", *instanceKey)
+	}
+	db, err := db.OpenTopology(instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return err
+	}
+	countMatchingProcesses := 0
+	err = db.QueryRow(`
+		select 
+			count(*) 
+		from 
+			information_schema.processlist 
+		where 
+			id = ? 
+			and user = ? 
+			and time >= ?
+		`, process.Id, process.User, process.Time).Scan(&countMatchingProcesses)
+	if err != nil {
+		return err
+	}
+	if countMatchingProcesses == 0 {
+		return fmt.Errorf("Process %d no longer running on %+v", process.Id, *instanceKey)
+	}
+	_, err = ExecInstance(instanceKey, fmt.Sprintf(`kill query %d`, process.Id))
+	return err
+}
+
+// EnforceQueryKillPolicies matches known long running queries against all query kill policies, and kills (or, on dry run,
+// just records) matching queries. A process is handled at most once per policy. Dry run policies do not stop evaluation,
+// so that a later policy may still kill a query a dry run policy has matched.
+// Long running queries are those collected during instance polling (see ReadLongRunningProcesses).
+func EnforceQueryKillPolicies() error {
+	policies, err := ReadQueryKillPolicies("")
+	if err != nil {
+		return err
+	}
+	if len(policies) == 0 {
+		return nil
+	}
+	processes, err := ReadLongRunningProcesses("")
+	if err != nil {
+		return err
+	}
+	for _, process := range processes {
+		process := process
+		instanceKey := InstanceKey{Hostname: process.InstanceHostname, Port: process.InstancePort}
+		instance, found, err := ReadInstance(&instanceKey)
+		if err != nil || !found {
+			continue
+		}
+		isMaster := !instance.IsSlave()
+		for _, policy := range policies {
+			policy := policy
+			if !policy.Matches(instance.ClusterName, isMaster, &process) {
+				continue
+			}
+			isNew, err := registerQueryKill(&policy, instance.ClusterName, &process)
+			if err != nil {
+				break
+			}
+			if policy.DryRun {
+				if isNew {
+					AuditOperation("kill-query-policy", &instanceKey, fmt.Sprintf("dry run: policy %d would kill query %d (user: %s, db: %s, command: %s, time: %ds)", policy.PolicyId, process.Id, process.User, process.Db, process.Command, process.Time))
+				}
+				continue
+			}
+			if !isNew {
+				break
+			}
+			if err := killPolicyProcess(&process); err != nil {
+				log.Errore(err)
+				writeQueryKillError(&policy, &process, err)
+				AuditOperation("kill-query-policy", &instanceKey, fmt.Sprintf("policy %d failed killing query %d: %+v", policy.PolicyId, process.Id, err))
+				break
+			}
+			AuditOperation("kill-query-policy", &instanceKey, fmt.Sprintf("policy %d killed query %d (user: %s, db: %s, command: %s, time: %ds)", policy.PolicyId, process.Id, process.User, process.Db, process.Command, process.Time))
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// ReplicationChannel describes replication from a single master over a named channel, as in multi-source replication.
+// The default channel has an empty name.
+type ReplicationChannel struct {
+	ChannelName           string
+	MasterKey             InstanceKey
+	Slave_SQL_Running     bool
+	Slave_IO_Running      bool
+	ReadBinlogCoordinates BinlogCoordinates
+	ExecBinlogCoordinates BinlogCoordinates
+	LastSQLError          string
+	LastIOError           string
+	SecondsBehindMaster   sql.NullInt64
+}
+
+// SlaveRunning returns true when both replication threads are running on this channel
+func (this *ReplicationChannel) SlaveRunning() bool {
+	return this.Slave_SQL_Running && this.Slave_IO_Running
+}
+
+// IsMultiSource returns true when this instance replicates from more than one master, or via a named channel
+func (this *Instance) IsMultiSource() bool {
+	if len(this.ReplicationChannels) > 1 {
+		return true
+	}
+	return len(this.ReplicationChannels) == 1 && this.ReplicationChannels[0].ChannelName != ""
+}
+
+// GetReplicationChannel returns the replication channel of given name
+func (this *Instance) GetReplicationChannel(channelName string) (*ReplicationChannel, error) {
+	for i := range this.ReplicationChannels {
+		if this.ReplicationChannels[i].ChannelName == channelName {
+			return &this.ReplicationChannels[i], nil
+		}
+	}
+	return nil, fmt.Errorf("No replication channel '%s' found on %+v", channelName, this.Key)
+}
+
+// forChannelClause returns the "FOR CHANNEL" clause to suffix replication statements with; empty on the default channel
+func forChannelClause(channelName string) string {
+	if channelName == "" {
+		return ""
+	}
+	return fmt.Sprintf(" for channel '%s'", channelName)
+}
@@ -0,0 +1,178 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// readReplicationChannelNames returns the names of replication channels persisted for given instance
+func readReplicationChannelNames(instanceKey *InstanceKey) ([]string, error) {
+	channelNames := []string{}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return channelNames, log.Errore(err)
+	}
+	err = sqlutils.QueryRowsMap(db, `
+		select
+			channel_name
+		from
+			database_instance_replication_channel
+		where
+			hostname = ?
+			and port = ?
+		`, func(m sqlutils.RowMap) error {
+		channelNames = append(channelNames, m.GetString("channel_name"))
+		return nil
+	}, instanceKey.Hostname, instanceKey.Port)
+	if err != nil {
+		return channelNames, log.Errore(err)
+	}
+	return channelNames, nil
+}
+
+// writeReplicationChannels persists the replication channels of a multi-source instance, removing channels
+// no longer present. Single source instances have no rows; these are only deleted when the instance
+// used to be multi-source.
+func writeReplicationChannels(instance *Instance) error {
+	persistedChannelNames, err := readReplicationChannelNames(&instance.Key)
+	if err != nil {
+		return err
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+	for _, channelName := range persistedChannelNames {
+		if instance.IsMultiSource() {
+			if _, err := instance.GetReplicationChannel(channelName); err == nil {
+				continue
+			}
+		}
+		_, err = sqlutils.Exec(db, `
+			delete from database_instance_replication_channel
+				where hostname = ? and port = ? and channel_name = ?
+			`, instance.Key.Hostname, instance.Key.Port, channelName,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+	}
+	if !instance.IsMultiSource() {
+		return nil
+	}
+	for _, channel := range instance.ReplicationChannels {
+		_, err = sqlutils.Exec(db, `
+			insert 
+				into database_instance_replication_channel (
+					hostname, port, channel_name, master_host, master_port, slave_sql_running, slave_io_running,
+					master_log_file, read_master_log_pos, relay_master_log_file, exec_master_log_pos,
+					last_sql_error, last_io_error, seconds_behind_master, last_checked
+				) VALUES (
+					?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NOW()
+				)
+				on duplicate key update
+					master_host=VALUES(master_host),
+					master_port=VALUES(master_port),
+					slave_sql_running=VALUES(slave_sql_running),
+					slave_io_running=VALUES(slave_io_running),
+					master_log_file=VALUES(master_log_file),
+					read_master_log_pos=VALUES(read_master_log_pos),
+					relay_master_log_file=VALUES(relay_master_log_file),
+					exec_master_log_pos=VALUES(exec_master_log_pos),
+					last_sql_error=VALUES(last_sql_error),
+					last_io_error=VALUES(last_io_error),
+					seconds_behind_master=VALUES(seconds_behind_master),
+					last_checked=VALUES(last_checked)
+			`,
+			instance.Key.Hostname,
+			instance.Key.Port,
+			channel.ChannelName,
+			channel.MasterKey.Hostname,
+			channel.MasterKey.Port,
+			channel.Slave_SQL_Running,
+			channel.Slave_IO_Running,
+			channel.ReadBinlogCoordinates.LogFile,
+			channel.ReadBinlogCoordinates.LogPos,
+			channel.ExecBinlogCoordinates.LogFile,
+			channel.ExecBinlogCoordinates.LogPos,
+			channel.LastSQLError,
+			channel.LastIOError,
+			channel.SecondsBehindMaster,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+	}
+	return nil
+}
+
+// readReplicationChannels populates the replication channels of given instances. Only multi-source
+// instances are listed in the channels table.
+func readReplicationChannels(instances [](*Instance)) error {
+	if len(instances) == 0 {
+		return nil
+	}
+	instancesMap := make(map[InstanceKey](*Instance))
+	for _, instance := range instances {
+		instancesMap[instance.Key] = instance
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	condition, args := instanceKeysCondition(instances)
+	query := fmt.Sprintf(`
+		select 
+			*
+		from 
+			database_instance_replication_channel
+		where
+			%s
+		order by
+			hostname, port, channel_name
+		`, condition)
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		instanceKey := InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")}
+		instance, found := instancesMap[instanceKey]
+		if !found {
+			return nil
+		}
+		channel := ReplicationChannel{}
+		channel.ChannelName = m.GetString("channel_name")
+		channel.MasterKey = InstanceKey{Hostname: m.GetString("master_host"), Port: m.GetInt("master_port")}
+		channel.Slave_SQL_Running = m.GetBool("slave_sql_running")
+		channel.Slave_IO_Running = m.GetBool("slave_io_running")
+		channel.ReadBinlogCoordinates.LogFile = m.GetString("master_log_file")
+		channel.ReadBinlogCoordinates.LogPos = m.GetInt64("read_master_log_pos")
+		channel.ExecBinlogCoordinates.LogFile = m.GetString("relay_master_log_file")
+		channel.ExecBinlogCoordinates.LogPos = m.GetInt64("exec_master_log_pos")
+		channel.LastSQLError = m.GetString("last_sql_error")
+		channel.LastIOError = m.GetString("last_io_error")
+		channel.SecondsBehindMaster = m.GetNullInt64("seconds_behind_master")
+		instance.ReplicationChannels = append(instance.ReplicationChannels, channel)
+		return nil
+	}, args...)
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
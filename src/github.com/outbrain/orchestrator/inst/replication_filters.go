@@ -0,0 +1,171 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReplicationFilters lists the replication filters configured on a slave, as presented by SHOW SLAVE STATUS.
+// Each list is normalized (trimmed, sorted) so that filters can be compared across instances.
+type ReplicationFilters struct {
+	DoDB            []string
+	IgnoreDB        []string
+	DoTable         []string
+	IgnoreTable     []string
+	WildDoTable     []string
+	WildIgnoreTable []string
+}
+
+// parseReplicationFilterList splits a comma delimited filter list into a normalized list
+func parseReplicationFilterList(filterList string) []string {
+	result := []string{}
+	for _, token := range strings.Split(filterList, ",") {
+		token = strings.TrimSpace(token)
+		if token != "" {
+			result = append(result, token)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// NewReplicationFilters creates replication filters from the comma delimited lists of SHOW SLAVE STATUS
+func NewReplicationFilters(doDB, ignoreDB, doTable, ignoreTable, wildDoTable, wildIgnoreTable string) ReplicationFilters {
+	return ReplicationFilters{
+		DoDB:            parseReplicationFilterList(doDB),
+		IgnoreDB:        parseReplicationFilterList(ignoreDB),
+		DoTable:         parseReplicationFilterList(doTable),
+		IgnoreTable:     parseReplicationFilterList(ignoreTable),
+		WildDoTable:     parseReplicationFilterList(wildDoTable),
+		WildIgnoreTable: parseReplicationFilterList(wildIgnoreTable),
+	}
+}
+
+// lists returns the filter lists by their SHOW SLAVE STATUS names
+func (this *ReplicationFilters) lists() map[string][]string {
+	return map[string][]string{
+		"Replicate_Do_DB":             this.DoDB,
+		"Replicate_Ignore_DB":         this.IgnoreDB,
+		"Replicate_Do_Table":          this.DoTable,
+		"Replicate_Ignore_Table":      this.IgnoreTable,
+		"Replicate_Wild_Do_Table":     this.WildDoTable,
+		"Replicate_Wild_Ignore_Table": this.WildIgnoreTable,
+	}
+}
+
+// IsEmpty returns true when no filter is configured
+func (this *ReplicationFilters) IsEmpty() bool {
+	for _, list := range this.lists() {
+		if len(list) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Equals tests whether this and other have the very same filters
+func (this *ReplicationFilters) Equals(other *ReplicationFilters) bool {
+	return len(this.Diff(other)) == 0
+}
+
+// Diff returns a description of each filter list that differs between this and other
+func (this *ReplicationFilters) Diff(other *ReplicationFilters) []string {
+	diff := []string{}
+	otherLists := other.lists()
+	for name, list := range this.lists() {
+		thisValue := strings.Join(list, ",")
+		otherValue := strings.Join(otherLists[name], ",")
+		if thisValue != otherValue {
+			diff = append(diff, fmt.Sprintf("%s: '%s' vs. '%s'", name, thisValue, otherValue))
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
+
+// String returns a canonical representation of these filters, by which filters can be grouped
+func (this *ReplicationFilters) String() string {
+	tokens := []string{}
+	for name, list := range this.lists() {
+		if len(list) > 0 {
+			tokens = append(tokens, fmt.Sprintf("%s=%s", name, strings.Join(list, ",")))
+		}
+	}
+	sort.Strings(tokens)
+	return strings.Join(tokens, "; ")
+}
+
+// ReplicationFiltersDivergence notes a slave whose replication filters differ from those of most of its siblings
+type ReplicationFiltersDivergence struct {
+	Key                        InstanceKey
+	MasterKey                  InstanceKey
+	ReplicationFilters         ReplicationFilters
+	SiblingsReplicationFilters ReplicationFilters
+	Diff                       []string
+}
+
+// GetClusterReplicationFiltersDivergence returns the slaves in given cluster whose replication filters diverge
+// from the filters common to most of their siblings
+func GetClusterReplicationFiltersDivergence(clusterName string) ([]ReplicationFiltersDivergence, error) {
+	instances, err := ReadClusterInstances(clusterName)
+	if err != nil {
+		return []ReplicationFiltersDivergence{}, err
+	}
+	return getReplicationFiltersDivergence(instances), nil
+}
+
+// getReplicationFiltersDivergence compares the replication filters of sibling slaves among given instances.
+// Siblings are compared against the filters shared by most of them; on a tie, those of the first sibling listed win.
+func getReplicationFiltersDivergence(instances [](*Instance)) []ReplicationFiltersDivergence {
+	result := []ReplicationFiltersDivergence{}
+	siblingsByMaster := make(map[InstanceKey]([](*Instance)))
+	for _, instance := range instances {
+		if instance.IsSlave() {
+			siblingsByMaster[instance.MasterKey] = append(siblingsByMaster[instance.MasterKey], instance)
+		}
+	}
+	for _, siblings := range siblingsByMaster {
+		if len(siblings) < 2 {
+			continue
+		}
+		countByFilters := make(map[string]int)
+		var commonFilters *ReplicationFilters
+		for _, sibling := range siblings {
+			filtersString := sibling.ReplicationFilters.String()
+			countByFilters[filtersString]++
+			if commonFilters == nil || countByFilters[filtersString] > countByFilters[commonFilters.String()] {
+				commonFilters = &sibling.ReplicationFilters
+			}
+		}
+		for _, sibling := range siblings {
+			if sibling.ReplicationFilters.Equals(commonFilters) {
+				continue
+			}
+			result = append(result, ReplicationFiltersDivergence{
+				Key:                        sibling.Key,
+				MasterKey:                  sibling.MasterKey,
+				ReplicationFilters:         sibling.ReplicationFilters,
+				SiblingsReplicationFilters: *commonFilters,
+				Diff:                       sibling.ReplicationFilters.Diff(commonFilters),
+			})
+		}
+	}
+	return result
+}
@@ -53,19 +53,11 @@ func GetCNAME(hostname string) (string, error) {
 }
 
 func resolveHostname(hostname string) (string, error) {
-	switch strings.ToLower(config.Config.HostnameResolveMethod) {
-	case "none":
-		return hostname, nil
-	case "default":
-		return hostname, nil
-	case "cname":
-		return GetCNAME(hostname)
-	}
-	return hostname, nil
+	return getHostnameResolver().Resolve(hostname)
 }
 
 // Attempt to resolve a hostname. This may returned a database cached hostname or otherwise
-// it may resolve the hostname via CNAME
+// it may resolve the hostname via the configured HostnameResolveMethod
 func ResolveHostname(hostname string) (string, error) {
 	hostname = strings.TrimSpace(hostname)
 	if hostname == "" {
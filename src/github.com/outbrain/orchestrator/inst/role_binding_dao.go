@@ -0,0 +1,99 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// GrantRole stores a role binding in the backend database
+func GrantRole(roleBinding *config.RoleBinding, grantedBy string) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			insert ignore
+				into role_binding (
+					user_name, role, cluster_pattern, granted_by, granted_timestamp
+				) VALUES (
+					?, ?, ?, ?, NOW()
+				)
+			`, roleBinding.User, roleBinding.Role, roleBinding.ClusterPattern, grantedBy,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	AuditOperation("grant-role", nil, fmt.Sprintf("user: %s, role: %s, cluster pattern: %s, granted by: %s", roleBinding.User, roleBinding.Role, roleBinding.ClusterPattern, grantedBy))
+	return nil
+}
+
+// RevokeRole removes a role binding from the backend database
+func RevokeRole(roleBinding *config.RoleBinding, revokedBy string) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	res, err := sqlutils.Exec(db, `
+			delete
+				from role_binding
+				where user_name = ? and role = ? and cluster_pattern = ?
+			`, roleBinding.User, roleBinding.Role, roleBinding.ClusterPattern,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("Role binding not found: %+v", *roleBinding)
+	}
+	AuditOperation("revoke-role", nil, fmt.Sprintf("user: %s, role: %s, cluster pattern: %s, revoked by: %s", roleBinding.User, roleBinding.Role, roleBinding.ClusterPattern, revokedBy))
+	return nil
+}
+
+// ReadRoleBindings reads all role bindings stored in the backend database
+func ReadRoleBindings() ([]config.RoleBinding, error) {
+	res := []config.RoleBinding{}
+	query := `
+		select 
+			user_name, role, cluster_pattern
+		from 
+			role_binding
+		order by
+			user_name, role, cluster_pattern
+		`
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		res = append(res, config.RoleBinding{User: m.GetString("user_name"), Role: m.GetString("role"), ClusterPattern: m.GetString("cluster_pattern")})
+		return nil
+	})
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
@@ -0,0 +1,96 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"time"
+)
+
+// Types of scheduled windows
+const (
+	ScheduledMaintenance string = "maintenance"
+	ScheduledDowntime           = "downtime"
+)
+
+// Recurrence of scheduled windows; an empty recurrence means a one-time window
+var scheduledWindowRecurrenceSeconds = map[string]uint{
+	"":       0,
+	"daily":  24 * 60 * 60,
+	"weekly": 7 * 24 * 60 * 60,
+}
+
+// ScheduledWindowTimestampFormat is the format of a window's start time, in the backend database's time zone
+const ScheduledWindowTimestampFormat = "2006-01-02 15:04:05"
+
+// ScheduledMaintenanceWindow is a maintenance or downtime period planned ahead of time, applying to a single
+// instance or to all instances of a cluster. While the window is active the respective maintenance/downtime
+// is applied; recurring windows are re-scheduled upon expiry.
+type ScheduledMaintenanceWindow struct {
+	WindowId               uint
+	WindowType             string
+	Key                    InstanceKey
+	ClusterName            string
+	StartTimestamp         string
+	DurationSeconds        uint
+	Recurrence             string
+	Owner                  string
+	Reason                 string
+	IsActive               bool
+	IsCompleted            bool
+	LastActivatedTimestamp string
+}
+
+// IsClusterWide returns true when this window applies to a cluster rather than to a single instance
+func (this *ScheduledMaintenanceWindow) IsClusterWide() bool {
+	return this.ClusterName != ""
+}
+
+// Validate checks this window is well formed
+func (this *ScheduledMaintenanceWindow) Validate() error {
+	if this.WindowType != ScheduledMaintenance && this.WindowType != ScheduledDowntime {
+		return fmt.Errorf("Unsupported window type: %s. Expected %s or %s", this.WindowType, ScheduledMaintenance, ScheduledDowntime)
+	}
+	if this.IsClusterWide() == this.Key.IsValid() {
+		return fmt.Errorf("Scheduled window must apply to either an instance or a cluster")
+	}
+	if _, err := time.Parse(ScheduledWindowTimestampFormat, this.StartTimestamp); err != nil {
+		return fmt.Errorf("Invalid start time: %s. Expected format: %s", this.StartTimestamp, ScheduledWindowTimestampFormat)
+	}
+	if this.DurationSeconds == 0 {
+		return fmt.Errorf("Scheduled window must have positive duration")
+	}
+	recurrenceSeconds, ok := scheduledWindowRecurrenceSeconds[this.Recurrence]
+	if !ok {
+		return fmt.Errorf("Unsupported recurrence: %s. Expected daily or weekly", this.Recurrence)
+	}
+	if recurrenceSeconds > 0 && this.DurationSeconds >= recurrenceSeconds {
+		return fmt.Errorf("Window duration must be shorter than its recurrence period")
+	}
+	if this.Owner == "" || this.Reason == "" {
+		return fmt.Errorf("Scheduled window requires owner and reason")
+	}
+	return nil
+}
+
+// TargetDescription returns a human readable description of the instance or cluster this window applies to
+func (this *ScheduledMaintenanceWindow) TargetDescription() string {
+	if this.IsClusterWide() {
+		return fmt.Sprintf("cluster %s", this.ClusterName)
+	}
+	return this.Key.DisplayString()
+}
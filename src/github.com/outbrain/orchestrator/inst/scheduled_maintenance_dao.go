@@ -0,0 +1,324 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/config"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// CreateScheduledMaintenanceWindow stores a new scheduled maintenance/downtime window, returning its id
+func CreateScheduledMaintenanceWindow(window *ScheduledMaintenanceWindow) (int64, error) {
+	if err := window.Validate(); err != nil {
+		return 0, log.Errore(err)
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+
+	res, err := sqlutils.Exec(db, `
+			insert 
+				into scheduled_maintenance_window (
+					window_type, hostname, port, cluster_name, start_timestamp, duration_seconds, recurrence, owner, reason
+				) VALUES (
+					?, ?, ?, ?, ?, ?, ?, ?, ?
+				)
+			`,
+		window.WindowType,
+		window.Key.Hostname,
+		window.Key.Port,
+		window.ClusterName,
+		window.StartTimestamp,
+		window.DurationSeconds,
+		window.Recurrence,
+		window.Owner,
+		window.Reason,
+	)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	windowId, _ := res.LastInsertId()
+	AuditOperation(fmt.Sprintf("schedule-%s", window.WindowType), &window.Key, fmt.Sprintf("window: %d, target: %s, start: %s, duration: %ds, recurrence: %s, owner: %s, reason: %s",
+		windowId, window.TargetDescription(), window.StartTimestamp, window.DurationSeconds, window.Recurrence, window.Owner, window.Reason))
+	return windowId, nil
+}
+
+// DeleteScheduledMaintenanceWindow removes a scheduled window. Maintenance/downtime already applied by
+// an active window is not affected, and expires on its own.
+func DeleteScheduledMaintenanceWindow(windowId uint) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	res, err := sqlutils.Exec(db, `
+			delete 
+				from scheduled_maintenance_window 
+				where window_id = ?
+			`, windowId,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return fmt.Errorf("Scheduled window not found: %d", windowId)
+	}
+	AuditOperation("unschedule-window", nil, fmt.Sprintf("window: %d", windowId))
+	return nil
+}
+
+// readScheduledMaintenanceWindows reads scheduled windows by given condition
+func readScheduledMaintenanceWindows(whereCondition string, args ...interface{}) ([]ScheduledMaintenanceWindow, error) {
+	res := []ScheduledMaintenanceWindow{}
+	query := fmt.Sprintf(`
+		select 
+			window_id,
+			window_type,
+			hostname,
+			port,
+			cluster_name,
+			start_timestamp,
+			duration_seconds,
+			recurrence,
+			owner,
+			reason,
+			window_active,
+			window_completed,
+			ifnull(last_activated_timestamp, '') as last_activated_timestamp
+		from 
+			scheduled_maintenance_window
+		%s
+		order by
+			start_timestamp, window_id
+		`, whereCondition)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		window := ScheduledMaintenanceWindow{}
+		window.WindowId = m.GetUint("window_id")
+		window.WindowType = m.GetString("window_type")
+		window.Key.Hostname = m.GetString("hostname")
+		window.Key.Port = m.GetInt("port")
+		window.ClusterName = m.GetString("cluster_name")
+		window.StartTimestamp = m.GetString("start_timestamp")
+		window.DurationSeconds = m.GetUint("duration_seconds")
+		window.Recurrence = m.GetString("recurrence")
+		window.Owner = m.GetString("owner")
+		window.Reason = m.GetString("reason")
+		window.IsActive = m.GetBool("window_active")
+		window.IsCompleted = m.GetBool("window_completed")
+		window.LastActivatedTimestamp = m.GetString("last_activated_timestamp")
+
+		res = append(res, window)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
+
+// ReadScheduledMaintenanceWindows returns all pending & active windows, optionally filtered by cluster.
+// A cluster filter matches cluster-wide windows as well as windows of the cluster's instances.
+func ReadScheduledMaintenanceWindows(clusterName string) ([]ScheduledMaintenanceWindow, error) {
+	if clusterName == "" {
+		return readScheduledMaintenanceWindows(`where window_completed = 0`)
+	}
+	return readScheduledMaintenanceWindows(`
+		where 
+			window_completed = 0
+			and (
+				cluster_name = ?
+				or (hostname, port) in (select hostname, port from database_instance where cluster_name = ?)
+			)
+		`, clusterName, clusterName)
+}
+
+// applyScheduledWindow applies maintenance/downtime on the instance(s) of given window, for given duration
+func applyScheduledWindow(window *ScheduledMaintenanceWindow, durationSeconds uint) error {
+	instanceKeys := [](*InstanceKey){}
+	if window.IsClusterWide() {
+		instances, err := ReadClusterInstances(window.ClusterName)
+		if err != nil {
+			return log.Errore(err)
+		}
+		for _, instance := range instances {
+			instanceKeys = append(instanceKeys, &instance.Key)
+		}
+	} else {
+		instanceKey := window.Key
+		instanceKeys = append(instanceKeys, &instanceKey)
+	}
+	reason := fmt.Sprintf("scheduled window %d: %s", window.WindowId, window.Reason)
+	for _, instanceKey := range instanceKeys {
+		var err error
+		if window.WindowType == ScheduledMaintenance {
+			_, err = BeginBoundedMaintenance(instanceKey, window.Owner, reason, durationSeconds)
+		} else {
+			// Do not cut short a longer downtime, e.g. manual or due to being lost in recovery
+			_, err = BeginDowntimeUnlessLonger(instanceKey, window.Owner, reason, durationSeconds)
+		}
+		if err != nil {
+			// Keep on applying the window on other instances
+			log.Errore(err)
+		}
+	}
+	return nil
+}
+
+// expireScheduledMaintenanceWindows deactivates windows whose time has passed. Recurring windows are
+// re-scheduled to their next period, others are marked as completed, and eventually purged.
+func expireScheduledMaintenanceWindows(windowType string) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	for recurrence, recurrenceSeconds := range scheduledWindowRecurrenceSeconds {
+		if recurrenceSeconds == 0 {
+			continue
+		}
+		// Move start time to the first period which has not yet ended
+		res, err := sqlutils.Exec(db, `
+			update
+				scheduled_maintenance_window
+			set
+				window_active = 0,
+				start_timestamp = start_timestamp + INTERVAL 
+					(floor((timestampdiff(second, start_timestamp, NOW()) - duration_seconds) / ?) + 1) * ? SECOND
+			where
+				window_type = ?
+				and recurrence = ?
+				and window_completed = 0
+				and start_timestamp + INTERVAL duration_seconds SECOND <= NOW()
+			`, recurrenceSeconds, recurrenceSeconds, windowType, recurrence,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected > 0 {
+			AuditOperation(fmt.Sprintf("reschedule-%s", windowType), nil, fmt.Sprintf("Rescheduled %d %s windows", rowsAffected, recurrence))
+		}
+	}
+	{
+		res, err := sqlutils.Exec(db, `
+			update
+				scheduled_maintenance_window
+			set
+				window_active = 0,
+				window_completed = 1
+			where
+				window_type = ?
+				and recurrence = ''
+				and window_completed = 0
+				and start_timestamp + INTERVAL duration_seconds SECOND <= NOW()
+			`, windowType,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		if rowsAffected, _ := res.RowsAffected(); rowsAffected > 0 {
+			AuditOperation(fmt.Sprintf("expire-scheduled-%s", windowType), nil, fmt.Sprintf("Completed %d windows", rowsAffected))
+		}
+	}
+	{
+		_, err := sqlutils.Exec(db, `
+			delete from
+				scheduled_maintenance_window
+			where
+				window_type = ?
+				and window_completed = 1
+				and start_timestamp + INTERVAL duration_seconds SECOND < NOW() - INTERVAL ? DAY
+			`, windowType, config.Config.MaintenancePurgeDays,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+	}
+	return nil
+}
+
+// activateScheduledMaintenanceWindows applies maintenance/downtime for windows whose time has come.
+// The applied maintenance/downtime is bounded by the window's end time.
+func activateScheduledMaintenanceWindows(windowType string) error {
+	windows, err := readScheduledMaintenanceWindows(`
+		where
+			window_type = ?
+			and window_completed = 0
+			and window_active = 0
+			and start_timestamp <= NOW()
+			and start_timestamp + INTERVAL duration_seconds SECOND > NOW()
+		`, windowType)
+	if err != nil {
+		return log.Errore(err)
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+	for _, window := range windows {
+		window := window
+		var remainingSeconds uint
+		err := db.QueryRow(`
+			select 
+				greatest(timestampdiff(second, NOW(), start_timestamp + INTERVAL duration_seconds SECOND), 1) 
+			from 
+				scheduled_maintenance_window 
+			where 
+				window_id = ?
+			`, window.WindowId).Scan(&remainingSeconds)
+		if err != nil {
+			log.Errore(err)
+			continue
+		}
+		applyScheduledWindow(&window, remainingSeconds)
+		_, err = sqlutils.Exec(db, `
+			update
+				scheduled_maintenance_window
+			set
+				window_active = 1,
+				last_activated_timestamp = NOW()
+			where
+				window_id = ?
+			`, window.WindowId,
+		)
+		if err != nil {
+			log.Errore(err)
+			continue
+		}
+		AuditOperation(fmt.Sprintf("activate-scheduled-%s", windowType), &window.Key, fmt.Sprintf("window: %d, target: %s, duration: %ds, reason: %s", window.WindowId, window.TargetDescription(), remainingSeconds, window.Reason))
+	}
+	return nil
+}
+
+// ApplyScheduledMaintenanceWindows expires past scheduled windows of given type, and activates those
+// whose time has come
+func ApplyScheduledMaintenanceWindows(windowType string) error {
+	if err := expireScheduledMaintenanceWindows(windowType); err != nil {
+		return err
+	}
+	return activateScheduledMaintenanceWindows(windowType)
+}
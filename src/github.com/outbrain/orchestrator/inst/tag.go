@@ -0,0 +1,172 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/orchestrator/config"
+	"regexp"
+	"strings"
+)
+
+var tagNameRegexp = regexp.MustCompile("^[a-zA-Z0-9_.:/-]+$")
+
+// Tag is a key/value label attached to an instance, e.g. role=reporting
+type Tag struct {
+	TagName  string
+	TagValue string
+}
+
+// ParseTag parses a "name=value" string. A missing value is allowed when allowEmptyValue is set,
+// in which case only a tag name is given (e.g. when removing a tag)
+func ParseTag(tagString string, allowEmptyValue bool) (*Tag, error) {
+	tagString = strings.TrimSpace(tagString)
+	tag := &Tag{}
+	tokens := strings.SplitN(tagString, "=", 2)
+	tag.TagName = strings.TrimSpace(tokens[0])
+	if len(tokens) == 2 {
+		tag.TagValue = strings.TrimSpace(tokens[1])
+	} else if !allowEmptyValue {
+		return nil, fmt.Errorf("Expected tag in name=value format; got: %s", tagString)
+	}
+	if !tagNameRegexp.MatchString(tag.TagName) {
+		return nil, fmt.Errorf("Invalid tag name: %s", tag.TagName)
+	}
+	return tag, nil
+}
+
+// String returns the name=value representation of this tag
+func (this *Tag) String() string {
+	return fmt.Sprintf("%s=%s", this.TagName, this.TagValue)
+}
+
+// tagSelectorTerm is a single condition within a TagSelector
+type tagSelectorTerm struct {
+	tagName  string
+	tagValue string
+	hasValue bool
+	negate   bool
+}
+
+func (this *tagSelectorTerm) matches(tags map[string]string) bool {
+	value, found := tags[this.tagName]
+	matched := found
+	if found && this.hasValue {
+		matched = (value == this.tagValue)
+	}
+	if this.negate {
+		return !matched
+	}
+	return matched
+}
+
+// TagSelector is a comma delimited list of conditions on instance tags, all of which must hold.
+// Supported conditions are:
+// - name=value: tag exists with given value
+// - name!=value: tag does not exist or has a different value
+// - name: tag exists, with any value
+// - !name: tag does not exist
+type TagSelector struct {
+	terms []tagSelectorTerm
+}
+
+// ParseTagSelector parses a tag selector such as "role=reporting,hw=ssd-gen3,!decommissioned"
+func ParseTagSelector(selector string) (*TagSelector, error) {
+	tagSelector := &TagSelector{terms: []tagSelectorTerm{}}
+	for _, token := range strings.Split(selector, ",") {
+		token = strings.TrimSpace(token)
+		if token == "" {
+			continue
+		}
+		term := tagSelectorTerm{}
+		if strings.Contains(token, "!=") {
+			tokens := strings.SplitN(token, "!=", 2)
+			term.tagName, term.tagValue, term.hasValue, term.negate = strings.TrimSpace(tokens[0]), strings.TrimSpace(tokens[1]), true, true
+		} else if strings.Contains(token, "=") {
+			tokens := strings.SplitN(token, "=", 2)
+			term.tagName, term.tagValue, term.hasValue = strings.TrimSpace(tokens[0]), strings.TrimSpace(tokens[1]), true
+		} else if strings.HasPrefix(token, "!") {
+			term.tagName, term.negate = strings.TrimSpace(token[1:]), true
+		} else {
+			term.tagName = token
+		}
+		if !tagNameRegexp.MatchString(term.tagName) {
+			return nil, fmt.Errorf("Invalid tag name in selector: %s", token)
+		}
+		tagSelector.terms = append(tagSelector.terms, term)
+	}
+	if len(tagSelector.terms) == 0 {
+		return nil, fmt.Errorf("Empty tag selector")
+	}
+	return tagSelector, nil
+}
+
+// Matches tests whether given instance satisfies all of this selector's conditions
+func (this *TagSelector) Matches(instance *Instance) bool {
+	for _, term := range this.terms {
+		if !term.matches(instance.Tags) {
+			return false
+		}
+	}
+	return true
+}
+
+// requiredTagName returns the name of a tag which must exist on any matching instance, or an
+// empty string when the selector only has negative conditions
+func (this *TagSelector) requiredTagName() string {
+	for _, term := range this.terms {
+		if !term.negate {
+			return term.tagName
+		}
+	}
+	return ""
+}
+
+// ValidateTagSelectorsConfig verifies the tag selectors given in configuration (OSCTagSelector,
+// PromotionIgnoreTagSelectors) are well formed
+func ValidateTagSelectorsConfig() error {
+	if config.Config.OSCTagSelector != "" {
+		if _, err := ParseTagSelector(config.Config.OSCTagSelector); err != nil {
+			return fmt.Errorf("OSCTagSelector: %+v", err)
+		}
+	}
+	for _, selector := range config.Config.PromotionIgnoreTagSelectors {
+		if _, err := ParseTagSelector(selector); err != nil {
+			return fmt.Errorf("PromotionIgnoreTagSelectors: %+v", err)
+		}
+	}
+	return nil
+}
+
+// FilterInstancesByTagSelector returns those of given instances which match given selector.
+// An empty selector matches all instances.
+func FilterInstancesByTagSelector(instances [](*Instance), selector string) ([](*Instance), error) {
+	if strings.TrimSpace(selector) == "" {
+		return instances, nil
+	}
+	tagSelector, err := ParseTagSelector(selector)
+	if err != nil {
+		return instances, err
+	}
+	result := [](*Instance){}
+	for _, instance := range instances {
+		if tagSelector.Matches(instance) {
+			result = append(result, instance)
+		}
+	}
+	return result, nil
+}
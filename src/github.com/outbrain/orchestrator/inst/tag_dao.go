@@ -0,0 +1,168 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/golib/sqlutils"
+	"github.com/outbrain/orchestrator/db"
+)
+
+// TagInstance sets a tag on given instance, overriding any previous value of same tag name
+func TagInstance(instanceKey *InstanceKey, tag *Tag) error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		_, err = sqlutils.Exec(db, `
+			insert 
+				into database_instance_tag (
+					hostname, port, tag_name, tag_value, last_updated
+				) VALUES (
+					?, ?, ?, ?, NOW()
+				)
+				on duplicate key update
+					tag_value=values(tag_value),
+					last_updated=values(last_updated)
+			`, instanceKey.Hostname, instanceKey.Port, tag.TagName, tag.TagValue,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		AuditOperation("tag", instanceKey, tag.String())
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// UntagInstance removes a tag, by name, from given instance
+func UntagInstance(instanceKey *InstanceKey, tagName string) error {
+	writeFunc := func() error {
+		db, err := db.OpenOrchestrator()
+		if err != nil {
+			return log.Errore(err)
+		}
+
+		res, err := sqlutils.Exec(db, `
+			delete 
+				from database_instance_tag 
+				where hostname = ? and port = ? and tag_name = ?
+			`, instanceKey.Hostname, instanceKey.Port, tagName,
+		)
+		if err != nil {
+			return log.Errore(err)
+		}
+		if affected, _ := res.RowsAffected(); affected == 0 {
+			return fmt.Errorf("Tag %s not found on %+v", tagName, *instanceKey)
+		}
+		AuditOperation("untag", instanceKey, tagName)
+		return nil
+	}
+	return ExecDBWriteFunc(writeFunc)
+}
+
+// ReadInstanceTags returns the tags of a single instance, as name=>value map
+func ReadInstanceTags(instanceKey *InstanceKey) (map[string]string, error) {
+	tags := make(map[string]string)
+	query := `
+		select 
+			tag_name, tag_value
+		from 
+			database_instance_tag
+		where
+			hostname = ? and port = ?
+		order by
+			tag_name
+		`
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		tags[m.GetString("tag_name")] = m.GetString("tag_value")
+		return nil
+	}, instanceKey.Hostname, instanceKey.Port)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return tags, err
+}
+
+// readInstancesTags populates the tags of given instances
+func readInstancesTags(instances [](*Instance)) error {
+	if len(instances) == 0 {
+		return nil
+	}
+	instancesMap := make(map[InstanceKey](*Instance))
+	for _, instance := range instances {
+		instancesMap[instance.Key] = instance
+	}
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	condition, args := instanceKeysCondition(instances)
+	query := fmt.Sprintf(`
+		select 
+			hostname, port, tag_name, tag_value
+		from 
+			database_instance_tag
+		where
+			%s
+		`, condition)
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		instanceKey := InstanceKey{Hostname: m.GetString("hostname"), Port: m.GetInt("port")}
+		instance, found := instancesMap[instanceKey]
+		if !found {
+			return nil
+		}
+		if instance.Tags == nil {
+			instance.Tags = make(map[string]string)
+		}
+		instance.Tags[m.GetString("tag_name")] = m.GetString("tag_value")
+		return nil
+	}, args...)
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// ReadTaggedInstances reads all known instances matching given tag selector
+func ReadTaggedInstances(selector string) ([](*Instance), error) {
+	tagSelector, err := ParseTagSelector(selector)
+	if err != nil {
+		return [](*Instance){}, log.Errore(err)
+	}
+	condition := `1 = 1`
+	if tagName := tagSelector.requiredTagName(); tagName != "" {
+		// tag names are validated to only contain safe characters
+		condition = fmt.Sprintf(`(hostname, port) in (select hostname, port from database_instance_tag where tag_name = '%s')`, tagName)
+	}
+	instances, err := readInstancesByCondition(condition)
+	if err != nil {
+		return instances, err
+	}
+	return FilterInstancesByTagSelector(instances, selector)
+}
@@ -0,0 +1,276 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	"fmt"
+	"github.com/outbrain/golib/log"
+	"github.com/outbrain/orchestrator/config"
+	"sync"
+	"time"
+)
+
+// Types of topology events
+const (
+	EventMasterChanged       = "master-changed"
+	EventReplicationStarted  = "replication-started"
+	EventReplicationStopped  = "replication-stopped"
+	EventReplicationLagging  = "replication-lagging"
+	EventReplicationCaughtUp = "replication-caught-up"
+	EventInstanceUnreachable = "instance-unreachable"
+	EventInstanceReachable   = "instance-reachable"
+	EventAnalysis            = "analysis"
+	EventAnalysisCleared     = "analysis-cleared"
+	EventRecoveryStarted     = "recovery-started"
+	EventRecoveryResolved    = "recovery-resolved"
+	EventMaintenanceBegun    = "maintenance-begun"
+	EventMaintenanceEnded    = "maintenance-ended"
+	EventDowntimeBegun       = "downtime-begun"
+	EventDowntimeEnded       = "downtime-ended"
+)
+
+const (
+	recentTopologyEventsSize      = 1000
+	topologyEventSubscriptionSize = 100
+)
+
+// TopologyEvent notes a change in the state of an instance or a cluster, as pushed to event subscribers
+type TopologyEvent struct {
+	EventId     int64
+	Type        string
+	Key         InstanceKey
+	ClusterName string
+	Timestamp   time.Time
+	Message     string
+	Details     interface{}
+}
+
+// TopologyEventSubscription receives published events matching its cluster & type filters.
+// A subscriber which falls behind has events dropped rather than block publishers.
+type TopologyEventSubscription struct {
+	Events      chan *TopologyEvent
+	clusterName string
+	eventTypes  map[string]bool
+}
+
+func (this *TopologyEventSubscription) matches(event *TopologyEvent) bool {
+	if this.clusterName != "" && this.clusterName != event.ClusterName {
+		return false
+	}
+	if len(this.eventTypes) > 0 && !this.eventTypes[event.Type] {
+		return false
+	}
+	return true
+}
+
+var topologyEventsMutex = &sync.Mutex{}
+var lastTopologyEventId int64
+var recentTopologyEvents = []*TopologyEvent{}
+var topologyEventSubscriptions = make(map[*TopologyEventSubscription]bool)
+
+// PublishTopologyEvent pushes an event to all matching subscribers. It never blocks.
+func PublishTopologyEvent(eventType string, instanceKey *InstanceKey, clusterName string, message string, details interface{}) {
+	topologyEventsMutex.Lock()
+	defer topologyEventsMutex.Unlock()
+
+	lastTopologyEventId++
+	event := &TopologyEvent{
+		EventId:     lastTopologyEventId,
+		Type:        eventType,
+		ClusterName: clusterName,
+		Timestamp:   time.Now(),
+		Message:     message,
+		Details:     details,
+	}
+	if instanceKey != nil {
+		event.Key = *instanceKey
+	}
+	recentTopologyEvents = append(recentTopologyEvents, event)
+	if len(recentTopologyEvents) > recentTopologyEventsSize {
+		recentTopologyEvents = recentTopologyEvents[len(recentTopologyEvents)-recentTopologyEventsSize:]
+	}
+	for subscription := range topologyEventSubscriptions {
+		if !subscription.matches(event) {
+			continue
+		}
+		select {
+		case subscription.Events <- event:
+		default:
+			log.Warningf("PublishTopologyEvent: subscriber is lagging; dropping event %d", event.EventId)
+		}
+	}
+}
+
+// SubscribeTopologyEvents registers a new subscription, filtered by cluster name and event types (both optional).
+// Recent events following given lastEventId are returned, so that a reconnecting subscriber may catch up.
+func SubscribeTopologyEvents(clusterName string, eventTypes []string, lastEventId int64) (*TopologyEventSubscription, []*TopologyEvent) {
+	subscription := &TopologyEventSubscription{
+		Events:      make(chan *TopologyEvent, topologyEventSubscriptionSize),
+		clusterName: clusterName,
+		eventTypes:  make(map[string]bool),
+	}
+	for _, eventType := range eventTypes {
+		subscription.eventTypes[eventType] = true
+	}
+
+	topologyEventsMutex.Lock()
+	defer topologyEventsMutex.Unlock()
+
+	missedEvents := []*TopologyEvent{}
+	if lastEventId > 0 {
+		for _, event := range recentTopologyEvents {
+			if event.EventId > lastEventId && subscription.matches(event) {
+				missedEvents = append(missedEvents, event)
+			}
+		}
+	}
+	topologyEventSubscriptions[subscription] = true
+	return subscription, missedEvents
+}
+
+// UnsubscribeTopologyEvents removes a subscription; no further events are sent on it
+func UnsubscribeTopologyEvents(subscription *TopologyEventSubscription) {
+	topologyEventsMutex.Lock()
+	defer topologyEventsMutex.Unlock()
+
+	delete(topologyEventSubscriptions, subscription)
+}
+
+// PublishInstanceTopologyEvent publishes an event on a given instance, attributed to its cluster as last read
+func PublishInstanceTopologyEvent(eventType string, instanceKey *InstanceKey, message string) {
+	if instanceKey == nil {
+		return
+	}
+	clusterName := ""
+	if instance, found, _ := ReadInstance(instanceKey); found {
+		clusterName = instance.ClusterName
+	}
+	PublishTopologyEvent(eventType, instanceKey, clusterName, message, nil)
+}
+
+// instanceEventState is the last observed state of an instance, against which lag & reachability events are detected
+type instanceEventState struct {
+	clusterName string
+	lagging     bool
+	reachable   bool
+}
+
+var instanceEventStatesMutex = &sync.Mutex{}
+var instanceEventStates = make(map[InstanceKey]instanceEventState)
+
+// publishInstanceEvents publishes events for a freshly read instance: master and replication thread changes,
+// as detected against given previous reading (nil when unknown), and changes in lag or reachability since
+// the instance was last observed by this process.
+func publishInstanceEvents(previous *Instance, instance *Instance, changes []InstanceChange) {
+	state := instanceEventState{
+		clusterName: instance.ClusterName,
+		lagging:     instance.IsSlave() && instance.SecondsBehindMaster.Valid && instance.SecondsBehindMaster.Int64 > int64(config.Config.ReasonableReplicationLagSeconds),
+		reachable:   true,
+	}
+
+	instanceEventStatesMutex.Lock()
+	previousState, found := instanceEventStates[instance.Key]
+	instanceEventStates[instance.Key] = state
+	instanceEventStatesMutex.Unlock()
+
+	if found && !previousState.reachable {
+		PublishTopologyEvent(EventInstanceReachable, &instance.Key, instance.ClusterName, "instance is reachable", nil)
+	}
+	if previous != nil {
+		replicationThreadsChanged := false
+		for _, change := range changes {
+			switch change.AttributeName {
+			case "master":
+				PublishTopologyEvent(EventMasterChanged, &instance.Key, instance.ClusterName,
+					fmt.Sprintf("master changed from %+v to %+v", previous.MasterKey, instance.MasterKey),
+					map[string]InstanceKey{"PreviousMasterKey": previous.MasterKey, "MasterKey": instance.MasterKey})
+			case "slave_sql_running", "slave_io_running":
+				replicationThreadsChanged = true
+			}
+		}
+		// The previous reading only has tracked attributes, see readInstanceChangeBaseline
+		wasReplicating := previous.Slave_SQL_Running && previous.Slave_IO_Running
+		isReplicating := instance.Slave_SQL_Running && instance.Slave_IO_Running
+		if replicationThreadsChanged && wasReplicating != isReplicating {
+			if isReplicating {
+				PublishTopologyEvent(EventReplicationStarted, &instance.Key, instance.ClusterName, "replication started", nil)
+			} else {
+				PublishTopologyEvent(EventReplicationStopped, &instance.Key, instance.ClusterName,
+					fmt.Sprintf("replication stopped; Slave_IO_Running: %t, Slave_SQL_Running: %t", instance.Slave_IO_Running, instance.Slave_SQL_Running), nil)
+			}
+		}
+	}
+	if found && previousState.lagging != state.lagging {
+		if state.lagging {
+			PublishTopologyEvent(EventReplicationLagging, &instance.Key, instance.ClusterName,
+				fmt.Sprintf("replication lag is %d seconds", instance.SecondsBehindMaster.Int64), nil)
+		} else {
+			PublishTopologyEvent(EventReplicationCaughtUp, &instance.Key, instance.ClusterName, "replication lag is reasonable", nil)
+		}
+	}
+}
+
+// detectInstanceUnreachableEvent publishes an event when a previously reachable instance cannot be read
+func detectInstanceUnreachableEvent(instanceKey *InstanceKey) {
+	instanceEventStatesMutex.Lock()
+	previousState, found := instanceEventStates[*instanceKey]
+	if found {
+		state := previousState
+		state.reachable = false
+		instanceEventStates[*instanceKey] = state
+	}
+	instanceEventStatesMutex.Unlock()
+
+	if found && previousState.reachable {
+		PublishTopologyEvent(EventInstanceUnreachable, instanceKey, previousState.clusterName, "instance is unreachable", nil)
+	}
+}
+
+// forgetInstanceEventState discards the last observed state of a forgotten instance
+func forgetInstanceEventState(instanceKey *InstanceKey) {
+	instanceEventStatesMutex.Lock()
+	defer instanceEventStatesMutex.Unlock()
+
+	delete(instanceEventStates, *instanceKey)
+}
+
+var analysisEventsMutex = &sync.Mutex{}
+var lastAnalysisEntries = make(map[InstanceKey]ReplicationAnalysis)
+
+// PublishReplicationAnalysisEvents publishes events for analysis entries which are new or changed since
+// the previous call, and for problems which have since cleared.
+func PublishReplicationAnalysisEvents(replicationAnalysis []ReplicationAnalysis) {
+	analysisEventsMutex.Lock()
+	defer analysisEventsMutex.Unlock()
+
+	analysisEntries := make(map[InstanceKey]ReplicationAnalysis)
+	for i := range replicationAnalysis {
+		analysisEntry := &replicationAnalysis[i]
+		instanceKey := analysisEntry.AnalyzedInstanceKey
+		analysisEntries[instanceKey] = *analysisEntry
+		if lastAnalysisEntries[instanceKey].Analysis != analysisEntry.Analysis {
+			PublishTopologyEvent(EventAnalysis, &instanceKey, analysisEntry.ClusterName, string(analysisEntry.Analysis), analysisEntry)
+		}
+	}
+	for instanceKey, analysisEntry := range lastAnalysisEntries {
+		if _, found := analysisEntries[instanceKey]; !found {
+			instanceKey := instanceKey
+			PublishTopologyEvent(EventAnalysisCleared, &instanceKey, analysisEntry.ClusterName, string(analysisEntry.Analysis), nil)
+		}
+	}
+	lastAnalysisEntries = analysisEntries
+}
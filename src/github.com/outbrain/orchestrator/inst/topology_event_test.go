@@ -0,0 +1,165 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package inst
+
+import (
+	. "gopkg.in/check.v1"
+)
+
+var eventKey1 = InstanceKey{Hostname: "host1", Port: 3306}
+var eventKey2 = InstanceKey{Hostname: "host2", Port: 3306}
+var eventKey3 = InstanceKey{Hostname: "host3", Port: 3306}
+
+// resetTopologyEvents discards published events and subscriptions
+func resetTopologyEvents() {
+	topologyEventsMutex.Lock()
+	defer topologyEventsMutex.Unlock()
+
+	recentTopologyEvents = []*TopologyEvent{}
+	topologyEventSubscriptions = make(map[*TopologyEventSubscription]bool)
+}
+
+// receivedTopologyEvents returns the types of events pending on given subscription
+func receivedTopologyEvents(subscription *TopologyEventSubscription) []string {
+	eventTypes := []string{}
+	for {
+		select {
+		case event := <-subscription.Events:
+			eventTypes = append(eventTypes, event.Type)
+		default:
+			return eventTypes
+		}
+	}
+}
+
+func (s *TestSuite) TestTopologyEventSubscriptionFilters(c *C) {
+	resetTopologyEvents()
+	all, _ := SubscribeTopologyEvents("", nil, 0)
+	defer UnsubscribeTopologyEvents(all)
+	byCluster, _ := SubscribeTopologyEvents("cluster1", nil, 0)
+	defer UnsubscribeTopologyEvents(byCluster)
+	byType, _ := SubscribeTopologyEvents("", []string{EventMasterChanged, EventAnalysis}, 0)
+	defer UnsubscribeTopologyEvents(byType)
+	byClusterAndType, _ := SubscribeTopologyEvents("cluster1", []string{EventAnalysis}, 0)
+	defer UnsubscribeTopologyEvents(byClusterAndType)
+
+	PublishTopologyEvent(EventMasterChanged, &eventKey1, "cluster1", "", nil)
+	PublishTopologyEvent(EventAnalysis, &eventKey2, "cluster2", "", nil)
+	PublishTopologyEvent(EventAnalysis, &eventKey1, "cluster1", "", nil)
+	PublishTopologyEvent(EventReplicationStopped, &eventKey3, "cluster2", "", nil)
+
+	c.Assert(receivedTopologyEvents(all), DeepEquals, []string{EventMasterChanged, EventAnalysis, EventAnalysis, EventReplicationStopped})
+	c.Assert(receivedTopologyEvents(byCluster), DeepEquals, []string{EventMasterChanged, EventAnalysis})
+	c.Assert(receivedTopologyEvents(byType), DeepEquals, []string{EventMasterChanged, EventAnalysis, EventAnalysis})
+	c.Assert(receivedTopologyEvents(byClusterAndType), DeepEquals, []string{EventAnalysis})
+}
+
+func (s *TestSuite) TestTopologyEventUnsubscribe(c *C) {
+	resetTopologyEvents()
+	subscription, _ := SubscribeTopologyEvents("", nil, 0)
+	UnsubscribeTopologyEvents(subscription)
+
+	PublishTopologyEvent(EventMasterChanged, &eventKey1, "cluster1", "", nil)
+	c.Assert(receivedTopologyEvents(subscription), DeepEquals, []string{})
+}
+
+func (s *TestSuite) TestTopologyEventReplay(c *C) {
+	resetTopologyEvents()
+	PublishTopologyEvent(EventMasterChanged, &eventKey1, "cluster1", "", nil)
+	lastEventId := lastTopologyEventId
+	PublishTopologyEvent(EventAnalysis, &eventKey2, "cluster2", "", nil)
+	PublishTopologyEvent(EventReplicationStopped, &eventKey1, "cluster1", "", nil)
+	PublishTopologyEvent(EventReplicationStarted, &eventKey1, "cluster1", "", nil)
+
+	{
+		subscription, missedEvents := SubscribeTopologyEvents("", nil, lastEventId)
+		defer UnsubscribeTopologyEvents(subscription)
+		c.Assert(len(missedEvents), Equals, 3)
+		c.Assert(missedEvents[0].EventId, Equals, lastEventId+1)
+		c.Assert(missedEvents[0].Type, Equals, EventAnalysis)
+		c.Assert(missedEvents[2].Type, Equals, EventReplicationStarted)
+	}
+	{
+		// Replayed events are filtered as well
+		subscription, missedEvents := SubscribeTopologyEvents("cluster1", []string{EventReplicationStopped}, lastEventId)
+		defer UnsubscribeTopologyEvents(subscription)
+		c.Assert(len(missedEvents), Equals, 1)
+		c.Assert(missedEvents[0].Key, Equals, eventKey1)
+	}
+	{
+		// Nothing to replay for a new subscriber
+		subscription, missedEvents := SubscribeTopologyEvents("", nil, 0)
+		defer UnsubscribeTopologyEvents(subscription)
+		c.Assert(len(missedEvents), Equals, 0)
+	}
+	{
+		// Only recent events are kept
+		for i := 0; i < recentTopologyEventsSize; i++ {
+			PublishTopologyEvent(EventAnalysis, &eventKey2, "cluster2", "", nil)
+		}
+		subscription, missedEvents := SubscribeTopologyEvents("", nil, lastEventId)
+		defer UnsubscribeTopologyEvents(subscription)
+		c.Assert(len(missedEvents), Equals, recentTopologyEventsSize)
+		c.Assert(missedEvents[len(missedEvents)-1].EventId, Equals, lastTopologyEventId)
+	}
+}
+
+func (s *TestSuite) TestTopologyEventDroppedForLaggingSubscriber(c *C) {
+	resetTopologyEvents()
+	lagging, _ := SubscribeTopologyEvents("", nil, 0)
+	defer UnsubscribeTopologyEvents(lagging)
+
+	firstEventId := lastTopologyEventId + 1
+	for i := 0; i < topologyEventSubscriptionSize+10; i++ {
+		PublishTopologyEvent(EventAnalysis, &eventKey1, "cluster1", "", nil)
+	}
+	// Publishing did not block; the subscriber has the oldest events, the rest were dropped
+	c.Assert(len(lagging.Events), Equals, topologyEventSubscriptionSize)
+	c.Assert((<-lagging.Events).EventId, Equals, firstEventId)
+
+	// Once drained, the subscriber receives new events
+	receivedTopologyEvents(lagging)
+	PublishTopologyEvent(EventMasterChanged, &eventKey1, "cluster1", "", nil)
+	c.Assert(receivedTopologyEvents(lagging), DeepEquals, []string{EventMasterChanged})
+}
+
+func (s *TestSuite) TestPublishInstanceEvents(c *C) {
+	resetTopologyEvents()
+	forgetInstanceEventState(&eventKey1)
+	subscription, _ := SubscribeTopologyEvents("", nil, 0)
+	defer UnsubscribeTopologyEvents(subscription)
+
+	previous := &Instance{Key: eventKey1, ClusterName: "cluster1", MasterKey: eventKey2, Slave_IO_Running: true, Slave_SQL_Running: true}
+	instance := *previous
+	publishInstanceEvents(nil, &instance, nil)
+	c.Assert(receivedTopologyEvents(subscription), DeepEquals, []string{})
+
+	instance.MasterKey = eventKey3
+	instance.Slave_SQL_Running = false
+	publishInstanceEvents(previous, &instance, diffInstanceChanges(previous, &instance))
+	c.Assert(receivedTopologyEvents(subscription), DeepEquals, []string{EventMasterChanged, EventReplicationStopped})
+
+	detectInstanceUnreachableEvent(&eventKey1)
+	detectInstanceUnreachableEvent(&eventKey1)
+	c.Assert(receivedTopologyEvents(subscription), DeepEquals, []string{EventInstanceUnreachable})
+	publishInstanceEvents(&instance, &instance, nil)
+	c.Assert(receivedTopologyEvents(subscription), DeepEquals, []string{EventInstanceReachable})
+
+	forgetInstanceEventState(&eventKey1)
+	detectInstanceUnreachableEvent(&eventKey1)
+	c.Assert(receivedTopologyEvents(subscription), DeepEquals, []string{})
+}
@@ -126,6 +126,7 @@ func ContinuousDiscovery() {
 	tick := time.Tick(time.Duration(config.Config.DiscoveryPollSeconds) * time.Second)
 	forgetUnseenTick := time.Tick(time.Minute)
 	recoverTick := time.Tick(10 * time.Second)
+	queryKillTick := time.Tick(10 * time.Second)
 
 	var snapshotTopologiesTick <-chan time.Time
 	if config.Config.SnapshotTopologiesIntervalHours > 0 {
@@ -157,6 +158,9 @@ func ContinuousDiscovery() {
 				inst.ExpireMaintenance()
 				inst.ExpireDowntime()
 				inst.ExpireCandidateInstances()
+				inst.ExpirePromotionRules()
+				inst.ExpireFailureDetectionSnapshots()
+				inst.ExpireInstanceChanges()
 			}
 			if !elected {
 				// Take this opportunity to refresh yourself
@@ -167,8 +171,13 @@ func ContinuousDiscovery() {
 		case <-recoverTick:
 			if elected {
 				ClearActiveRecoveries()
+				ExpireBlockedRecoveries()
 				CheckAndRecover(nil, nil, false)
 			}
+		case <-queryKillTick:
+			if elected {
+				go inst.EnforceQueryKillPolicies()
+			}
 		case <-snapshotTopologiesTick:
 			inst.SnapshotTopologies()
 		}
@@ -39,9 +39,47 @@ type TopologyRecovery struct {
 	RecoveryEndTimestamp   string
 	ProcessingNodeHostname string
 	ProcessingNodeToken    string
+	Acknowledged           bool
+	AcknowledgedAt         string
+	AcknowledgedBy         string
+	AcknowledgedComment    string
+	LostSlaves             inst.InstanceKeyMap
+	AllSteps               []TopologyRecoveryStep
+}
+
+// TopologyRecoveryStep represents an entry in the topology_recovery_steps table: a single, ordered
+// step taken as part of a topology recovery
+type TopologyRecoveryStep struct {
+	RecoveryStepId int64
+	RecoveryId     int64
+	AuditAt        string
+	Message        string
+}
+
+// RecoveryBlock represents an entry in the topology_recovery_block table. A block is set up when
+// recovery rate limits are exceeded, and remains in place until manually acknowledged.
+// An empty ClusterName indicates a global block.
+type RecoveryBlock struct {
+	BlockId               int64
+	ClusterName           string
+	Reason                string
+	StartTimestamp        string
+	IsAcknowledged        bool
+	AcknowledgedBy        string
+	AcknowledgedTimestamp string
+}
+
+// BlockedTopologyRecovery represents an entry in the blocked_topology_recovery table
+type BlockedTopologyRecovery struct {
+	FailedInstanceKey    inst.InstanceKey
+	ClusterName          string
+	Analysis             inst.AnalysisCode
+	LastBlockedTimestamp string
+	BlockingReason       string
 }
 
 var emergencyReadTopologyInstanceMap = cache.New(time.Duration(config.Config.DiscoveryPollSeconds)*time.Second, time.Duration(config.Config.DiscoveryPollSeconds)*time.Second)
+var failureDetectionSnapshotMap = cache.New(10*time.Minute, time.Minute)
 
 // InstancesByCountSlaves sorts instances by umber of slaves, descending
 type InstancesByCountSlaves [](*inst.Instance)
@@ -72,6 +110,13 @@ func replaceCommandPlaceholders(command string, analysisEntry inst.ReplicationAn
 	}
 
 	command = strings.Replace(command, "{slaveHosts}", analysisEntry.GetSlaveHostsAsString(), -1)
+	if strings.Contains(command, "{lostSlaves}") {
+		lostSlaves := ""
+		if topologyRecovery, _ := readActiveTopologyRecovery(&analysisEntry.AnalyzedInstanceKey); topologyRecovery != nil {
+			lostSlaves = topologyRecovery.LostSlaves.ToCommaDelimitedList()
+		}
+		command = strings.Replace(command, "{lostSlaves}", lostSlaves, -1)
+	}
 
 	return command
 }
@@ -109,12 +154,14 @@ func executeProcesses(processes []string, description string, analysisEntry inst
 
 		if cmdErr := os.CommandRun(command); cmdErr == nil {
 			log.Infof("Executed %s command: %s", description, command)
+			AuditTopologyRecovery(&analysisEntry.AnalyzedInstanceKey, fmt.Sprintf("Executed %s command: %s; exit code: 0", description, command))
 		} else {
 			if err == nil {
 				// Note first error
 				err = cmdErr
 			}
 			log.Errorf("Failed to execute %s command: %s", description, command)
+			AuditTopologyRecovery(&analysisEntry.AnalyzedInstanceKey, fmt.Sprintf("Failed to execute %s command: %s; exit code: %d", description, command, os.CommandExitCode(cmdErr)))
 			if failOnError {
 				return err
 			}
@@ -123,6 +170,37 @@ func executeProcesses(processes []string, description string, analysisEntry inst
 	return err
 }
 
+// handleLostSlaves looks for slaves which still replicate from the failed master after regrouping; these are
+// lost in recovery. They are recorded in the recovery entry and optionally downtimed.
+func handleLostSlaves(failedInstanceKey *inst.InstanceKey, promotedSlave *inst.Instance) error {
+	slaves, err := inst.ReadSlaveInstances(failedInstanceKey)
+	if err != nil {
+		return log.Errore(err)
+	}
+	lostSlaves := make(inst.InstanceKeyMap)
+	for _, slave := range slaves {
+		if slave.Key.Equals(&promotedSlave.Key) {
+			continue
+		}
+		lostSlaves[slave.Key] = true
+	}
+	if len(lostSlaves) == 0 {
+		return nil
+	}
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadMaster: %d slaves lost in recovery: %s", len(lostSlaves), lostSlaves.ToCommaDelimitedList()))
+	if err := writeTopologyRecoveryLostSlaves(failedInstanceKey, &lostSlaves); err != nil {
+		return err
+	}
+	if config.Config.LostInRecoveryDowntimeSeconds > 0 {
+		for lostSlaveKey := range lostSlaves {
+			lostSlaveKey := lostSlaveKey
+			inst.BeginDowntime(&lostSlaveKey, inst.GetMaintenanceOwner(), fmt.Sprintf("lost in recovery of %+v", *failedInstanceKey), uint(config.Config.LostInRecoveryDowntimeSeconds))
+		}
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadMaster: downtimed %d lost slaves for %d seconds", len(lostSlaves), config.Config.LostInRecoveryDowntimeSeconds))
+	}
+	return nil
+}
+
 func RecoverDeadMaster(analysisEntry inst.ReplicationAnalysis) (bool, *inst.Instance, error) {
 	failedInstanceKey := &analysisEntry.AnalyzedInstanceKey
 	if ok, err := AttemptRecoveryRegistration(&analysisEntry); !ok {
@@ -131,17 +209,31 @@ func RecoverDeadMaster(analysisEntry inst.ReplicationAnalysis) (bool, *inst.Inst
 	}
 
 	inst.AuditOperation("recover-dead-master", failedInstanceKey, "problem found; will recover")
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadMaster: problem found (%s); will recover", analysisEntry.Analysis))
 	if err := executeProcesses(config.Config.PreFailoverProcesses, "PreFailoverProcesses", analysisEntry, nil, true); err != nil {
+		AuditTopologyRecovery(failedInstanceKey, "RecoverDeadMaster: PreFailoverProcesses failed; aborting recovery")
 		return false, nil, err
 	}
 
 	log.Debugf("RecoverDeadMaster: will recover %+v", *failedInstanceKey)
-	_, _, _, candidateSlave, err := inst.RegroupSlaves(failedInstanceKey, nil)
+	aheadSlaves, equalSlaves, laterSlaves, candidateSlave, err := inst.RegroupSlaves(failedInstanceKey, nil)
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadMaster: regrouped slaves: %d ahead, %d equal, %d later; error: %+v", len(aheadSlaves), len(equalSlaves), len(laterSlaves), err))
+	if candidateSlave == nil {
+		ResolveRecovery(failedInstanceKey, nil)
+		AuditTopologyRecovery(failedInstanceKey, "RecoverDeadMaster: no slave was promoted")
+		return false, nil, err
+	}
+	if len(aheadSlaves) > 0 {
+		// The candidate is the most advanced promotable slave; nothing can replicate from a less advanced server
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadMaster: %d slaves are ahead of promoted slave and are lost", len(aheadSlaves)))
+	}
+	handleLostSlaves(failedInstanceKey, candidateSlave)
 
 	ResolveRecovery(failedInstanceKey, &candidateSlave.Key)
 
 	log.Debugf("- RecoverDeadMaster: candidate slave is %+v", candidateSlave.Key)
 	inst.AuditOperation("recover-dead-master", failedInstanceKey, fmt.Sprintf("master: %+v", candidateSlave.Key))
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadMaster: promoted %+v", candidateSlave.Key))
 
 	return true, candidateSlave, err
 }
@@ -154,6 +246,7 @@ func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promot
 		for _, candidateSlave := range candidateSlaves {
 			if promotedSlave.Key.Equals(&candidateSlave.Key) {
 				// Seems like we promoted a candidate! We're happy!
+				AuditTopologyRecovery(deadInstanceKey, fmt.Sprintf("promoted instance %+v is a registered candidate", promotedSlave.Key))
 				return promotedSlave, nil
 			}
 		}
@@ -163,8 +256,8 @@ func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promot
 		// Try a candidate slave that is in same DC & env as the dead instance
 		if deadInstance, _, err := inst.ReadInstance(deadInstanceKey); err == nil && deadInstance != nil {
 			for _, candidateSlave := range candidateSlaves {
-				if deadInstance.DataCenter == deadInstance.DataCenter &&
-					deadInstance.PhysicalEnvironment == deadInstance.PhysicalEnvironment &&
+				if deadInstance.DataCenter == candidateSlave.DataCenter &&
+					deadInstance.PhysicalEnvironment == candidateSlave.PhysicalEnvironment &&
 					candidateSlave.MasterKey.Equals(&promotedSlave.Key) {
 					// This would make a good candidate
 					candidateInstanceKey = &candidateSlave.Key
@@ -186,11 +279,27 @@ func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promot
 			}
 		}
 	}
+	// Still nothing? Maybe we promoted an instance we'd rather not have as master
+	if candidateInstanceKey == nil && promotedSlave.PromotionRule == inst.PreferNotPromoteRule {
+		if slaves, err := inst.ReadSlaveInstances(&promotedSlave.Key); err == nil {
+			for _, slave := range slaves {
+				if slave.LogBinEnabled && slave.LogSlaveUpdatesEnabled &&
+					slave.PromotionRule.BetterThan(promotedSlave.PromotionRule) &&
+					promotedSlave.DataCenter == slave.DataCenter &&
+					promotedSlave.PhysicalEnvironment == slave.PhysicalEnvironment {
+					candidateInstanceKey = &slave.Key
+					log.Debugf("Promoted instance %+v has %s promotion rule; orchestrator picks %+v as replacement", promotedSlave.Key, promotedSlave.PromotionRule, slave.Key)
+					break
+				}
+			}
+		}
+	}
 
 	// So do we have a candidate?
 	if candidateInstanceKey == nil {
 		return promotedSlave, nil
 	}
+	AuditTopologyRecovery(deadInstanceKey, fmt.Sprintf("candidate replacement for promoted instance %+v: %+v", promotedSlave.Key, *candidateInstanceKey))
 	if promotedSlave.Key.Equals(candidateInstanceKey) {
 		// It IS the candidate
 		return promotedSlave, nil
@@ -208,15 +317,163 @@ func replacePromotedSlaveWithCandidate(deadInstanceKey *inst.InstanceKey, promot
 		log.Debugf("Suggested candidate %+v is slave of promoted instance %+v. Will try and enslave its master", *candidateInstanceKey, promotedSlave.Key)
 		candidateInstance, err = inst.EnslaveMaster(&candidateInstance.Key)
 		if err != nil {
+			AuditTopologyRecovery(deadInstanceKey, fmt.Sprintf("failed replacing promoted instance %+v with candidate %+v: %+v", promotedSlave.Key, *candidateInstanceKey, err))
 			return promotedSlave, log.Errore(err)
 		}
+		inst.AuditOperation("recover-dead-master", deadInstanceKey, fmt.Sprintf("replaced promoted instance %+v with candidate %+v", promotedSlave.Key, candidateInstance.Key))
+		AuditTopologyRecovery(deadInstanceKey, fmt.Sprintf("replaced promoted instance %+v with candidate %+v", promotedSlave.Key, candidateInstance.Key))
 		return candidateInstance, nil
 	}
 
 	log.Debugf("Could not manage to promoted suggested candidate %+v", *candidateInstanceKey)
+	AuditTopologyRecovery(deadInstanceKey, fmt.Sprintf("could not replace promoted instance %+v with candidate %+v: candidate is not its slave", promotedSlave.Key, *candidateInstanceKey))
 	return promotedSlave, nil
 }
 
+// keepIntraDataCenterReplicationChains regroups slaves of a newly promoted master which reside in a remote
+// data center, such that only one of them replicates cross data center and its local siblings replicate from it.
+func keepIntraDataCenterReplicationChains(deadInstanceKey *inst.InstanceKey, promotedSlave *inst.Instance) error {
+	if promotedSlave.DataCenter == "" {
+		return nil
+	}
+	slaves, err := inst.ReadSlaveInstances(&promotedSlave.Key)
+	if err != nil {
+		return log.Errore(err)
+	}
+	remoteSlaves := make(map[string]([](*inst.Instance)))
+	for _, slave := range slaves {
+		if slave.DataCenter == "" || slave.DataCenter == promotedSlave.DataCenter {
+			continue
+		}
+		remoteSlaves[slave.DataCenter] = append(remoteSlaves[slave.DataCenter], slave)
+	}
+	for dataCenter, dataCenterSlaves := range remoteSlaves {
+		if len(dataCenterSlaves) < 2 {
+			continue
+		}
+		sort.Sort(sort.Reverse(inst.InstancesByExecBinlogCoordinates(dataCenterSlaves)))
+		var localMaster *inst.Instance
+		for _, slave := range dataCenterSlaves {
+			if slave.LogBinEnabled && slave.LogSlaveUpdatesEnabled && slave.IsLastCheckValid && slave.PromotionRule != inst.MustNotPromoteRule {
+				localMaster = slave
+				break
+			}
+		}
+		if localMaster == nil {
+			log.Debugf("keepIntraDataCenterReplicationChains: no valid local master found in data center %s", dataCenter)
+			continue
+		}
+		movedSlaves := 0
+		for _, slave := range dataCenterSlaves {
+			if slave.Key.Equals(&localMaster.Key) {
+				continue
+			}
+			if _, err := inst.MoveBelow(&slave.Key, &localMaster.Key); err != nil {
+				log.Errore(err)
+				continue
+			}
+			movedSlaves++
+		}
+		inst.AuditOperation("recover-dead-master", &promotedSlave.Key, fmt.Sprintf("regrouped %d slaves in data center %s below %+v", movedSlaves, dataCenter, localMaster.Key))
+		AuditTopologyRecovery(deadInstanceKey, fmt.Sprintf("regrouped %d slaves in data center %s below %+v", movedSlaves, dataCenter, localMaster.Key))
+	}
+	return nil
+}
+
+// preserveSemiSync re-enables semi-sync on a newly promoted master in case the failed master was a semi-sync master,
+// and enables semi-sync on enough of its replicating slaves to satisfy the required count.
+func preserveSemiSync(failedInstanceKey *inst.InstanceKey, promotedInstanceKey *inst.InstanceKey) error {
+	failedInstance, found, err := inst.ReadInstance(failedInstanceKey)
+	if err != nil || !found {
+		return err
+	}
+	if !failedInstance.SemiSyncMasterEnabled {
+		return nil
+	}
+	_, err = inst.SetSemiSyncMaster(promotedInstanceKey, true)
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: enabled semi-sync master on %+v; error: %+v", *promotedInstanceKey, err))
+	if err != nil {
+		return err
+	}
+
+	requiredSemiSyncSlaves := failedInstance.SemiSyncRequiredSlaves()
+	slaves, err := inst.ReadSlaveInstances(promotedInstanceKey)
+	if err != nil {
+		return log.Errore(err)
+	}
+	countSemiSyncSlaves := uint(0)
+	for _, slave := range slaves {
+		if slave.IsReplicatingSemiSyncSlave() {
+			countSemiSyncSlaves++
+		}
+	}
+	for _, slave := range slaves {
+		if countSemiSyncSlaves >= requiredSemiSyncSlaves {
+			break
+		}
+		if slave.SemiSyncSlaveEnabled || !slave.Slave_IO_Running || !slave.IsLastCheckValid {
+			continue
+		}
+		if _, err := inst.SetSemiSyncSlave(&slave.Key, true); err == nil {
+			countSemiSyncSlaves++
+			AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: enabled semi-sync slave on %+v", slave.Key))
+		}
+	}
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: %d/%d semi-sync slaves attached to %+v", countSemiSyncSlaves, requiredSemiSyncSlaves, *promotedInstanceKey))
+	if countSemiSyncSlaves < requiredSemiSyncSlaves {
+		return fmt.Errorf("Only %d semi-sync slaves attached to %+v; %d required", countSemiSyncSlaves, *promotedInstanceKey, requiredSemiSyncSlaves)
+	}
+	return nil
+}
+
+// ApplyPostPromotionActions runs the configured post-promotion steps on a newly promoted master: discarding its
+// replication config, preserving semi-sync, making it writeable, and carrying over the alias of the cluster it was promoted in.
+// Each step is audited onto the recovery of the failed instance, if such recovery exists.
+func ApplyPostPromotionActions(failedInstanceKey *inst.InstanceKey, promotedInstanceKey *inst.InstanceKey, clusterAlias string) (*inst.Instance, error) {
+	promotedInstance, err := inst.ReadTopologyInstance(promotedInstanceKey)
+	if err != nil {
+		return promotedInstance, log.Errore(err)
+	}
+	switch config.Config.PostPromotionResetSlaveMethod {
+	case "":
+		break
+	case "reset", "detach":
+		{
+			if _, err := inst.StopSlave(promotedInstanceKey); err != nil {
+				AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: failed stopping slave on %+v: %+v", *promotedInstanceKey, err))
+				break
+			}
+			if config.Config.PostPromotionResetSlaveMethod == "reset" {
+				_, err = inst.ResetSlave(promotedInstanceKey)
+			} else {
+				_, err = inst.DetachSlave(promotedInstanceKey)
+			}
+			AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: %s slave on %+v; error: %+v", config.Config.PostPromotionResetSlaveMethod, *promotedInstanceKey, err))
+		}
+	default:
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: unknown PostPromotionResetSlaveMethod: %s", config.Config.PostPromotionResetSlaveMethod))
+	}
+	preserveSemiSync(failedInstanceKey, promotedInstanceKey)
+	if config.Config.PostPromotionSetWriteable {
+		_, err := inst.SetReadOnly(promotedInstanceKey, false)
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: set read_only=0 on %+v; error: %+v", *promotedInstanceKey, err))
+	}
+	if clusterAlias != "" && config.Config.PostPromotionClusterAliasUpdateQuery != "" {
+		_, err := inst.ExecInstance(promotedInstanceKey, config.Config.PostPromotionClusterAliasUpdateQuery, clusterAlias)
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: updated cluster alias data on %+v to %s; error: %+v", *promotedInstanceKey, clusterAlias, err))
+	}
+	promotedInstance, err = inst.ReadTopologyInstance(promotedInstanceKey)
+	if err != nil {
+		return promotedInstance, log.Errore(err)
+	}
+	if clusterAlias != "" && config.Config.PostPromotionRegisterClusterAlias {
+		err := inst.SetClusterAlias(promotedInstance.ClusterName, clusterAlias)
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("post-promotion: registered alias %s onto cluster %s; error: %+v", clusterAlias, promotedInstance.ClusterName, err))
+	}
+	inst.AuditOperation("post-promotion", promotedInstanceKey, fmt.Sprintf("applied post-promotion actions; alias: %s", clusterAlias))
+	return promotedInstance, nil
+}
+
 // checkAndRecoverDeadMaster checks a given analysis, decides whether to take action, and possibly takes action
 // Returns true when action was taken.
 func checkAndRecoverDeadMaster(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, skipFilters bool) (bool, *inst.Instance, error) {
@@ -229,6 +486,12 @@ func checkAndRecoverDeadMaster(analysisEntry inst.ReplicationAnalysis, candidate
 
 	if actionTaken && promotedSlave != nil {
 		promotedSlave, _ = replacePromotedSlaveWithCandidate(&analysisEntry.AnalyzedInstanceKey, promotedSlave, candidateInstanceKey)
+		if config.Config.KeepIntraDataCenterReplicationChains {
+			keepIntraDataCenterReplicationChains(&analysisEntry.AnalyzedInstanceKey, promotedSlave)
+		}
+		if refreshedPromotedSlave, err := ApplyPostPromotionActions(&analysisEntry.AnalyzedInstanceKey, &promotedSlave.Key, analysisEntry.ClusterAlias); err == nil {
+			promotedSlave = refreshedPromotedSlave
+		}
 		// Execute post master-failover processes
 		executeProcesses(config.Config.PostMasterFailoverProcesses, "PostMasterFailoverProcesses", analysisEntry, promotedSlave, false)
 	}
@@ -266,7 +529,7 @@ func isValidAsCandidateSiblingOfIntermediateMaster(intermediateMasterInstance *i
 	if sibling.PhysicalEnvironment != intermediateMasterInstance.PhysicalEnvironment {
 		return false
 	}
-	if sibling.HasReplicationFilters != intermediateMasterInstance.HasReplicationFilters {
+	if !sibling.ReplicationFilters.Equals(&intermediateMasterInstance.ReplicationFilters) {
 		return false
 	}
 	if sibling.IsMaxScale() || intermediateMasterInstance.IsMaxScale() {
@@ -318,8 +581,10 @@ func RecoverDeadIntermediateMaster(analysisEntry inst.ReplicationAnalysis) (acti
 	}
 
 	inst.AuditOperation("recover-dead-intermediate-master", failedInstanceKey, "problem found; will recover")
+	AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadIntermediateMaster: problem found (%s); will recover", analysisEntry.Analysis))
 	log.Debugf("RecoverDeadIntermediateMaster: will recover %+v", *failedInstanceKey)
 	if err := executeProcesses(config.Config.PreFailoverProcesses, "PreFailoverProcesses", analysisEntry, nil, true); err != nil {
+		AuditTopologyRecovery(failedInstanceKey, "RecoverDeadIntermediateMaster: PreFailoverProcesses failed; aborting recovery")
 		return false, nil, err
 	}
 
@@ -334,9 +599,11 @@ func RecoverDeadIntermediateMaster(analysisEntry inst.ReplicationAnalysis) (acti
 
 			log.Debugf("- RecoverDeadIntermediateMaster: move to candidate intermediate master (%+v) went with %d errors", candidateSibling.Key, len(errs))
 			inst.AuditOperation("recover-dead-intermediate-master", failedInstanceKey, fmt.Sprintf("Done. Matched %d slaves under candidate sibling: %+v; %d errors: %+v", len(matchedSlaves), candidateSibling.Key, len(errs), errs))
+			AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadIntermediateMaster: matched %d slaves under candidate sibling: %+v; %d errors: %+v", len(matchedSlaves), candidateSibling.Key, len(errs), errs))
 		} else {
 			log.Debugf("- RecoverDeadIntermediateMaster: move to candidate intermediate master (%+v) did not complete: %+v", candidateSibling.Key, err)
 			inst.AuditOperation("recover-dead-intermediate-master", failedInstanceKey, fmt.Sprintf("Matched %d slaves under candidate sibling: %+v; %d errors: %+v", len(matchedSlaves), candidateSibling.Key, len(errs), errs))
+			AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadIntermediateMaster: move to candidate sibling %+v did not complete; matched %d slaves; error: %+v", candidateSibling.Key, len(matchedSlaves), err))
 		}
 	}
 	if !actionTaken {
@@ -353,6 +620,7 @@ func RecoverDeadIntermediateMaster(analysisEntry inst.ReplicationAnalysis) (acti
 		matchedSlaves, successorInstance, err, errs = inst.MatchUpSlaves(failedInstanceKey, "")
 		if len(matchedSlaves) == 0 {
 			log.Errorf("RecoverDeadIntermediateMaster failed to match up any slave from %+v", *failedInstanceKey)
+			AuditTopologyRecovery(failedInstanceKey, "RecoverDeadIntermediateMaster: failed to match up any slave")
 			return false, successorInstance, err
 		}
 		ResolveRecovery(failedInstanceKey, &successorInstance.Key)
@@ -360,6 +628,7 @@ func RecoverDeadIntermediateMaster(analysisEntry inst.ReplicationAnalysis) (acti
 
 		log.Debugf("- RecoverDeadIntermediateMaster: matched up to %+v", successorInstance.Key)
 		inst.AuditOperation("recover-dead-intermediate-master", failedInstanceKey, fmt.Sprintf("Done. Matched slaves under: %+v %d errors: %+v", successorInstance.Key, len(errs), errs))
+		AuditTopologyRecovery(failedInstanceKey, fmt.Sprintf("RecoverDeadIntermediateMaster: matched %d slaves under %+v; %d errors: %+v", len(matchedSlaves), successorInstance.Key, len(errs), errs))
 	}
 	return actionTaken, successorInstance, err
 }
@@ -403,6 +672,30 @@ func emergentlyReadTopologyInstanceSlaves(instanceKey *inst.InstanceKey, analysi
 	}
 }
 
+// captureFailureDetectionSnapshots captures processlist & status of an analyzed instance and of its slaves, where reachable.
+// Capture is made at most once per instance & analysis in a 10 minute period.
+func captureFailureDetectionSnapshots(analysisEntry inst.ReplicationAnalysis) {
+	if !config.Config.CaptureFailureDetectionSnapshots {
+		return
+	}
+	snapshotKey := fmt.Sprintf("%s/%s", analysisEntry.AnalyzedInstanceKey.DisplayString(), analysisEntry.Analysis)
+	if err := failureDetectionSnapshotMap.Add(snapshotKey, true, 0); err != nil {
+		// Already captured recently
+		return
+	}
+	instanceKeys := []inst.InstanceKey{analysisEntry.AnalyzedInstanceKey}
+	for slaveKey := range analysisEntry.SlaveHosts {
+		instanceKeys = append(instanceKeys, slaveKey)
+	}
+	for _, instanceKey := range instanceKeys {
+		instanceKey := instanceKey
+		go inst.ExecuteOnTopology(func() {
+			snapshot := inst.CaptureFailureDetectionSnapshot(&instanceKey, &analysisEntry)
+			inst.WriteFailureDetectionSnapshot(snapshot)
+		})
+	}
+}
+
 // executeCheckAndRecoverFunction will choose the correct check & recovery function based on analysis.
 // It executes the function synchronuously
 func executeCheckAndRecoverFunction(analysisEntry inst.ReplicationAnalysis, candidateInstanceKey *inst.InstanceKey, skipFilters bool) (bool, *inst.Instance, error) {
@@ -421,8 +714,10 @@ func executeCheckAndRecoverFunction(analysisEntry inst.ReplicationAnalysis, cand
 		checkAndRecoverFunction = checkAndRecoverDeadIntermediateMaster
 	case inst.UnreachableMaster:
 		go emergentlyReadTopologyInstanceSlaves(&analysisEntry.AnalyzedInstanceKey, analysisEntry.Analysis)
+		go captureFailureDetectionSnapshots(analysisEntry)
 	case inst.AllMasterSlavesNotReplicating:
 		go emergentlyReadTopologyInstance(&analysisEntry.AnalyzedInstanceKey, analysisEntry.Analysis)
+		go captureFailureDetectionSnapshots(analysisEntry)
 	case inst.FirstTierSlaveFailingToConnectToMaster:
 		go emergentlyReadTopologyInstance(&analysisEntry.AnalyzedInstanceMasterKey, analysisEntry.Analysis)
 	}
@@ -452,6 +747,9 @@ func CheckAndRecover(specificInstance *inst.InstanceKey, candidateInstanceKey *i
 	if err != nil {
 		return false, nil, log.Errore(err)
 	}
+	if specificInstance == nil {
+		inst.PublishReplicationAnalysisEvents(replicationAnalysis)
+	}
 	for _, analysisEntry := range replicationAnalysis {
 		if specificInstance != nil {
 			// We are looking for a specific instance; if this is not the one, skip!
@@ -23,10 +23,35 @@ import (
 	"github.com/outbrain/orchestrator/config"
 	"github.com/outbrain/orchestrator/db"
 	"github.com/outbrain/orchestrator/inst"
+	"strings"
+	"sync"
 )
 
+// recoveryRegistrationMutex serializes recovery registration, such that rate limits are
+// checked against an up to date count of recoveries, even when many recoveries are attempted at once
+var recoveryRegistrationMutex = &sync.Mutex{}
+
 // AttemptRecoveryRegistration tries to add a recovery entry; if this fails that means recovery is already in place.
+// Recovery is also refused when blocked by recovery rate limits, in which case the blocked recovery is recorded.
 func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis) (bool, error) {
+	recoveryRegistrationMutex.Lock()
+	defer recoveryRegistrationMutex.Unlock()
+
+	// An already active recovery on the instance is not a blocked recovery; it merely deduplicates this one
+	activeRecoveries, err := readRecoveries(`where in_active_period = 1 and hostname = ? and port = ?`, ``,
+		analysisEntry.AnalyzedInstanceKey.Hostname, analysisEntry.AnalyzedInstanceKey.Port)
+	if err != nil {
+		return false, log.Errore(err)
+	}
+	if len(activeRecoveries) > 0 {
+		return false, nil
+	}
+	if blockReason, err := getRecoveryBlockReason(analysisEntry); err != nil {
+		return false, log.Errore(err)
+	} else if blockReason != "" {
+		registerBlockedRecovery(analysisEntry, blockReason)
+		return false, log.Errorf("Recovery of %+v is blocked: %s", analysisEntry.AnalyzedInstanceKey, blockReason)
+	}
 
 	db, err := db.OpenOrchestrator()
 	if err != nil {
@@ -47,7 +72,9 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis) (bool,
 					cluster_name,
 					cluster_alias,
 					count_affected_slaves,
-					slave_hosts
+					slave_hosts,
+					acknowledged_comment,
+					lost_slaves
 				) values (
 					?,
 					?,
@@ -60,7 +87,9 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis) (bool,
 					?,
 					?,
 					?,
-					?
+					?,
+					'',
+					''
 				)
 			`, analysisEntry.AnalyzedInstanceKey.Hostname, analysisEntry.AnalyzedInstanceKey.Port, ThisHostname, ProcessToken.Hash,
 		string(analysisEntry.Analysis), analysisEntry.ClusterName, analysisEntry.ClusterAlias, analysisEntry.CountSlaves, analysisEntry.GetSlaveHostsAsString(),
@@ -69,6 +98,9 @@ func AttemptRecoveryRegistration(analysisEntry *inst.ReplicationAnalysis) (bool,
 		return false, log.Errore(err)
 	}
 	rows, err := sqlResult.RowsAffected()
+	if err == nil && rows > 0 {
+		inst.PublishTopologyEvent(inst.EventRecoveryStarted, &analysisEntry.AnalyzedInstanceKey, analysisEntry.ClusterName, string(analysisEntry.Analysis), nil)
+	}
 	return (err == nil && rows > 0), err
 }
 
@@ -127,11 +159,140 @@ func ResolveRecovery(failedKey *inst.InstanceKey, successorKey *inst.InstanceKey
 	if err != nil {
 		return log.Errore(err)
 	}
+	inst.PublishInstanceTopologyEvent(inst.EventRecoveryResolved, failedKey, fmt.Sprintf("successor: %+v", *successorKey))
+	return nil
+}
+
+// AuditTopologyRecovery writes down a recovery step on the active recovery of given failed instance,
+// as processed by this node. It is a no-op when there is no such active recovery.
+func AuditTopologyRecovery(failedInstanceKey *inst.InstanceKey, message string) error {
+	log.Infof("Topology recovery %+v: %s", *failedInstanceKey, message)
+
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			insert 
+				into topology_recovery_steps (
+					recovery_step_id, recovery_id, audit_at, message
+				) 
+				select 
+					null, recovery_id, NOW(), ?
+				from 
+					topology_recovery
+				where
+					hostname = ?
+					AND port = ?
+					AND in_active_period = 1
+					AND processing_node_hostname = ?
+					AND processcing_node_token = ?
+				order by 
+					recovery_id desc
+				limit 1
+			`, message, failedInstanceKey.Hostname, failedInstanceKey.Port, ThisHostname, ProcessToken.Hash,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
 	return nil
 }
 
+// populateTopologyRecoverySteps reads and assigns recovery steps onto given recoveries
+func populateTopologyRecoverySteps(recoveries []TopologyRecovery) error {
+	if len(recoveries) == 0 {
+		return nil
+	}
+	recoveriesMap := make(map[int64]*TopologyRecovery)
+	recoveryIds := []string{}
+	for i := range recoveries {
+		recoveries[i].AllSteps = []TopologyRecoveryStep{}
+		recoveriesMap[recoveries[i].TopologyRecoveryId] = &recoveries[i]
+		recoveryIds = append(recoveryIds, fmt.Sprintf("%d", recoveries[i].TopologyRecoveryId))
+	}
+	query := fmt.Sprintf(`
+		select 
+			recovery_step_id,
+			recovery_id,
+			audit_at,
+			message
+		from 
+			topology_recovery_steps
+		where
+			recovery_id in (%s)
+		order by
+			recovery_step_id asc
+		`, strings.Join(recoveryIds, ", "))
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		recoveryStep := TopologyRecoveryStep{}
+		recoveryStep.RecoveryStepId = m.GetInt64("recovery_step_id")
+		recoveryStep.RecoveryId = m.GetInt64("recovery_id")
+		recoveryStep.AuditAt = m.GetString("audit_at")
+		recoveryStep.Message = m.GetString("message")
+
+		if topologyRecovery, ok := recoveriesMap[recoveryStep.RecoveryId]; ok {
+			topologyRecovery.AllSteps = append(topologyRecovery.AllSteps, recoveryStep)
+		}
+		return nil
+	})
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// writeTopologyRecoveryLostSlaves writes down slaves lost in the active recovery of given failed instance
+func writeTopologyRecoveryLostSlaves(failedInstanceKey *inst.InstanceKey, lostSlaves *inst.InstanceKeyMap) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			update topology_recovery set 
+				lost_slaves = ?
+			where
+				hostname = ?
+				AND port = ?
+				AND in_active_period = 1
+				AND processing_node_hostname = ?
+				AND processcing_node_token = ?
+			`, lostSlaves.ToCommaDelimitedList(),
+		failedInstanceKey.Hostname, failedInstanceKey.Port, ThisHostname, ProcessToken.Hash,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// readActiveTopologyRecovery reads the active recovery of given failed instance, as processed by this node
+func readActiveTopologyRecovery(failedInstanceKey *inst.InstanceKey) (*TopologyRecovery, error) {
+	recoveries, err := readRecoveries(`
+		where 
+			hostname = ? 
+			and port = ? 
+			and in_active_period = 1 
+			and processing_node_hostname = ? 
+			and processcing_node_token = ?
+		`, `limit 1`, failedInstanceKey.Hostname, failedInstanceKey.Port, ThisHostname, ProcessToken.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if len(recoveries) == 0 {
+		return nil, nil
+	}
+	return &recoveries[0], nil
+}
+
 // readRecoveries reads recovery entry/audit entires from topology_recovery
-func readRecoveries(whereCondition string, limit string) ([]TopologyRecovery, error) {
+func readRecoveries(whereCondition string, limit string, args ...interface{}) ([]TopologyRecovery, error) {
 	res := []TopologyRecovery{}
 	query := fmt.Sprintf(`
 		select 
@@ -150,7 +311,12 @@ func readRecoveries(whereCondition string, limit string) ([]TopologyRecovery, er
             cluster_name,
             cluster_alias,
             count_affected_slaves,
-            slave_hosts		
+            slave_hosts,
+            acknowledged,
+            ifnull(acknowledged_at, '') as acknowledged_at,
+            acknowledged_by,
+            acknowledged_comment,
+            lost_slaves
 		from 
 			topology_recovery
 		%s
@@ -164,7 +330,7 @@ func readRecoveries(whereCondition string, limit string) ([]TopologyRecovery, er
 	}
 
 	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
-		topologyRecovery := TopologyRecovery{}
+		topologyRecovery := TopologyRecovery{LostSlaves: make(inst.InstanceKeyMap)}
 		topologyRecovery.TopologyRecoveryId = m.GetInt64("recovery_id")
 
 		topologyRecovery.IsActive = m.GetBool("is_active")
@@ -184,9 +350,18 @@ func readRecoveries(whereCondition string, limit string) ([]TopologyRecovery, er
 		topologyRecovery.SuccessorKey.Hostname = m.GetString("successor_hostname")
 		topologyRecovery.SuccessorKey.Port = m.GetInt("successor_port")
 
+		topologyRecovery.Acknowledged = m.GetBool("acknowledged")
+		topologyRecovery.AcknowledgedAt = m.GetString("acknowledged_at")
+		topologyRecovery.AcknowledgedBy = m.GetString("acknowledged_by")
+		topologyRecovery.AcknowledgedComment = m.GetString("acknowledged_comment")
+		topologyRecovery.LostSlaves.ReadCommaDelimitedList(m.GetString("lost_slaves"))
+
 		res = append(res, topologyRecovery)
 		return nil
-	})
+	}, args...)
+	if err == nil {
+		err = populateTopologyRecoverySteps(res)
+	}
 Cleanup:
 
 	if err != nil {
@@ -217,3 +392,378 @@ func ReadRecentRecoveries(page int) ([]TopologyRecovery, error) {
 		config.Config.AuditPageSize, page*config.Config.AuditPageSize)
 	return readRecoveries(``, limit)
 }
+
+// ReadRecoveries reads a range of latest recovery entries from topology_recovery, optionally of a given cluster
+func ReadRecoveries(clusterName string, offset int, limit int) ([]TopologyRecovery, error) {
+	whereCondition := ``
+	args := sqlutils.Args()
+	if clusterName != "" {
+		whereCondition = `where cluster_name = ?`
+		args = append(args, clusterName)
+	}
+	limitClause := fmt.Sprintf(`
+		limit %d
+		offset %d`,
+		limit, offset)
+	return readRecoveries(whereCondition, limitClause, args...)
+}
+
+// ReadRecovery reads a single recovery entry from topology_recovery. The result is empty when no such recovery exists
+func ReadRecovery(recoveryId int64) ([]TopologyRecovery, error) {
+	return readRecoveries(`where recovery_id = ?`, ``, recoveryId)
+}
+
+// ReadUnacknowledgedRecoveries reads recoveries which have not been acknowledged
+func ReadUnacknowledgedRecoveries() ([]TopologyRecovery, error) {
+	return readRecoveries(`where acknowledged = 0`, ``)
+}
+
+// acknowledgeRecoveries marks recoveries matching given condition as acknowledged by given owner, with comment
+func acknowledgeRecoveries(owner string, comment string, whereCondition string, args []interface{}) (countAcknowledged int64, err error) {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+
+	query := fmt.Sprintf(`
+			update topology_recovery set 
+				acknowledged = 1,
+				acknowledged_at = NOW(),
+				acknowledged_by = ?,
+				acknowledged_comment = ?
+			where
+				acknowledged = 0
+				and %s
+			`, whereCondition)
+	args = append(sqlutils.Args(owner, comment), args...)
+	sqlResult, err := sqlutils.Exec(db, query, args...)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	return sqlResult.RowsAffected()
+}
+
+// AcknowledgeRecovery acknowledges a specific recovery
+func AcknowledgeRecovery(recoveryId int64, owner string, comment string) (countAcknowledged int64, err error) {
+	countAcknowledged, err = acknowledgeRecoveries(owner, comment, `recovery_id = ?`, sqlutils.Args(recoveryId))
+	if err == nil {
+		inst.AuditOperation("ack-recovery", nil, fmt.Sprintf("recovery: %d; acknowledged by %s: %s", recoveryId, owner, comment))
+	}
+	return countAcknowledged, err
+}
+
+// AcknowledgeClusterRecoveries acknowledges all recoveries on a given cluster
+func AcknowledgeClusterRecoveries(clusterName string, owner string, comment string) (countAcknowledged int64, err error) {
+	countAcknowledged, err = acknowledgeRecoveries(owner, comment, `cluster_name = ?`, sqlutils.Args(clusterName))
+	if err == nil {
+		inst.AuditOperation("ack-recovery", nil, fmt.Sprintf("cluster: %s; %d recoveries acknowledged by %s: %s", clusterName, countAcknowledged, owner, comment))
+	}
+	return countAcknowledged, err
+}
+
+// AcknowledgeInstanceRecoveries acknowledges all recoveries on a given failed instance
+func AcknowledgeInstanceRecoveries(instanceKey *inst.InstanceKey, owner string, comment string) (countAcknowledged int64, err error) {
+	countAcknowledged, err = acknowledgeRecoveries(owner, comment, `hostname = ? and port = ?`, sqlutils.Args(instanceKey.Hostname, instanceKey.Port))
+	if err == nil {
+		inst.AuditOperation("ack-recovery", instanceKey, fmt.Sprintf("%d recoveries acknowledged by %s: %s", countAcknowledged, owner, comment))
+	}
+	return countAcknowledged, err
+}
+
+// readRecoveryBlocks reads recovery block entries from topology_recovery_block
+func readRecoveryBlocks(whereCondition string, args ...interface{}) ([]RecoveryBlock, error) {
+	res := []RecoveryBlock{}
+	query := fmt.Sprintf(`
+		select 
+			block_id,
+			cluster_name,
+			block_reason,
+			start_block,
+			is_acknowledged,
+			acknowledged_by,
+			ifnull(acknowledged_at, '') as acknowledged_at
+		from 
+			topology_recovery_block
+		%s
+		order by
+			block_id desc
+		`, whereCondition)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		recoveryBlock := RecoveryBlock{}
+		recoveryBlock.BlockId = m.GetInt64("block_id")
+		recoveryBlock.ClusterName = m.GetString("cluster_name")
+		recoveryBlock.Reason = m.GetString("block_reason")
+		recoveryBlock.StartTimestamp = m.GetString("start_block")
+		recoveryBlock.IsAcknowledged = m.GetBool("is_acknowledged")
+		recoveryBlock.AcknowledgedBy = m.GetString("acknowledged_by")
+		recoveryBlock.AcknowledgedTimestamp = m.GetString("acknowledged_at")
+
+		res = append(res, recoveryBlock)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
+
+// ReadActiveRecoveryBlocks reads recovery blocks which have not been acknowledged
+func ReadActiveRecoveryBlocks() ([]RecoveryBlock, error) {
+	return readRecoveryBlocks(`where is_acknowledged = 0`)
+}
+
+// countRecoveriesSinceAcknowledgement counts recoveries started in the past given number of minutes, and which
+// have not been preceded by an acknowledged block on same scope. An empty clusterName indicates global scope.
+func countRecoveriesSinceAcknowledgement(clusterName string, windowMinutes int) (int, error) {
+	clusterCondition := ""
+	args := sqlutils.Args(windowMinutes, clusterName)
+	if clusterName != "" {
+		clusterCondition = `and cluster_name = ?`
+		args = append(args, clusterName)
+	}
+	query := fmt.Sprintf(`
+		select 
+			count(*) as count_recoveries
+		from 
+			topology_recovery
+		where
+			start_active_period >= NOW() - INTERVAL ? MINUTE
+			and start_active_period > ifnull(
+				(select max(acknowledged_at) from topology_recovery_block where cluster_name = ? and is_acknowledged = 1), 
+				'1970-01-02')
+			%s
+		`, clusterCondition)
+	countRecoveries := 0
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return countRecoveries, log.Errore(err)
+	}
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		countRecoveries = m.GetInt("count_recoveries")
+		return nil
+	}, args...)
+	return countRecoveries, err
+}
+
+// beginRecoveryBlock sets up a new recovery block on given scope. An empty clusterName indicates global scope.
+func beginRecoveryBlock(clusterName string, reason string) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			insert 
+				into topology_recovery_block (
+					cluster_name, block_reason, start_block
+				) VALUES (
+					?, ?, NOW()
+				)
+			`, clusterName, reason,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	inst.AuditOperation("begin-recovery-block", nil, fmt.Sprintf("cluster: %s; %s", clusterName, reason))
+	return nil
+}
+
+// getRecoveryBlockReason checks whether recovery of given analysis is blocked: either by a
+// non-acknowledged recovery block or by exceeding recovery rate limits (in which case a block is set up).
+// It returns an empty string when recovery is allowed.
+func getRecoveryBlockReason(analysisEntry *inst.ReplicationAnalysis) (string, error) {
+	activeBlocks, err := readRecoveryBlocks(`where is_acknowledged = 0 and cluster_name in ('', ?)`, analysisEntry.ClusterName)
+	if err != nil {
+		return "", err
+	}
+	for _, recoveryBlock := range activeBlocks {
+		if recoveryBlock.ClusterName == "" {
+			return fmt.Sprintf("global recovery block %d: %s", recoveryBlock.BlockId, recoveryBlock.Reason), nil
+		}
+		return fmt.Sprintf("cluster recovery block %d: %s", recoveryBlock.BlockId, recoveryBlock.Reason), nil
+	}
+	if config.Config.BlockRecoveriesUntilAcknowledged {
+		unacknowledgedRecoveries, err := readRecoveries(`where acknowledged = 0 and cluster_name = ?`, ``, analysisEntry.ClusterName)
+		if err != nil {
+			return "", err
+		}
+		if len(unacknowledgedRecoveries) > 0 {
+			return fmt.Sprintf("%d unacknowledged recoveries on cluster %s; latest: %d", len(unacknowledgedRecoveries), analysisEntry.ClusterName, unacknowledgedRecoveries[0].TopologyRecoveryId), nil
+		}
+	}
+	if config.Config.MaxGlobalRecoveries > 0 {
+		countRecoveries, err := countRecoveriesSinceAcknowledgement("", config.Config.MaxGlobalRecoveriesWindowMinutes)
+		if err != nil {
+			return "", err
+		}
+		if countRecoveries >= config.Config.MaxGlobalRecoveries {
+			reason := fmt.Sprintf("%d recoveries in past %d minutes; MaxGlobalRecoveries is %d", countRecoveries, config.Config.MaxGlobalRecoveriesWindowMinutes, config.Config.MaxGlobalRecoveries)
+			return reason, beginRecoveryBlock("", reason)
+		}
+	}
+	if config.Config.MaxClusterRecoveriesPerHour > 0 {
+		countRecoveries, err := countRecoveriesSinceAcknowledgement(analysisEntry.ClusterName, 60)
+		if err != nil {
+			return "", err
+		}
+		if countRecoveries >= config.Config.MaxClusterRecoveriesPerHour {
+			reason := fmt.Sprintf("%d recoveries on cluster %s in past hour; MaxClusterRecoveriesPerHour is %d", countRecoveries, analysisEntry.ClusterName, config.Config.MaxClusterRecoveriesPerHour)
+			return reason, beginRecoveryBlock(analysisEntry.ClusterName, reason)
+		}
+	}
+	return "", nil
+}
+
+// AcknowledgeRecoveryBlocks acknowledges (and thereby lifts) active recovery blocks on given scope.
+// An empty clusterName indicates global scope.
+func AcknowledgeRecoveryBlocks(clusterName string, owner string) (int64, error) {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+
+	sqlResult, err := sqlutils.Exec(db, `
+			update topology_recovery_block set 
+				is_acknowledged = 1,
+				acknowledged_by = ?,
+				acknowledged_at = NOW()
+			where
+				cluster_name = ?
+				and is_acknowledged = 0
+			`, owner, clusterName,
+	)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	inst.AuditOperation("ack-recovery-blocks", nil, fmt.Sprintf("cluster: %s; acknowledged by %s", clusterName, owner))
+	return rows, err
+}
+
+// AcknowledgeRecoveryBlock acknowledges (and thereby lifts) a specific recovery block
+func AcknowledgeRecoveryBlock(blockId int64, owner string) (int64, error) {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+
+	sqlResult, err := sqlutils.Exec(db, `
+			update topology_recovery_block set 
+				is_acknowledged = 1,
+				acknowledged_by = ?,
+				acknowledged_at = NOW()
+			where
+				block_id = ?
+				and is_acknowledged = 0
+			`, owner, blockId,
+	)
+	if err != nil {
+		return 0, log.Errore(err)
+	}
+	rows, err := sqlResult.RowsAffected()
+	inst.AuditOperation("ack-recovery-blocks", nil, fmt.Sprintf("block: %d; acknowledged by %s", blockId, owner))
+	return rows, err
+}
+
+// registerBlockedRecovery writes down a recovery attempt which was blocked, along with the reason
+func registerBlockedRecovery(analysisEntry *inst.ReplicationAnalysis, blockingReason string) error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			insert 
+				into blocked_topology_recovery (
+					hostname, port, cluster_name, analysis, last_blocked_timestamp, blocking_reason
+				) VALUES (
+					?, ?, ?, ?, NOW(), ?
+				)
+				on duplicate key update
+					cluster_name=values(cluster_name),
+					analysis=values(analysis),
+					last_blocked_timestamp=values(last_blocked_timestamp),
+					blocking_reason=values(blocking_reason)
+			`, analysisEntry.AnalyzedInstanceKey.Hostname, analysisEntry.AnalyzedInstanceKey.Port,
+		analysisEntry.ClusterName, string(analysisEntry.Analysis), blockingReason,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// ExpireBlockedRecoveries removes blocked recovery entries which have not been re-attempted for a while
+func ExpireBlockedRecoveries() error {
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		return log.Errore(err)
+	}
+
+	_, err = sqlutils.Exec(db, `
+			delete 
+				from blocked_topology_recovery 
+			where
+				last_blocked_timestamp < NOW() - INTERVAL ? MINUTE
+			`, config.Config.RecoveryPeriodBlockMinutes,
+	)
+	if err != nil {
+		return log.Errore(err)
+	}
+	return nil
+}
+
+// ReadBlockedRecoveries reads blocked recovery entries, optionally limited to a given cluster
+func ReadBlockedRecoveries(clusterName string) ([]BlockedTopologyRecovery, error) {
+	res := []BlockedTopologyRecovery{}
+	whereClause := ""
+	args := sqlutils.Args()
+	if clusterName != "" {
+		whereClause = `where cluster_name = ?`
+		args = append(args, clusterName)
+	}
+	query := fmt.Sprintf(`
+		select 
+			hostname,
+			port,
+			cluster_name,
+			analysis,
+			last_blocked_timestamp,
+			blocking_reason
+		from 
+			blocked_topology_recovery
+		%s
+		order by
+			last_blocked_timestamp desc
+		`, whereClause)
+	db, err := db.OpenOrchestrator()
+	if err != nil {
+		goto Cleanup
+	}
+
+	err = sqlutils.QueryRowsMap(db, query, func(m sqlutils.RowMap) error {
+		blockedTopologyRecovery := BlockedTopologyRecovery{}
+		blockedTopologyRecovery.FailedInstanceKey.Hostname = m.GetString("hostname")
+		blockedTopologyRecovery.FailedInstanceKey.Port = m.GetInt("port")
+		blockedTopologyRecovery.ClusterName = m.GetString("cluster_name")
+		blockedTopologyRecovery.Analysis = inst.AnalysisCode(m.GetString("analysis"))
+		blockedTopologyRecovery.LastBlockedTimestamp = m.GetString("last_blocked_timestamp")
+		blockedTopologyRecovery.BlockingReason = m.GetString("blocking_reason")
+
+		res = append(res, blockedTopologyRecovery)
+		return nil
+	}, args...)
+Cleanup:
+
+	if err != nil {
+		log.Errore(err)
+	}
+	return res, err
+}
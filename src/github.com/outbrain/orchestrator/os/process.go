@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"os/exec"
+	"syscall"
 )
 
 func execCmd(commandText string, arguments ...string) (*exec.Cmd, string, error) {
@@ -52,3 +53,17 @@ func CommandRun(commandText string, arguments ...string) error {
 	}
 	return nil
 }
+
+// CommandExitCode returns the exit code of a command, given the error returned by running it.
+// A nil error maps to zero; -1 is returned when the exit code cannot be determined.
+func CommandExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok {
+			return status.ExitStatus()
+		}
+	}
+	return -1
+}
@@ -0,0 +1,81 @@
+/*
+   Copyright 2015 Shlomi Noach, courtesy Booking.com
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package ssl
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// NewTLSConfig returns a TLS configuration for serving or connecting. When caFile is given, its certificates
+// are trusted for verifying peers. With mutualTLS, a server requires and verifies client certificates.
+func NewTLSConfig(caFile string, mutualTLS bool) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if caFile != "" {
+		caPool, err := ReadCAFile(caFile)
+		if err != nil {
+			return tlsConfig, err
+		}
+		tlsConfig.ClientCAs = caPool
+		tlsConfig.RootCAs = caPool
+	}
+	if mutualTLS {
+		if caFile == "" {
+			return tlsConfig, errors.New("Mutual TLS requires a CA file to verify client certificates")
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// ReadCAFile reads a PEM encoded CA bundle into a certificate pool
+func ReadCAFile(caFile string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("No certificates found in CA file %s", caFile)
+	}
+	return caPool, nil
+}
+
+// AppendKeyPair loads a certificate & private key pair into given TLS configuration, to be presented to peers
+func AppendKeyPair(tlsConfig *tls.Config, certFile string, keyFile string) error {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	tlsConfig.Certificates = append(tlsConfig.Certificates, cert)
+	return nil
+}
+
+// GetClientCertCommonName returns the common name of a request's verified client certificate, or an empty
+// string when no verified client certificate was presented
+func GetClientCertCommonName(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return req.TLS.VerifiedChains[0][0].Subject.CommonName
+}